@@ -2,7 +2,9 @@ package commands
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"time"
 
 	"github.com/adamf123git/git-migrator/internal/mapping"
 	"github.com/adamf123git/git-migrator/internal/vcs/cvs"
@@ -16,20 +18,25 @@ var analyzeCmd = &cobra.Command{
 including the number of commits, branches, tags, and unique authors.
 
 This command is useful for understanding what will be migrated before
-running the actual migration.`,
+running the actual migration. With --config, the repository is read with
+the source settings of the configuration, such as the CVS module or the
+SVN layout, and branch and tag names are shown as the migration will
+rename or skip them.`,
 	RunE: runAnalyze,
 }
 
 var (
 	analyzeSourceType string
 	analyzeSource     string
+	analyzeConfigFile string
 )
 
 func init() {
 	rootCmd.AddCommand(analyzeCmd)
 
 	analyzeCmd.Flags().StringVarP(&analyzeSourceType, "source-type", "t", "cvs", "Source VCS type (cvs or svn)")
-	analyzeCmd.Flags().StringVarP(&analyzeSource, "source", "s", "", "Path to source repository")
+	analyzeCmd.Flags().StringVarP(&analyzeSource, "source", "s", "", "Path to source repository (svnadmin dump file for svn)")
+	analyzeCmd.Flags().StringVarP(&analyzeConfigFile, "config", "c", "", "Migration configuration whose source settings and branch and tag mappings to preview")
 	var err = analyzeCmd.MarkFlagRequired("source")
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error marking flag as required: %v\n", err)
@@ -43,16 +50,33 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("unsupported source type: %s (supported: cvs, svn)", analyzeSourceType)
 	}
 
-	// Currently only CVS is implemented
-	if analyzeSourceType == "svn" {
-		return fmt.Errorf("SVN support is not yet implemented")
+	// Load the source settings and branch and tag mappings
+	config := &ConfigFile{}
+	if analyzeConfigFile != "" {
+		var err error
+		if config, err = loadConfigFile(analyzeConfigFile); err != nil {
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+	}
+	branchMap, tagMap, err := refMaps(config)
+	if err != nil {
+		return err
 	}
 
-	// Create reader
-	reader := cvs.NewReader(analyzeSource)
+	// Create the reader as the migration would, from the source settings
+	// of the configuration
+	config.Source.Path = analyzeSource
+	if config.Source.Type == "" || (cmd != nil && cmd.Flags().Changed("source-type")) {
+		config.Source.Type = analyzeSourceType
+	}
+	reader, err := sourceReader(config)
+	if err != nil {
+		return err
+	}
+	cvsReader, _ := reader.(*cvs.Reader)
 
 	// Validate repository
-	fmt.Printf("Analyzing %s repository at: %s\n\n", analyzeSourceType, analyzeSource)
+	fmt.Printf("Analyzing %s repository at: %s\n\n", config.Source.Type, analyzeSource)
 	if err := reader.Validate(); err != nil {
 		return fmt.Errorf("repository validation failed: %w", err)
 	}
@@ -88,6 +112,18 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("error iterating commits: %w", err)
 	}
 
+	// CVSROOT/history dates the rtags that created branches and tags
+	var history *cvs.History
+	if cvsReader != nil {
+		if history, err = cvsReader.GetHistory(); err != nil {
+			return fmt.Errorf("failed to read history: %w", err)
+		}
+	}
+	created := make(map[string]time.Time)
+	if history != nil {
+		created = history.TagDates()
+	}
+
 	// Close reader
 	if err := reader.Close(); err != nil {
 		return fmt.Errorf("failed to close reader: %w", err)
@@ -96,7 +132,7 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 	// Display results
 	fmt.Println("Repository Analysis Results")
 	fmt.Println("==========================")
-	fmt.Printf("Type:           %s\n", analyzeSourceType)
+	fmt.Printf("Type:           %s\n", config.Source.Type)
 	fmt.Printf("Path:           %s\n", analyzeSource)
 	fmt.Printf("Commits:        %d\n", commitCount)
 	fmt.Printf("Branches:       %d\n", len(branches))
@@ -106,7 +142,11 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 	if len(branches) > 0 {
 		fmt.Println("Branches:")
 		for _, branch := range branches {
-			fmt.Printf("  - %s\n", branch)
+			if date, ok := created[branch]; ok {
+				fmt.Printf("  - %s (created: %s)\n", refPreview(branchMap, branch), date.Format("2006-01-02 15:04:05"))
+			} else {
+				fmt.Printf("  - %s\n", refPreview(branchMap, branch))
+			}
 		}
 		fmt.Println()
 	}
@@ -114,7 +154,31 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 	if len(tags) > 0 {
 		fmt.Println("Tags:")
 		for name, rev := range tags {
-			fmt.Printf("  - %s (revision: %s)\n", name, rev)
+			fmt.Printf("  - %s (revision: %s)\n", refPreview(tagMap, name), rev)
+		}
+		fmt.Println()
+	}
+
+	if history != nil {
+		printCVSHistory(os.Stdout, history, branches, tags)
+	}
+
+	// Report Git names that are invalid or shared by several source names
+	var problems []string
+	if _, err := branchMap.MapNames(branches); err != nil {
+		problems = append(problems, fmt.Sprintf("branches: %v", err))
+	}
+	tagNames := make([]string, 0, len(tags))
+	for name := range tags {
+		tagNames = append(tagNames, name)
+	}
+	if _, err := tagMap.MapNames(tagNames); err != nil {
+		problems = append(problems, fmt.Sprintf("tags: %v", err))
+	}
+	if len(problems) > 0 {
+		fmt.Println("Mapping Problems:")
+		for _, problem := range problems {
+			fmt.Printf("  - %s\n", problem)
 		}
 		fmt.Println()
 	}
@@ -128,7 +192,70 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 		fmt.Println()
 	}
 
-	fmt.Println("Repository is valid and ready for migration.")
+	if len(problems) > 0 {
+		fmt.Println("Repository is valid, but the branch and tag mappings need fixing before migration.")
+	} else {
+		fmt.Println("Repository is valid and ready for migration.")
+	}
 
 	return nil
 }
+
+// refPreview describes what the migration does with a branch or tag name
+func refPreview(refMap *mapping.RefMap, name string) string {
+	gitName, ok := refMap.Get(name)
+	if !ok {
+		return name + " (skipped)"
+	}
+	if gitName != name {
+		return name + " -> " + gitName
+	}
+	return name
+}
+
+// printCVSHistory reports what CVSROOT/history and val-tags record: how
+// often the repository was checked out and committed to, and every tag
+// that was ever applied, including those no longer in the repository
+func printCVSHistory(out io.Writer, history *cvs.History, branches []string, tags map[string]string) {
+	if len(history.Records) == 0 && len(history.ValTags) == 0 {
+		return
+	}
+
+	fmt.Fprintln(out, "History:")
+	fmt.Fprintf(out, "  Checkouts:      %d\n", history.Count(cvs.HistoryCheckout, cvs.HistoryExport))
+	fmt.Fprintf(out, "  File Commits:   %d\n", history.Count(cvs.HistoryModified, cvs.HistoryAdded, cvs.HistoryRemoved))
+	fmt.Fprintf(out, "  Tag Operations: %d\n", history.Count(cvs.HistoryTag))
+	fmt.Fprintln(out)
+
+	present := make(map[string]bool)
+	for _, branch := range branches {
+		present[branch] = true
+	}
+	for name := range tags {
+		present[name] = true
+	}
+
+	applied := history.Tags()
+	if len(applied) == 0 {
+		return
+	}
+	fmt.Fprintf(out, "Tags Ever Applied (%d):\n", len(applied))
+	for _, tag := range applied {
+		line := "  - " + tag.Name
+		if !tag.Applied.IsZero() {
+			line += fmt.Sprintf(" (%s by %s", tag.Applied.Format("2006-01-02 15:04:05"), tag.User)
+			if tag.Module != "" {
+				line += " on " + tag.Module
+			}
+			line += ")"
+		}
+		switch {
+		case tag.Deleted:
+			line += " [deleted]"
+		case !present[tag.Name]:
+			line += " [not in repository]"
+		}
+		fmt.Fprintln(out, line)
+	}
+	fmt.Fprintln(out)
+}
@@ -2,8 +2,10 @@ package commands
 
 import (
 	"encoding/json"
+	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -89,16 +91,35 @@ func TestRunAnalyze_InvalidSourceType(t *testing.T) {
 	require.Contains(t, err.Error(), "unsupported source type")
 }
 
-func TestRunAnalyze_SVNNotImplemented(t *testing.T) {
+func TestRunAnalyze_SVNValidationFailure(t *testing.T) {
 	oldType := analyzeSourceType
 	oldSource := analyzeSource
 	analyzeSourceType = "svn"
-	analyzeSource = "/tmp"
+	analyzeSource = t.TempDir()
 	defer func() { analyzeSourceType = oldType; analyzeSource = oldSource }()
 
 	err := runAnalyze(nil, nil)
 	require.Error(t, err)
-	require.Contains(t, err.Error(), "SVN support is not yet implemented")
+	require.Contains(t, err.Error(), "validation failed")
+}
+
+func TestRunAnalyze_SVNDump(t *testing.T) {
+	dump := "SVN-fs-dump-format-version: 2\n\n" +
+		"Revision-number: 1\nProp-content-length: 36\nContent-length: 36\n\n" +
+		"K 10\nsvn:author\nV 5\nalice\nPROPS-END\n\n" +
+		"Node-path: trunk\nNode-kind: dir\nNode-action: add\n\n" +
+		"Node-path: trunk/README\nNode-kind: file\nNode-action: add\nText-content-length: 6\nContent-length: 6\n\nhello\n\n"
+	path := filepath.Join(t.TempDir(), "repo.dump")
+	require.NoError(t, os.WriteFile(path, []byte(dump), 0644))
+
+	oldType := analyzeSourceType
+	oldSource := analyzeSource
+	analyzeSourceType = "svn"
+	analyzeSource = path
+	defer func() { analyzeSourceType = oldType; analyzeSource = oldSource }()
+
+	err := runAnalyze(nil, nil)
+	require.NoError(t, err)
 }
 
 func TestRunAnalyze_ValidationFailure(t *testing.T) {
@@ -142,7 +163,10 @@ text
 log
 @Initial revision@
 text
-@initial content@
+@d1 1
+a1 1
+initial content
+@
 `
 	rcsFile := filepath.Join(dir, "file.txt,v")
 	require.NoError(t, os.WriteFile(rcsFile, []byte(rcsContent), 0644))
@@ -209,7 +233,10 @@ text
 log
 @Initial revision@
 text
-@initial@
+@d1 1
+a1 1
+initial
+@
 `
 	rcsFile := filepath.Join(dir, "file.txt,v")
 	require.NoError(t, os.WriteFile(rcsFile, []byte(rcsContent), 0644))
@@ -223,3 +250,254 @@ text
 	err := runAuthorsExtract(nil, nil)
 	require.NoError(t, err)
 }
+
+func TestRunAnalyze_RefPreview(t *testing.T) {
+	node := func(path, copyFrom string) string {
+		return "Node-path: " + path + "\nNode-kind: dir\nNode-action: add\n" +
+			"Node-copyfrom-rev: 1\nNode-copyfrom-path: " + copyFrom + "\n\n"
+	}
+	dump := "SVN-fs-dump-format-version: 2\n\n" +
+		"Revision-number: 1\nProp-content-length: 36\nContent-length: 36\n\n" +
+		"K 10\nsvn:author\nV 5\nalice\nPROPS-END\n\n" +
+		"Node-path: trunk\nNode-kind: dir\nNode-action: add\n\n" +
+		"Node-path: trunk/README\nNode-kind: file\nNode-action: add\nText-content-length: 6\nContent-length: 6\n\nhello\n\n" +
+		"Revision-number: 2\nProp-content-length: 36\nContent-length: 36\n\n" +
+		"K 10\nsvn:author\nV 5\nalice\nPROPS-END\n\n" +
+		node("branches/RELEASE_1_0", "trunk") +
+		node("tags/REL_1_0", "trunk") +
+		node("tags/NIGHTLY_1", "trunk")
+	tmp := t.TempDir()
+	path := filepath.Join(tmp, "repo.dump")
+	require.NoError(t, os.WriteFile(path, []byte(dump), 0644))
+
+	cfgPath := filepath.Join(tmp, "cfg.yaml")
+	writeConfig := func(tagMapping string) {
+		cfg := "source:\n  type: svn\n  path: " + path + "\ntarget:\n  path: /tmp/target\nmapping:\n" +
+			"  branchRules:\n    - pattern: '^RELEASE_(\\d+)_(\\d+)$'\n      replace: 'release/$1.$2'\n" + tagMapping
+		require.NoError(t, os.WriteFile(cfgPath, []byte(cfg), 0644))
+	}
+	writeConfig("  tagRules:\n    - pattern: '^REL_'\n      replace: 'v'\n  skipTags: ['NIGHTLY_*']\n")
+
+	oldType, oldSource, oldConfig := analyzeSourceType, analyzeSource, analyzeConfigFile
+	analyzeSourceType, analyzeSource, analyzeConfigFile = "svn", path, cfgPath
+	defer func() { analyzeSourceType, analyzeSource, analyzeConfigFile = oldType, oldSource, oldConfig }()
+
+	orig := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	err := runAnalyze(nil, nil)
+	_ = w.Close()
+	os.Stdout = orig
+	require.NoError(t, err)
+	out, _ := io.ReadAll(r)
+
+	require.Contains(t, string(out), "  - RELEASE_1_0 -> release/1.0\n")
+	require.Contains(t, string(out), "  - REL_1_0 -> v1_0 (revision: ")
+	require.Contains(t, string(out), "  - NIGHTLY_1 (skipped) (revision: ")
+	require.Contains(t, string(out), "Repository is valid and ready for migration.")
+
+	// Renaming two tags onto one name is reported
+	writeConfig("  tagRules:\n    - pattern: '.*'\n      replace: 'same'\n")
+	r, w, _ = os.Pipe()
+	os.Stdout = w
+	err = runAnalyze(nil, nil)
+	_ = w.Close()
+	os.Stdout = orig
+	require.NoError(t, err)
+	out, _ = io.ReadAll(r)
+	require.Contains(t, string(out), "Mapping Problems:\n  - tags: NIGHTLY_1, REL_1_0 all map to same\n")
+}
+
+func TestRunAnalyze_ConfigSourceSettings(t *testing.T) {
+	dump := "SVN-fs-dump-format-version: 2\n\n" +
+		"Revision-number: 1\nProp-content-length: 36\nContent-length: 36\n\n" +
+		"K 10\nsvn:author\nV 5\nalice\nPROPS-END\n\n" +
+		"Node-path: main\nNode-kind: dir\nNode-action: add\n\n" +
+		"Node-path: main/README\nNode-kind: file\nNode-action: add\nText-content-length: 6\nContent-length: 6\n\nhello\n\n" +
+		"Revision-number: 2\nProp-content-length: 36\nContent-length: 36\n\n" +
+		"K 10\nsvn:author\nV 5\nalice\nPROPS-END\n\n" +
+		"Node-path: releases/R1\nNode-kind: dir\nNode-action: add\n" +
+		"Node-copyfrom-rev: 1\nNode-copyfrom-path: main\n\n"
+	tmp := t.TempDir()
+	path := filepath.Join(tmp, "repo.dump")
+	require.NoError(t, os.WriteFile(path, []byte(dump), 0644))
+
+	// The preview reads the repository with the layout of the configuration
+	cfgPath := filepath.Join(tmp, "cfg.yaml")
+	cfg := "source:\n  type: svn\n  path: " + path + "\n  trunk: main\n  branches: [releases/*]\ntarget:\n  path: /tmp/target\n"
+	require.NoError(t, os.WriteFile(cfgPath, []byte(cfg), 0644))
+
+	oldType, oldSource, oldConfig := analyzeSourceType, analyzeSource, analyzeConfigFile
+	analyzeSourceType, analyzeSource, analyzeConfigFile = "cvs", path, cfgPath
+	defer func() { analyzeSourceType, analyzeSource, analyzeConfigFile = oldType, oldSource, oldConfig }()
+
+	orig := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	err := runAnalyze(nil, nil)
+	_ = w.Close()
+	os.Stdout = orig
+	require.NoError(t, err)
+	out, _ := io.ReadAll(r)
+
+	require.Contains(t, string(out), "Type:           svn\n")
+	require.Contains(t, string(out), "Branches:\n  - R1\n")
+}
+
+func TestRunAuthorsMailmap(t *testing.T) {
+	revision := func(number int, date string) string {
+		props := "K 10\nsvn:author\nV 5\nalice\nK 8\nsvn:date\nV " + strconv.Itoa(len(date)) + "\n" + date + "\nPROPS-END\n"
+		return "Revision-number: " + strconv.Itoa(number) + "\nProp-content-length: " + strconv.Itoa(len(props)) +
+			"\nContent-length: " + strconv.Itoa(len(props)) + "\n\n" + props + "\n"
+	}
+	dump := "SVN-fs-dump-format-version: 2\n\n" +
+		revision(1, "2008-06-01T10:00:00.000000Z") +
+		"Node-path: trunk\nNode-kind: dir\nNode-action: add\n\n" +
+		"Node-path: trunk/README\nNode-kind: file\nNode-action: add\nText-content-length: 6\nContent-length: 6\n\nhello\n\n" +
+		revision(2, "2010-06-01T10:00:00.000000Z") +
+		"Node-path: trunk/README\nNode-kind: file\nNode-action: change\nText-content-length: 6\nContent-length: 6\n\nworld\n\n"
+	tmp := t.TempDir()
+	source := filepath.Join(tmp, "repo.dump")
+	require.NoError(t, os.WriteFile(source, []byte(dump), 0644))
+
+	cfgPath := filepath.Join(tmp, "cfg.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte(`source:
+  type: svn
+  path: `+source+`
+target:
+  path: /tmp/target
+mapping:
+  authors:
+    alice: "Alice Example <alice@example.com>"
+  authorHistory:
+    alice:
+      - author: "Alice Old <alice@old.example.com>"
+        until: 2009-01-01
+`), 0644))
+
+	output := filepath.Join(tmp, ".mailmap")
+	oldConfig, oldOutput := authorsConfigFile, authorsOutput
+	authorsConfigFile, authorsOutput = cfgPath, output
+	defer func() { authorsConfigFile, authorsOutput = oldConfig, oldOutput }()
+
+	require.NoError(t, runAuthorsMailmap(nil, nil))
+	content, err := os.ReadFile(output)
+	require.NoError(t, err)
+	require.Contains(t, string(content), "Alice Example <alice@example.com> Alice Old <alice@old.example.com>\n")
+}
+
+// authorsDump writes a dump file with commits by alice in 2008 and 2010 and
+// by bob in 2009
+func authorsDump(t *testing.T) string {
+	revision := func(number int, author, date string) string {
+		props := "K 10\nsvn:author\nV " + strconv.Itoa(len(author)) + "\n" + author +
+			"\nK 8\nsvn:date\nV " + strconv.Itoa(len(date)) + "\n" + date + "\nPROPS-END\n"
+		return "Revision-number: " + strconv.Itoa(number) + "\nProp-content-length: " + strconv.Itoa(len(props)) +
+			"\nContent-length: " + strconv.Itoa(len(props)) + "\n\n" + props + "\n"
+	}
+	dump := "SVN-fs-dump-format-version: 2\n\n" +
+		revision(1, "alice", "2008-06-01T10:00:00.000000Z") +
+		"Node-path: trunk\nNode-kind: dir\nNode-action: add\n\n" +
+		"Node-path: trunk/README\nNode-kind: file\nNode-action: add\nText-content-length: 6\nContent-length: 6\n\nhello\n\n" +
+		revision(2, "bob", "2009-02-01T10:00:00.000000Z") +
+		"Node-path: trunk/README\nNode-kind: file\nNode-action: change\nText-content-length: 6\nContent-length: 6\n\nworld\n\n" +
+		revision(3, "alice", "2010-06-01T10:00:00.000000Z") +
+		"Node-path: trunk/README\nNode-kind: file\nNode-action: change\nText-content-length: 4\nContent-length: 4\n\nbye\n\n"
+	source := filepath.Join(t.TempDir(), "repo.dump")
+	require.NoError(t, os.WriteFile(source, []byte(dump), 0644))
+	return source
+}
+
+// runAuthorsCheckOutput runs authors check and returns what it printed
+func runAuthorsCheckOutput(t *testing.T) (string, error) {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	stdout := os.Stdout
+	os.Stdout = w
+	runErr := runAuthorsCheck(nil, nil)
+	os.Stdout = stdout
+	require.NoError(t, w.Close())
+	report, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return string(report), runErr
+}
+
+func TestRunAuthorsCheck(t *testing.T) {
+	source := authorsDump(t)
+	tmp := t.TempDir()
+	authorsFile := filepath.Join(tmp, "authors.txt")
+	require.NoError(t, os.WriteFile(authorsFile, []byte("alice = Alice Example <alice@example.com>\ncarol = Carol\n"), 0644))
+	output := filepath.Join(tmp, "updated.yaml")
+
+	oldSource, oldType, oldFiles, oldWrite := authorsSource, authorsSourceType, authorsFiles, authorsWrite
+	authorsSource, authorsSourceType, authorsFiles, authorsWrite = source, "svn", []string{authorsFile}, output
+	defer func() {
+		authorsSource, authorsSourceType, authorsFiles, authorsWrite = oldSource, oldType, oldFiles, oldWrite
+	}()
+
+	report, err := runAuthorsCheckOutput(t)
+	require.NoError(t, err)
+
+	require.Regexp(t, `alice\s+2\s+2008-06-01\s+2010-06-01\s+Alice Example <alice@example.com>`, string(report))
+	require.Regexp(t, `bob\s+1\s+2009-02-01\s+2009-02-01\s+\(unmapped\)`, string(report))
+	require.Contains(t, string(report), "Unmapped Users (1):\n  - bob\n")
+	require.Contains(t, string(report), "Unused Entries (1):\n  - "+authorsFile+":2: carol\n")
+	require.Contains(t, string(report), "Malformed Entries (1):\n  - "+authorsFile+`:2: carol: expected "Name <email>", got "Carol"`)
+
+	content, err := os.ReadFile(output)
+	require.NoError(t, err)
+	require.Equal(t, "alice: Alice Example <alice@example.com>\nbob: bob <bob@example.com>\ncarol: Carol\n", string(content))
+}
+
+func TestRunAuthorsCheck_Config(t *testing.T) {
+	source := authorsDump(t)
+	cfgPath := filepath.Join(t.TempDir(), "cfg.yaml")
+	cfg := "source:\n  type: svn\n  path: " + source + "\ntarget:\n  path: /tmp/target\n" +
+		"mapping:\n  authors:\n    alice: Alice Example <alice@example.com>\n" +
+		"  authorRules:\n    - pattern: '^b(.*)$'\n      name: 'B$1'\n      email: 'b$1@example.org'\n"
+	require.NoError(t, os.WriteFile(cfgPath, []byte(cfg), 0644))
+
+	old := []string{authorsSource, authorsSourceType, authorsWrite, authorsConfigFile}
+	oldFiles := authorsFiles
+	authorsSource, authorsSourceType, authorsWrite, authorsConfigFile, authorsFiles = "", "cvs", "", cfgPath, nil
+	defer func() {
+		authorsSource, authorsSourceType, authorsWrite, authorsConfigFile = old[0], old[1], old[2], old[3]
+		authorsFiles = oldFiles
+	}()
+
+	// bob is mapped by the rule of the configuration, as migrate maps him
+	report, err := runAuthorsCheckOutput(t)
+	require.NoError(t, err)
+	require.Regexp(t, `bob\s+1\s+2009-02-01\s+2009-02-01\s+Bob <bob@example.org>`, report)
+	require.Contains(t, report, "No mapping problems found.")
+
+	authorsConfigFile = ""
+	_, err = runAuthorsCheckOutput(t)
+	require.ErrorContains(t, err, "--authors or --config is required")
+}
+
+func TestRunAuthorsCheck_ConfigMalformedAuthorsFile(t *testing.T) {
+	source := authorsDump(t)
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "authors.txt"),
+		[]byte("alice = Alice Example <alice@example.com>\nbob Bob no email\n"), 0644))
+	cfgPath := filepath.Join(dir, "cfg.yaml")
+	cfg := "source:\n  type: svn\n  path: " + source + "\ntarget:\n  path: /tmp/target\n" +
+		"mapping:\n  authors_file: authors.txt\n"
+	require.NoError(t, os.WriteFile(cfgPath, []byte(cfg), 0644))
+
+	old := []string{authorsSource, authorsSourceType, authorsWrite, authorsConfigFile}
+	oldFiles := authorsFiles
+	authorsSource, authorsSourceType, authorsWrite, authorsConfigFile, authorsFiles = "", "cvs", "", cfgPath, nil
+	defer func() {
+		authorsSource, authorsSourceType, authorsWrite, authorsConfigFile = old[0], old[1], old[2], old[3]
+		authorsFiles = oldFiles
+	}()
+
+	// A migration rejects the file, but the check reports what is wrong
+	_, err := loadConfigFile(cfgPath)
+	require.Error(t, err)
+	report, err := runAuthorsCheckOutput(t)
+	require.NoError(t, err)
+	require.Contains(t, report, "Malformed Entries (1):\n  - "+filepath.Join(dir, "authors.txt")+`:2: bob: expected "Name <email>", got "Bob no email"`)
+}
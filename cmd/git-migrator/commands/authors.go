@@ -2,9 +2,13 @@ package commands
 
 import (
 	"fmt"
+	"io"
+	"log"
 	"os"
+	"text/tabwriter"
 
 	"github.com/adamf123git/git-migrator/internal/mapping"
+	"github.com/adamf123git/git-migrator/internal/vcs"
 	"github.com/adamf123git/git-migrator/internal/vcs/cvs"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
@@ -17,7 +21,9 @@ var authorsCmd = &cobra.Command{
 version control repositories.
 
 Use the extract subcommand to get a list of all unique authors from a
-repository, which can then be used to create author mappings for migration.`,
+repository, which can then be used to create author mappings for migration.
+Use the check subcommand to review a mapping against the repository and
+the mailmap subcommand to write a .mailmap for a migrated repository.`,
 }
 
 var authorsExtractCmd = &cobra.Command{
@@ -31,9 +37,43 @@ The output can be in plain text (one author per line) or YAML format
 	RunE: runAuthorsExtract,
 }
 
+var authorsMailmapCmd = &cobra.Command{
+	Use:   "mailmap",
+	Short: "Generate a .mailmap from the author mapping of a migration",
+	Long: `Generate a .mailmap for the repository a migration configuration
+produces. Every Git identity the author mapping gives the commits of a
+source user is mapped to the identity of their latest commit, so that
+git shortlog and git log --use-mailmap group each person's commits.`,
+	RunE: runAuthorsMailmap,
+}
+
+var authorsCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Check an author mapping against a repository",
+	Long: `Check author mapping files against the users of a repository. The
+report lists the commit count and first and last commit dates of every
+user with the author they map to, followed by users without a mapping,
+entries for users without commits, malformed entries and usernames that
+are listed more than once.
+
+Mapping files may be in any format mapping.authors_file accepts; files
+given later take precedence. With --config the source settings and author
+mapping of a migration configuration are checked, resolving users through
+its authorRules, defaultAuthor and authorHistory as the migration does;
+--authors files then take precedence over its authors. With --write the
+merged mapping is written as a YAML or authors.txt file, with a
+placeholder for every unmapped user, ready to be edited and checked again.`,
+	RunE: runAuthorsCheck,
+}
+
 var (
-	authorsSource string
-	authorsFormat string
+	authorsSource     string
+	authorsSourceType string
+	authorsFormat     string
+	authorsConfigFile string
+	authorsOutput     string
+	authorsFiles      []string
+	authorsWrite      string
 )
 
 func init() {
@@ -47,6 +87,22 @@ func init() {
 		fmt.Fprintf(os.Stderr, "Error marking flag as required: %v\n", err)
 		os.Exit(1)
 	}
+
+	authorsCmd.AddCommand(authorsCheckCmd)
+	authorsCheckCmd.Flags().StringVarP(&authorsSource, "source", "s", "", "Path to source repository")
+	authorsCheckCmd.Flags().StringVarP(&authorsSourceType, "source-type", "t", "cvs", "Source repository type (cvs or svn)")
+	authorsCheckCmd.Flags().StringArrayVarP(&authorsFiles, "authors", "a", nil, "Author mapping file to check (repeatable)")
+	authorsCheckCmd.Flags().StringVarP(&authorsWrite, "write", "w", "", "Write the merged mapping to this YAML or authors.txt file")
+	authorsCheckCmd.Flags().StringVarP(&authorsConfigFile, "config", "c", "", "Migration configuration whose source and author mapping to check")
+
+	authorsCmd.AddCommand(authorsMailmapCmd)
+	authorsMailmapCmd.Flags().StringVarP(&authorsConfigFile, "config", "c", "", "Path to migration configuration file")
+	authorsMailmapCmd.Flags().StringVarP(&authorsOutput, "output", "o", "-", "File to write the mailmap to, - for standard output")
+	err = authorsMailmapCmd.MarkFlagRequired("config")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error marking flag as required: %v\n", err)
+		os.Exit(1)
+	}
 }
 
 func runAuthorsExtract(cmd *cobra.Command, args []string) error {
@@ -104,3 +160,179 @@ func runAuthorsExtract(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+func runAuthorsMailmap(cmd *cobra.Command, args []string) error {
+	config, err := loadConfigFile(authorsConfigFile)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	am, err := authorMap(config)
+	if err != nil {
+		return err
+	}
+
+	reader, err := sourceReader(config)
+	if err != nil {
+		return err
+	}
+	if err := reader.Validate(); err != nil {
+		return fmt.Errorf("repository validation failed: %w", err)
+	}
+
+	// Collect the identities of all commits
+	commitIter, err := reader.GetCommits()
+	if err != nil {
+		return fmt.Errorf("failed to get commits: %w", err)
+	}
+	var commits []*vcs.Commit
+	for commitIter.Next() {
+		commits = append(commits, commitIter.Commit())
+	}
+	if err := commitIter.Err(); err != nil {
+		return fmt.Errorf("error iterating commits: %w", err)
+	}
+	if err := reader.Close(); err != nil {
+		return fmt.Errorf("failed to close reader: %w", err)
+	}
+
+	mailmap := mapping.NewMailmap()
+	mailmap.AddCommits(am, commits)
+
+	if authorsOutput == "-" {
+		_, err = os.Stdout.Write(mailmap.Bytes())
+		return err
+	}
+	if err := os.WriteFile(authorsOutput, mailmap.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write mailmap: %w", err)
+	}
+	fmt.Printf("Wrote %d mailmap entries to %s\n", mailmap.Len(), authorsOutput)
+	return nil
+}
+
+func runAuthorsCheck(cmd *cobra.Command, args []string) error {
+	// Start from the configuration, if any, as the migration would, but
+	// keep malformed author entries to report them
+	config := &ConfigFile{}
+	if authorsConfigFile != "" {
+		var err error
+		if config, err = readConfigFile(authorsConfigFile, mapping.ReadAuthorsFile); err != nil {
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+	} else if len(authorsFiles) == 0 {
+		return fmt.Errorf("--authors or --config is required")
+	}
+	if authorsSource != "" {
+		config.Source.Path = authorsSource
+	}
+	if config.Source.Path == "" {
+		return fmt.Errorf("--source or --config is required")
+	}
+	if config.Source.Type == "" || (cmd != nil && cmd.Flags().Changed("source-type")) {
+		config.Source.Type = authorsSourceType
+	}
+
+	entries := config.authorEntries
+	for _, file := range authorsFiles {
+		fileEntries, err := mapping.ReadAuthorsFile(file)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, fileEntries...)
+	}
+
+	reader, err := sourceReader(config)
+	if err != nil {
+		return err
+	}
+	if err := reader.Validate(); err != nil {
+		return fmt.Errorf("repository validation failed: %w", err)
+	}
+
+	commitIter, err := reader.GetCommits()
+	if err != nil {
+		return fmt.Errorf("failed to get commits: %w", err)
+	}
+	authorExtractor := mapping.NewAuthorExtractor()
+	for commitIter.Next() {
+		commit := commitIter.Commit()
+		authorExtractor.AddCommit(commit.Author, commit.Date)
+	}
+	if err := commitIter.Err(); err != nil {
+		return fmt.Errorf("error iterating commits: %w", err)
+	}
+	if err := reader.Close(); err != nil {
+		return fmt.Errorf("failed to close reader: %w", err)
+	}
+
+	report, err := mapping.CheckAuthors(entries, authorExtractor.Stats(), authorMapOptions(config))
+	if err != nil {
+		return fmt.Errorf("invalid author mapping: %w", err)
+	}
+	printAuthorReport(os.Stdout, report)
+
+	if authorsWrite != "" {
+		updated := report.Updated()
+		content, err := mapping.FormatAuthors(updated, mapping.AuthorsFileFormat(authorsWrite))
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(authorsWrite, content, 0644); err != nil {
+			return fmt.Errorf("failed to write author mapping: %w", err)
+		}
+		fmt.Printf("\nWrote %d author mappings to %s\n", len(updated), authorsWrite)
+	}
+	return nil
+}
+
+// printAuthorReport writes the users of a repository and the problems of
+// their mapping to out
+func printAuthorReport(out io.Writer, report *mapping.AuthorReport) {
+	fmt.Fprintf(out, "Users (%d):\n", len(report.Users))
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  USER\tCOMMITS\tFIRST\tLAST\tAUTHOR")
+	for _, user := range report.Users {
+		author, ok := report.Resolved[user.Username]
+		if entry, listed := report.Authors[user.Username]; listed {
+			if _, _, err := mapping.ParseAuthor(entry); err != nil {
+				author, ok = "(malformed) "+entry, true
+			}
+		}
+		if !ok {
+			author = "(unmapped)"
+		}
+		fmt.Fprintf(tw, "  %s\t%d\t%s\t%s\t%s\n", user.Username, user.Commits,
+			user.First.Format("2006-01-02"), user.Last.Format("2006-01-02"), author)
+	}
+	if err := tw.Flush(); err != nil {
+		log.Printf("Warning: failed to write report: %v", err)
+	}
+
+	if len(report.Unmapped) > 0 {
+		fmt.Fprintf(out, "\nUnmapped Users (%d):\n", len(report.Unmapped))
+		for _, username := range report.Unmapped {
+			fmt.Fprintf(out, "  - %s\n", username)
+		}
+	}
+	if len(report.Unused) > 0 {
+		fmt.Fprintf(out, "\nUnused Entries (%d):\n", len(report.Unused))
+		for _, entry := range report.Unused {
+			fmt.Fprintf(out, "  - %s: %s\n", entry.Source, entry.Username)
+		}
+	}
+	if len(report.Malformed) > 0 {
+		fmt.Fprintf(out, "\nMalformed Entries (%d):\n", len(report.Malformed))
+		for _, entry := range report.Malformed {
+			fmt.Fprintf(out, "  - %s: %s: expected \"Name <email>\", got %q\n", entry.Source, entry.Username, entry.Author)
+		}
+	}
+	if len(report.Repeated) > 0 {
+		fmt.Fprintf(out, "\nRepeated Entries (%d):\n", len(report.Repeated))
+		for _, problem := range report.Repeated {
+			fmt.Fprintf(out, "  - %s\n", problem)
+		}
+	}
+
+	if len(report.Unmapped)+len(report.Unused)+len(report.Malformed)+len(report.Repeated) == 0 {
+		fmt.Fprintln(out, "\nNo mapping problems found.")
+	}
+}
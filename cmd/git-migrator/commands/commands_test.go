@@ -5,8 +5,12 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/adamf123git/git-migrator/internal/core"
+	"github.com/adamf123git/git-migrator/internal/mapping"
+	"github.com/adamf123git/git-migrator/internal/vcs/cvs"
+	"github.com/adamf123git/git-migrator/internal/vcs/svn"
 	"github.com/stretchr/testify/require"
 )
 
@@ -68,7 +72,7 @@ func TestPrintMigrationInfo_DoesNotPanic(t *testing.T) {
 	mig := &core.MigrationConfig{SourcePath: "/src", TargetPath: "/t", AuthorMap: map[string]string{"a": "b"}}
 
 	// Call the function
-	printMigrationInfo(cfg, mig)
+	printMigrationInfo(os.Stdout, cfg, mig)
 
 	// Restore stdout
 	_ = w.Close()
@@ -104,3 +108,291 @@ func TestRunAuthorsExtract_InvalidFormat(t *testing.T) {
 	err := runAuthorsExtract(nil, nil)
 	require.Error(t, err)
 }
+
+func TestLoadConfigFile_FuzzWindow(t *testing.T) {
+	tmp := t.TempDir()
+	cfgPath := filepath.Join(tmp, "cfg.yaml")
+	content := `source:
+  type: cvs
+  path: /tmp/src
+  fuzzWindow: 2m
+target:
+  path: /tmp/target
+`
+	require.NoError(t, os.WriteFile(cfgPath, []byte(content), 0644))
+
+	cfg, err := loadConfigFile(cfgPath)
+	require.NoError(t, err)
+	require.Equal(t, 2*time.Minute, cfg.Source.FuzzWindow)
+}
+
+func TestLoadConfigFile_TargetOptions(t *testing.T) {
+	tmp := t.TempDir()
+	cfgPath := filepath.Join(tmp, "cfg.yaml")
+	content := `source:
+  type: cvs
+  path: /tmp/src
+target:
+  path: /srv/git/project.git
+  bare: true
+  treeBuilder: true
+`
+	require.NoError(t, os.WriteFile(cfgPath, []byte(content), 0644))
+
+	cfg, err := loadConfigFile(cfgPath)
+	require.NoError(t, err)
+	require.Equal(t, "git", cfg.Target.Type)
+	require.True(t, cfg.Target.Bare)
+	require.True(t, cfg.Target.TreeBuilder)
+}
+
+func TestLoadConfigFile_Mailmap(t *testing.T) {
+	tmp := t.TempDir()
+	cfgPath := filepath.Join(tmp, "cfg.yaml")
+	write := func(mode string) {
+		content := "source:\n  type: cvs\n  path: /tmp/src\ntarget:\n  path: /tmp/target\n  mailmap: " + mode + "\n"
+		require.NoError(t, os.WriteFile(cfgPath, []byte(content), 0644))
+	}
+
+	write("commit")
+	cfg, err := loadConfigFile(cfgPath)
+	require.NoError(t, err)
+	require.Equal(t, "commit", cfg.Target.Mailmap)
+
+	write("tracked")
+	_, err = loadConfigFile(cfgPath)
+	require.ErrorContains(t, err, "unsupported target.mailmap")
+}
+
+func TestLoadConfigFile_TagOptions(t *testing.T) {
+	tmp := t.TempDir()
+	write := func(mapping string) string {
+		cfgPath := filepath.Join(tmp, "cfg.yaml")
+		content := "source:\n  type: cvs\n  path: /tmp/src\ntarget:\n  path: /tmp/target\nmapping:\n" + mapping
+		require.NoError(t, os.WriteFile(cfgPath, []byte(content), 0644))
+		return cfgPath
+	}
+
+	cfg, err := loadConfigFile(write(`  tagType: annotated
+  tagMessage: "Release {tag} (CVS {cvsTag})"
+  tagger: "Release Bot <bot@example.com>"
+  tagDate: 2024-03-01T12:00:00Z
+`))
+	require.NoError(t, err)
+	require.Equal(t, "annotated", cfg.Mapping.TagType)
+	require.Equal(t, "Release {tag} (CVS {cvsTag})", cfg.Mapping.TagMessage)
+	require.Equal(t, "Release Bot <bot@example.com>", cfg.Mapping.Tagger)
+	require.Equal(t, time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), cfg.Mapping.TagDate)
+
+	_, err = loadConfigFile(write("  tagType: signed\n"))
+	require.ErrorContains(t, err, "unsupported mapping.tagType")
+
+	_, err = loadConfigFile(write("  tagger: nobody\n"))
+	require.ErrorContains(t, err, "invalid mapping.tagger")
+}
+
+func TestLoadConfigFile_AuthorRules(t *testing.T) {
+	tmp := t.TempDir()
+	write := func(mapping string) string {
+		cfgPath := filepath.Join(tmp, "cfg.yaml")
+		content := "source:\n  type: cvs\n  path: /tmp/src\ntarget:\n  path: /tmp/target\nmapping:\n" + mapping
+		require.NoError(t, os.WriteFile(cfgPath, []byte(content), 0644))
+		return cfgPath
+	}
+
+	cfg, err := loadConfigFile(write(`  authorRules:
+    - pattern: '^(buildbot|jenkins)$'
+      name: CI System
+      email: ci@example.com
+    - pattern: '^(.+)$'
+      email: '$1@company.com'
+  defaultAuthor:
+    name: Unknown Author
+    email: unknown@example.com
+  strictAuthors: true
+`))
+	require.NoError(t, err)
+	require.Equal(t, []mapping.AuthorRule{
+		{Pattern: "^(buildbot|jenkins)$", Name: "CI System", Email: "ci@example.com"},
+		{Pattern: "^(.+)$", Email: "$1@company.com"},
+	}, cfg.Mapping.AuthorRules)
+	require.Equal(t, &mapping.AuthorRule{Name: "Unknown Author", Email: "unknown@example.com"}, cfg.Mapping.DefaultAuthor)
+	require.True(t, cfg.Mapping.StrictAuthors)
+
+	cfg, err = loadConfigFile(write(`  authorHistory:
+    jsmith:
+      - author: "John Smith <jsmith@oldcorp.com>"
+        until: 2009-01-01
+      - author: "John Smith <john.smith@newcorp.com>"
+        from: 2009-01-01
+`))
+	require.NoError(t, err)
+	switchover := time.Date(2009, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.Equal(t, mapping.AuthorHistory{"jsmith": {
+		{Author: "John Smith <jsmith@oldcorp.com>", Until: switchover},
+		{Author: "John Smith <john.smith@newcorp.com>", From: switchover},
+	}}, cfg.Mapping.AuthorHistory)
+
+	_, err = loadConfigFile(write("  authorHistory:\n    jsmith:\n      - author: John\n"))
+	require.ErrorContains(t, err, "author history of jsmith")
+
+	_, err = loadConfigFile(write("  authorRules:\n    - pattern: '('\n"))
+	require.ErrorContains(t, err, "invalid author mapping")
+}
+
+func TestLoadConfigFile_AuthorsFile(t *testing.T) {
+	tmp := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmp, "authors.txt"), []byte(
+		"jsmith = John Smith <old@example.com>\nmjones = Mary Jones <mary@example.com>\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmp, "hr.csv"), []byte(
+		"username,name,email\njsmith,John Smith,john@example.com\ntwilliams,Tom Williams,tom@example.com\n"), 0644))
+
+	write := func(mapping string) string {
+		cfgPath := filepath.Join(tmp, "cfg.yaml")
+		content := "source:\n  type: cvs\n  path: /tmp/src\ntarget:\n  path: /tmp/target\nmapping:\n" + mapping
+		require.NoError(t, os.WriteFile(cfgPath, []byte(content), 0644))
+		return cfgPath
+	}
+
+	// Later files override earlier ones and inline authors override files
+	cfg, err := loadConfigFile(write(`  authors_file: [authors.txt, hr.csv]
+  authors:
+    twilliams: "Thomas Williams <tom@example.com>"
+`))
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"jsmith":    "John Smith <john@example.com>",
+		"mjones":    "Mary Jones <mary@example.com>",
+		"twilliams": "Thomas Williams <tom@example.com>",
+	}, cfg.Mapping.Authors)
+
+	// A single file may be given without a list
+	cfg, err = loadConfigFile(write("  authors_file: " + filepath.Join(tmp, "authors.txt") + "\n"))
+	require.NoError(t, err)
+	require.Equal(t, "John Smith <old@example.com>", cfg.Mapping.Authors["jsmith"])
+
+	_, err = loadConfigFile(write("  authors_file: missing.yaml\n"))
+	require.ErrorContains(t, err, "invalid mapping.authors_file")
+}
+
+func TestLoadConfigFile_RefRules(t *testing.T) {
+	tmp := t.TempDir()
+	write := func(mapping string) string {
+		cfgPath := filepath.Join(tmp, "cfg.yaml")
+		content := "source:\n  type: cvs\n  path: /tmp/src\ntarget:\n  path: /tmp/target\nmapping:\n" + mapping
+		require.NoError(t, os.WriteFile(cfgPath, []byte(content), 0644))
+		return cfgPath
+	}
+
+	cfg, err := loadConfigFile(write(`  branchRules:
+    - pattern: '^RELEASE_(\d+)_(\d+)$'
+      replace: 'release/$1.$2'
+  skipBranches: ["temp-*"]
+  tagRules:
+    - pattern: '^REL_(.*)$'
+      replace: 'v$1'
+  skipTags: ["NIGHTLY_*"]
+`))
+	require.NoError(t, err)
+	require.Equal(t, []mapping.RefRule{{Pattern: `^RELEASE_(\d+)_(\d+)$`, Replace: "release/$1.$2"}}, cfg.Mapping.BranchRules)
+	require.Equal(t, []string{"temp-*"}, cfg.Mapping.SkipBranches)
+	require.Equal(t, []mapping.RefRule{{Pattern: "^REL_(.*)$", Replace: "v$1"}}, cfg.Mapping.TagRules)
+	require.Equal(t, []string{"NIGHTLY_*"}, cfg.Mapping.SkipTags)
+
+	_, err = loadConfigFile(write("  branchRules:\n    - pattern: '('\n"))
+	require.ErrorContains(t, err, "invalid branch mapping")
+
+	_, err = loadConfigFile(write("  skipTags: ['[']\n"))
+	require.ErrorContains(t, err, "invalid tag mapping")
+}
+
+func TestSVNLayout(t *testing.T) {
+	tmp := t.TempDir()
+	cfgPath := filepath.Join(tmp, "cfg.yaml")
+	content := `source:
+  type: svn
+  path: /tmp/repo.dump
+  root: projects/alpha
+  branches:
+    - branches/*
+    - releases/*
+target:
+  path: /tmp/target
+`
+	require.NoError(t, os.WriteFile(cfgPath, []byte(content), 0644))
+
+	cfg, err := loadConfigFile(cfgPath)
+	require.NoError(t, err)
+	layout, err := svnLayout(cfg)
+	require.NoError(t, err)
+	require.Equal(t, svn.Layout{
+		Root:     "projects/alpha",
+		Trunk:    "trunk",
+		Branches: []string{"branches/*", "releases/*"},
+		Tags:     []string{"tags/*"},
+	}, layout)
+
+	cfg.Source.Layout = "none"
+	cfg.Source.Branches = nil
+	layout, err = svnLayout(cfg)
+	require.NoError(t, err)
+	require.Equal(t, svn.Layout{Root: "projects/alpha"}, layout)
+
+	cfg.Source.Layout = "flat"
+	_, err = svnLayout(cfg)
+	require.Error(t, err)
+
+	cfg.Source.Layout = ""
+	cfg.Source.Tags = []string{"tags/["}
+	_, err = svnLayout(cfg)
+	require.Error(t, err)
+}
+
+func TestLoadConfigFile_Keywords(t *testing.T) {
+	tmp := t.TempDir()
+	cfgPath := filepath.Join(tmp, "cfg.yaml")
+	write := func(source string) {
+		content := "source:\n  type: cvs\n  path: /tmp/src\n" + source + "target:\n  path: /tmp/target\n"
+		require.NoError(t, os.WriteFile(cfgPath, []byte(content), 0644))
+	}
+
+	write("  keywords: collapse\n  keywordRules:\n    - pattern: \"*.c\"\n      mode: expand\n")
+	cfg, err := loadConfigFile(cfgPath)
+	require.NoError(t, err)
+	require.Equal(t, cvs.KeywordOptions{
+		Mode:  cvs.KeywordsCollapse,
+		Rules: []cvs.KeywordRule{{Pattern: "*.c", Mode: cvs.KeywordsExpand}},
+	}, cvsKeywords(cfg))
+
+	write("  keywords: kv\n")
+	_, err = loadConfigFile(cfgPath)
+	require.ErrorContains(t, err, "invalid source.keywords")
+}
+
+func TestPrintCVSHistory(t *testing.T) {
+	applied := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	history := &cvs.History{
+		Records: []cvs.HistoryRecord{
+			{Type: cvs.HistoryCheckout, Time: applied, User: "alice", Name: "project"},
+			{Type: cvs.HistoryModified, Time: applied, User: "alice", Revision: "1.2", Name: "main.c"},
+			{Type: cvs.HistoryTag, Time: applied, User: "bob", Repository: "A", Revision: "REL_1_0", Name: "project"},
+			{Type: cvs.HistoryTag, Time: applied, User: "bob", Repository: "A", Revision: "REL_0_9", Name: "project"},
+		},
+		ValTags: []string{"REL_1_0", "OLD"},
+	}
+
+	buf := &bytes.Buffer{}
+	printCVSHistory(buf, history, []string{"stable"}, map[string]string{"REL_1_0": "abc"})
+	out := buf.String()
+	require.Contains(t, out, "Checkouts:      1\n")
+	require.Contains(t, out, "File Commits:   1\n")
+	require.Contains(t, out, "Tag Operations: 2\n")
+	require.Contains(t, out, "Tags Ever Applied (3):\n")
+	require.Contains(t, out, "  - OLD [not in repository]\n")
+	require.Contains(t, out, "  - REL_0_9 (2024-01-01 00:00:00 by bob on project) [not in repository]\n")
+	require.Contains(t, out, "  - REL_1_0 (2024-01-01 00:00:00 by bob on project)\n")
+
+	buf.Reset()
+	printCVSHistory(buf, &cvs.History{}, nil, nil)
+	require.Empty(t, buf.String())
+}
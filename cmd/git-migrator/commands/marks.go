@@ -0,0 +1,179 @@
+package commands
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/adamf123git/git-migrator/internal/core"
+	"github.com/adamf123git/git-migrator/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var marksCmd = &cobra.Command{
+	Use:   "marks",
+	Short: "Show which Git commits were created for source revisions",
+	Long: `Look up the marks recorded by a migration, which map source commits and
+file revisions to the Git commits they produced.
+
+Without flags every mark is listed, one per line, as the Git commit hash
+followed by the source commit identifier or path:revision of a file.
+
+A migration to a fast-import stream records fast-import marks such as :12
+instead of commit hashes. Pass the marks file git fast-import wrote with
+--export-marks to translate them.
+
+Example usage:
+  git-migrator marks --config config.yaml
+  git-migrator marks --config config.yaml --file src/foo.c --revision 1.42
+  git-migrator marks --config config.yaml --commit 3f2a9c1
+  git-migrator marks --config config.yaml --export-marks repo.marks`,
+	RunE: runMarks,
+}
+
+var (
+	marksConfigFile string
+	marksFile       string
+	marksRevision   string
+	marksCommit     string
+	marksExportFile string
+)
+
+func init() {
+	rootCmd.AddCommand(marksCmd)
+
+	marksCmd.Flags().StringVarP(&marksConfigFile, "config", "c", "", "Path to configuration file (required)")
+	marksCmd.Flags().StringVarP(&marksFile, "file", "f", "", "Source file path to look up")
+	marksCmd.Flags().StringVarP(&marksRevision, "revision", "r", "", "Source revision or commit identifier to look up")
+	marksCmd.Flags().StringVar(&marksCommit, "commit", "", "Git commit hash (or prefix) to find source revisions for")
+	marksCmd.Flags().StringVar(&marksExportFile, "export-marks", "", "Marks file of git fast-import, to translate fast-import marks into commit hashes")
+
+	var err = marksCmd.MarkFlagRequired("config")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error marking flag as required: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func runMarks(cmd *cobra.Command, args []string) error {
+	if marksFile != "" && marksRevision == "" {
+		return fmt.Errorf("--file requires --revision")
+	}
+
+	config, err := loadConfigFile(marksConfigFile)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	stateFile := stateFilePath(config.Target.Path)
+	if _, err := os.Stat(stateFile); err != nil {
+		return fmt.Errorf("no migration state found at %s: %w", stateFile, err)
+	}
+
+	db, err := storage.NewStateDB(stateFile)
+	if err != nil {
+		return fmt.Errorf("failed to open state database: %w", err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			log.Printf("Warning: failed to close state database: %v", err)
+		}
+	}()
+
+	migrationID := core.MigrationID(config.Source.Path, config.Target.Path)
+
+	var exported map[string]string
+	if marksExportFile != "" {
+		if exported, err = readExportedMarks(marksExportFile); err != nil {
+			return err
+		}
+	}
+
+	// Single revision lookup
+	if marksRevision != "" {
+		commit, err := db.LookupMark(migrationID, marksFile, marksRevision)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("no Git commit recorded for %s", formatSource(marksFile, marksRevision))
+		}
+		if err != nil {
+			return fmt.Errorf("failed to look up mark: %w", err)
+		}
+		if commit, err = translateMark(commit, exported); err != nil {
+			return err
+		}
+		fmt.Println(commit)
+		return nil
+	}
+
+	marks, err := db.Marks(migrationID)
+	if err != nil {
+		return fmt.Errorf("failed to load marks: %w", err)
+	}
+
+	found := false
+	for _, mark := range marks {
+		commit, err := translateMark(mark.Commit, exported)
+		if err != nil {
+			return err
+		}
+		if marksCommit != "" && !strings.HasPrefix(commit, marksCommit) && mark.Commit != marksCommit {
+			continue
+		}
+		found = true
+		fmt.Printf("%s %s\n", commit, formatSource(mark.Path, mark.Revision))
+	}
+	if marksCommit != "" && !found {
+		return fmt.Errorf("no source revisions recorded for commit %s", marksCommit)
+	}
+
+	return nil
+}
+
+// formatSource renders a source commit identifier or file revision
+func formatSource(path, revision string) string {
+	if path == "" {
+		return revision
+	}
+	return path + ":" + revision
+}
+
+// readExportedMarks reads a marks file written by git fast-import
+// --export-marks, whose lines are a mark such as :12 and a commit hash
+func readExportedMarks(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read marks file: %w", err)
+	}
+
+	marks := make(map[string]string)
+	for i, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 || !strings.HasPrefix(fields[0], ":") {
+			return nil, fmt.Errorf("%s:%d: expected a mark and a commit hash", path, i+1)
+		}
+		marks[fields[0]] = fields[1]
+	}
+	return marks, nil
+}
+
+// translateMark returns the commit hash of a recorded commit. Fast-import
+// marks are looked up in the exported marks, which must be given.
+func translateMark(commit string, exported map[string]string) (string, error) {
+	if !strings.HasPrefix(commit, ":") {
+		return commit, nil
+	}
+	if exported == nil {
+		return "", fmt.Errorf("%s is a fast-import mark; pass the marks file of git fast-import --export-marks with --export-marks", commit)
+	}
+	hash, ok := exported[commit]
+	if !ok {
+		return "", fmt.Errorf("fast-import mark %s is not in the exported marks", commit)
+	}
+	return hash, nil
+}
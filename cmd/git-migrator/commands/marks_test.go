@@ -0,0 +1,126 @@
+package commands
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/adamf123git/git-migrator/internal/core"
+	"github.com/adamf123git/git-migrator/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func setupMarks(t *testing.T) {
+	t.Helper()
+
+	src := makeEmptyCVSRepo(t)
+	tgt := filepath.Join(t.TempDir(), "repo")
+	cfgPath := filepath.Join(t.TempDir(), "cfg.yaml")
+	cfg := "source:\n  type: cvs\n  path: " + src + "\ntarget:\n  path: " + tgt + "\n"
+	require.NoError(t, os.WriteFile(cfgPath, []byte(cfg), 0644))
+
+	db, err := storage.NewStateDB(stateFilePath(tgt))
+	require.NoError(t, err)
+	require.NoError(t, db.SaveMarks(core.MigrationID(src, tgt), []storage.Mark{
+		{Revision: "0123456789abcdef", Commit: "3f2a9c1e"},
+		{Revision: "1.42", Path: "src/foo.c", Commit: "3f2a9c1e"},
+	}))
+	require.NoError(t, db.Close())
+
+	old := []string{marksConfigFile, marksFile, marksRevision, marksCommit, marksExportFile}
+	marksConfigFile = cfgPath
+	t.Cleanup(func() {
+		marksConfigFile, marksFile, marksRevision, marksCommit, marksExportFile = old[0], old[1], old[2], old[3], old[4]
+	})
+}
+
+func TestRunMarks_ListAndLookup(t *testing.T) {
+	setupMarks(t)
+
+	require.NoError(t, runMarks(nil, nil))
+
+	marksFile, marksRevision = "src/foo.c", "1.42"
+	require.NoError(t, runMarks(nil, nil))
+
+	marksFile, marksRevision = "", "0123456789abcdef"
+	require.NoError(t, runMarks(nil, nil))
+
+	marksRevision, marksCommit = "", "3f2a"
+	require.NoError(t, runMarks(nil, nil))
+}
+
+func TestRunMarks_NotFound(t *testing.T) {
+	setupMarks(t)
+
+	marksFile, marksRevision = "src/foo.c", "1.43"
+	err := runMarks(nil, nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "src/foo.c:1.43")
+
+	marksFile, marksRevision, marksCommit = "", "", "ffff"
+	require.Error(t, runMarks(nil, nil))
+
+	marksFile, marksCommit = "src/foo.c", ""
+	err = runMarks(nil, nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "--file requires --revision")
+}
+
+func TestRunMarks_NoState(t *testing.T) {
+	src := makeEmptyCVSRepo(t)
+	cfgPath := filepath.Join(t.TempDir(), "cfg.yaml")
+	cfg := "source:\n  type: cvs\n  path: " + src + "\ntarget:\n  path: " + filepath.Join(t.TempDir(), "repo") + "\n"
+	require.NoError(t, os.WriteFile(cfgPath, []byte(cfg), 0644))
+
+	old := marksConfigFile
+	marksConfigFile = cfgPath
+	defer func() { marksConfigFile = old }()
+
+	err := runMarks(nil, nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "no migration state found")
+}
+
+func TestRunMarks_FastImport(t *testing.T) {
+	setupMarks(t)
+	config, err := loadConfigFile(marksConfigFile)
+	require.NoError(t, err)
+	db, err := storage.NewStateDB(stateFilePath(config.Target.Path))
+	require.NoError(t, err)
+	require.NoError(t, db.SaveMarks(core.MigrationID(config.Source.Path, config.Target.Path), []storage.Mark{
+		{Revision: "1.43", Path: "src/foo.c", Commit: ":12"},
+	}))
+	require.NoError(t, db.Close())
+
+	run := func() (string, error) {
+		orig := os.Stdout
+		r, w, _ := os.Pipe()
+		os.Stdout = w
+		err := runMarks(nil, nil)
+		_ = w.Close()
+		os.Stdout = orig
+		out, _ := io.ReadAll(r)
+		return string(out), err
+	}
+
+	// Marks cannot be reported as commits without the exported marks
+	marksFile, marksRevision = "src/foo.c", "1.43"
+	_, err = run()
+	require.ErrorContains(t, err, "--export-marks")
+
+	marksExportFile = filepath.Join(t.TempDir(), "repo.marks")
+	require.NoError(t, os.WriteFile(marksExportFile, []byte(":12 9d4c0ffee9d4c0ffee9d4c0ffee9d4c0ffee9d4\n"), 0644))
+	out, err := run()
+	require.NoError(t, err)
+	require.Equal(t, "9d4c0ffee9d4c0ffee9d4c0ffee9d4c0ffee9d4\n", out)
+
+	marksFile, marksRevision, marksCommit = "", "", "9d4c"
+	out, err = run()
+	require.NoError(t, err)
+	require.Equal(t, "9d4c0ffee9d4c0ffee9d4c0ffee9d4c0ffee9d4 src/foo.c:1.43\n", out)
+
+	require.NoError(t, os.WriteFile(marksExportFile, []byte("not a mark\n"), 0644))
+	_, err = run()
+	require.ErrorContains(t, err, "expected a mark and a commit hash")
+}
@@ -2,10 +2,20 @@ package commands
 
 import (
 	"fmt"
+	"io"
+	"log"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/adamf123git/git-migrator/internal/core"
+	"github.com/adamf123git/git-migrator/internal/mapping"
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	"github.com/adamf123git/git-migrator/internal/vcs/cvs"
+	"github.com/adamf123git/git-migrator/internal/vcs/fastimport"
+	"github.com/adamf123git/git-migrator/internal/vcs/svn"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
 )
@@ -41,21 +51,48 @@ var (
 // ConfigFile represents the YAML configuration file structure
 type ConfigFile struct {
 	Source struct {
-		Type   string `yaml:"type"`
-		Path   string `yaml:"path"`
-		Module string `yaml:"module"`
+		Type           string            `yaml:"type"`
+		Path           string            `yaml:"path"`
+		Module         string            `yaml:"module"`
+		ExcludeModules []string          `yaml:"excludeModules"`
+		FuzzWindow     time.Duration     `yaml:"fuzzWindow"`
+		Keywords       string            `yaml:"keywords"`
+		KeywordRules   []cvs.KeywordRule `yaml:"keywordRules"`
+		VendorBranch   string            `yaml:"vendorBranch"`
+		Layout         string            `yaml:"layout"`
+		Root           string            `yaml:"root"`
+		Trunk          string            `yaml:"trunk"`
+		Branches       []string          `yaml:"branches"`
+		Tags           []string          `yaml:"tags"`
 	} `yaml:"source"`
 
 	Target struct {
-		Type   string `yaml:"type"`
-		Path   string `yaml:"path"`
-		Remote string `yaml:"remote"`
+		Type          string `yaml:"type"`
+		Path          string `yaml:"path"`
+		Remote        string `yaml:"remote"`
+		Bare          bool   `yaml:"bare"`
+		TreeBuilder   bool   `yaml:"treeBuilder"`
+		InitialBranch string `yaml:"initialBranch"`
+		Mailmap       string `yaml:"mailmap"`
 	} `yaml:"target"`
 
 	Mapping struct {
-		Authors  map[string]string `yaml:"authors"`
-		Branches map[string]string `yaml:"branches"`
-		Tags     map[string]string `yaml:"tags"`
+		Authors       map[string]string     `yaml:"authors"`
+		AuthorsFile   pathList              `yaml:"authors_file"`
+		AuthorHistory mapping.AuthorHistory `yaml:"authorHistory"`
+		AuthorRules   []mapping.AuthorRule  `yaml:"authorRules"`
+		DefaultAuthor *mapping.AuthorRule   `yaml:"defaultAuthor"`
+		StrictAuthors bool                  `yaml:"strictAuthors"`
+		Branches      map[string]string     `yaml:"branches"`
+		BranchRules   []mapping.RefRule     `yaml:"branchRules"`
+		SkipBranches  []string              `yaml:"skipBranches"`
+		Tags          map[string]string     `yaml:"tags"`
+		TagRules      []mapping.RefRule     `yaml:"tagRules"`
+		SkipTags      []string              `yaml:"skipTags"`
+		TagType       string                `yaml:"tagType"`
+		TagMessage    string                `yaml:"tagMessage"`
+		Tagger        string                `yaml:"tagger"`
+		TagDate       time.Time             `yaml:"tagDate"`
 	} `yaml:"mapping"`
 
 	Options struct {
@@ -64,6 +101,9 @@ type ConfigFile struct {
 		ChunkSize int  `yaml:"chunkSize"`
 		Resume    bool `yaml:"resume"`
 	} `yaml:"options"`
+
+	// Entries of the author files and inline authors, in precedence order
+	authorEntries []mapping.AuthorEntry
 }
 
 func init() {
@@ -101,15 +141,45 @@ func runMigrate(cmd *cobra.Command, args []string) error {
 
 	// Convert config file to migration config
 	migrationConfig := &core.MigrationConfig{
-		SourceType: config.Source.Type,
-		SourcePath: config.Source.Path,
-		TargetPath: config.Target.Path,
-		AuthorMap:  config.Mapping.Authors,
-		BranchMap:  config.Mapping.Branches,
-		TagMap:     config.Mapping.Tags,
-		DryRun:     config.Options.DryRun,
-		Resume:     config.Options.Resume,
-		ChunkSize:  config.Options.ChunkSize,
+		SourceType:     config.Source.Type,
+		SourcePath:     config.Source.Path,
+		FuzzWindow:     config.Source.FuzzWindow,
+		Keywords:       cvsKeywords(config),
+		VendorBranch:   config.Source.VendorBranch,
+		Module:         config.Source.Module,
+		ExcludeModules: config.Source.ExcludeModules,
+		TargetType:     config.Target.Type,
+		TargetPath:     config.Target.Path,
+		TreeBuilder:    config.Target.TreeBuilder,
+		Bare:           config.Target.Bare,
+		InitialBranch:  config.Target.InitialBranch,
+		Mailmap:        config.Target.Mailmap,
+		AuthorMap:      config.Mapping.Authors,
+		AuthorHistory:  config.Mapping.AuthorHistory,
+		AuthorRules:    config.Mapping.AuthorRules,
+		DefaultAuthor:  config.Mapping.DefaultAuthor,
+		StrictAuthors:  config.Mapping.StrictAuthors,
+		BranchMap:      config.Mapping.Branches,
+		BranchRules:    config.Mapping.BranchRules,
+		SkipBranches:   config.Mapping.SkipBranches,
+		TagMap:         config.Mapping.Tags,
+		TagRules:       config.Mapping.TagRules,
+		SkipTags:       config.Mapping.SkipTags,
+		TagType:        config.Mapping.TagType,
+		TagMessage:     config.Mapping.TagMessage,
+		Tagger:         config.Mapping.Tagger,
+		TagDate:        config.Mapping.TagDate,
+		DryRun:         config.Options.DryRun,
+		Resume:         config.Options.Resume,
+		ChunkSize:      config.Options.ChunkSize,
+	}
+
+	if config.Source.Type == "svn" {
+		layout, err := svnLayout(config)
+		if err != nil {
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+		migrationConfig.SVNLayout = layout
 	}
 
 	// Set default chunk size if not specified
@@ -118,41 +188,84 @@ func runMigrate(cmd *cobra.Command, args []string) error {
 	}
 
 	// Set state file path
-	stateFile := filepath.Join(
-		filepath.Dir(migrationConfig.TargetPath),
-		".git-migrator-state.db",
-	)
-	migrationConfig.StateFile = stateFile
+	migrationConfig.StateFile = stateFilePath(migrationConfig.TargetPath)
+
+	// A fast-import stream written to stdout must not be mixed with messages
+	out := io.Writer(os.Stdout)
+	if config.Target.Type == "fast-import" && config.Target.Path == fastimport.Stdout {
+		out = os.Stderr
+	}
 
 	// Display migration information
 	if config.Options.Verbose || config.Options.DryRun {
-		printMigrationInfo(config, migrationConfig)
+		printMigrationInfo(out, config, migrationConfig)
 	}
 
 	if config.Options.DryRun {
-		fmt.Println("\n🔍 DRY RUN MODE - No changes will be made")
+		fmt.Fprintln(out, "\n🔍 DRY RUN MODE - No changes will be made")
 	}
 
 	// Create migrator
 	migrator := core.NewMigrator(migrationConfig)
 
 	// Run migration
-	fmt.Println("\nStarting migration...")
+	fmt.Fprintln(out, "\nStarting migration...")
 	if err := migrator.Run(); err != nil {
 		return fmt.Errorf("migration failed: %w", err)
 	}
 
 	if config.Options.DryRun {
-		fmt.Println("\n✓ Dry run completed successfully")
-		fmt.Println("Run without --dry-run to perform actual migration")
+		fmt.Fprintln(out, "\n✓ Dry run completed successfully")
+		fmt.Fprintln(out, "Run without --dry-run to perform actual migration")
 	} else {
-		fmt.Println("\n✓ Migration completed successfully!")
+		fmt.Fprintln(out, "\n✓ Migration completed successfully!")
 	}
 
 	return nil
 }
 
+// svnLayout builds the SVN repository layout from the source section. The
+// standard layout applies unless disabled, and configured directories
+// replace its defaults.
+func svnLayout(config *ConfigFile) (svn.Layout, error) {
+	var layout svn.Layout
+	switch config.Source.Layout {
+	case "", "standard":
+		layout = svn.StandardLayout
+	case "none":
+	default:
+		return svn.Layout{}, fmt.Errorf("unsupported source.layout: %s (supported: standard, none)", config.Source.Layout)
+	}
+
+	layout.Root = config.Source.Root
+	if config.Source.Trunk != "" {
+		layout.Trunk = config.Source.Trunk
+	}
+	if config.Source.Branches != nil {
+		layout.Branches = config.Source.Branches
+	}
+	if config.Source.Tags != nil {
+		layout.Tags = config.Source.Tags
+	}
+
+	if err := layout.Check(); err != nil {
+		return svn.Layout{}, err
+	}
+	return layout, nil
+}
+
+// stateFilePath returns the state database location for a target repository
+func stateFilePath(targetPath string) string {
+	return filepath.Join(filepath.Dir(targetPath), ".git-migrator-state.db")
+}
+
 func loadConfigFile(path string) (*ConfigFile, error) {
+	return readConfigFile(path, mapping.LoadAuthorsFile)
+}
+
+// readConfigFile loads a configuration, reading its author files with
+// readAuthors
+func readConfigFile(path string, readAuthors func(string) ([]mapping.AuthorEntry, error)) (*ConfigFile, error) {
 	// Read file
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -176,6 +289,37 @@ func loadConfigFile(path string) (*ConfigFile, error) {
 		return nil, fmt.Errorf("target.path is required")
 	}
 
+	switch config.Target.Mailmap {
+	case "", "commit", "untracked":
+	default:
+		return nil, fmt.Errorf("unsupported target.mailmap: %s (supported: commit, untracked)", config.Target.Mailmap)
+	}
+
+	switch config.Mapping.TagType {
+	case "", "lightweight", "annotated":
+	default:
+		return nil, fmt.Errorf("unsupported mapping.tagType: %s (supported: lightweight, annotated)", config.Mapping.TagType)
+	}
+	if config.Mapping.Tagger != "" {
+		if _, _, err := mapping.ParseAuthor(config.Mapping.Tagger); err != nil {
+			return nil, fmt.Errorf("invalid mapping.tagger: %w", err)
+		}
+	}
+
+	if err := cvsKeywords(&config).Check(); err != nil {
+		return nil, fmt.Errorf("invalid source.keywords: %w", err)
+	}
+
+	if err := loadAuthorsFiles(path, &config, readAuthors); err != nil {
+		return nil, err
+	}
+	if _, err := authorMap(&config); err != nil {
+		return nil, err
+	}
+	if _, _, err := refMaps(&config); err != nil {
+		return nil, err
+	}
+
 	// Set defaults
 	if config.Target.Type == "" {
 		config.Target.Type = "git"
@@ -193,46 +337,242 @@ func loadConfigFile(path string) (*ConfigFile, error) {
 	return &config, nil
 }
 
-func printMigrationInfo(config *ConfigFile, migrationConfig *core.MigrationConfig) {
-	fmt.Println("\nMigration Configuration")
-	fmt.Println("======================")
-	fmt.Printf("Source Type:    %s\n", config.Source.Type)
-	fmt.Printf("Source Path:    %s\n", config.Source.Path)
+// pathList is a list of paths that may also be written as a single path
+type pathList []string
+
+// UnmarshalYAML accepts a single path or a list of paths
+func (p *pathList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*p = pathList{value.Value}
+		return nil
+	}
+	var paths []string
+	if err := value.Decode(&paths); err != nil {
+		return err
+	}
+	*p = paths
+	return nil
+}
+
+// loadAuthorsFiles merges the author files of a configuration into its
+// author mapping. Files listed later take precedence over earlier ones,
+// and inline authors over all files. Relative paths are relative to the
+// configuration file.
+func loadAuthorsFiles(configPath string, config *ConfigFile, readAuthors func(string) ([]mapping.AuthorEntry, error)) error {
+	var entries []mapping.AuthorEntry
+	for _, file := range config.Mapping.AuthorsFile {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(filepath.Dir(configPath), file)
+		}
+		fileEntries, err := readAuthors(file)
+		if err != nil {
+			return fmt.Errorf("invalid mapping.authors_file: %w", err)
+		}
+		entries = append(entries, fileEntries...)
+	}
+
+	usernames := make([]string, 0, len(config.Mapping.Authors))
+	for username := range config.Mapping.Authors {
+		usernames = append(usernames, username)
+	}
+	sort.Strings(usernames)
+	for _, username := range usernames {
+		entries = append(entries, mapping.AuthorEntry{
+			Username: username,
+			Author:   config.Mapping.Authors[username],
+			Source:   configPath,
+		})
+	}
+
+	config.authorEntries = entries
+	if len(config.Mapping.AuthorsFile) == 0 {
+		return nil
+	}
+
+	authors, problems := mapping.MergeAuthors(entries)
+	for _, problem := range problems {
+		log.Printf("Warning: %s", problem)
+	}
+	config.Mapping.Authors = authors
+	return nil
+}
+
+// authorMap builds the author mapping of a configuration
+func authorMap(config *ConfigFile) (*mapping.AuthorMap, error) {
+	am, err := mapping.NewAuthorMapWithOptions(config.Mapping.Authors, authorMapOptions(config))
+	if err != nil {
+		return nil, fmt.Errorf("invalid author mapping: %w", err)
+	}
+	return am, nil
+}
+
+// authorMapOptions returns how a configuration maps users that have no
+// direct mapping
+func authorMapOptions(config *ConfigFile) mapping.AuthorMapOptions {
+	return mapping.AuthorMapOptions{
+		History: config.Mapping.AuthorHistory,
+		Rules:   config.Mapping.AuthorRules,
+		Default: config.Mapping.DefaultAuthor,
+	}
+}
+
+// sourceReader creates a reader for the source repository of a configuration
+func sourceReader(config *ConfigFile) (vcs.VCSReader, error) {
+	switch config.Source.Type {
+	case "cvs":
+		return cvs.NewReaderWithOptions(config.Source.Path, cvs.ReaderOptions{
+			FuzzWindow:     config.Source.FuzzWindow,
+			Keywords:       cvsKeywords(config),
+			VendorBranch:   config.Source.VendorBranch,
+			Module:         config.Source.Module,
+			ExcludeModules: config.Source.ExcludeModules,
+		}), nil
+	case "svn":
+		layout, err := svnLayout(config)
+		if err != nil {
+			return nil, err
+		}
+		return svn.NewReaderWithOptions(config.Source.Path, svn.ReaderOptions{Layout: layout}), nil
+	default:
+		return nil, fmt.Errorf("unsupported source type: %s (supported: cvs, svn)", config.Source.Type)
+	}
+}
+
+// cvsKeywords returns the keyword options of a configuration
+func cvsKeywords(config *ConfigFile) cvs.KeywordOptions {
+	return cvs.KeywordOptions{Mode: config.Source.Keywords, Rules: config.Source.KeywordRules}
+}
+
+// refMaps compiles the branch and tag mappings of a configuration
+func refMaps(config *ConfigFile) (*mapping.RefMap, *mapping.RefMap, error) {
+	branches, err := mapping.NewBranchMap(config.Mapping.Branches, config.Mapping.BranchRules, config.Mapping.SkipBranches)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid branch mapping: %w", err)
+	}
+	tags, err := mapping.NewTagMap(config.Mapping.Tags, config.Mapping.TagRules, config.Mapping.SkipTags)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid tag mapping: %w", err)
+	}
+	return branches, tags, nil
+}
+
+// printMigrationInfo writes the effective configuration to out
+func printMigrationInfo(out io.Writer, config *ConfigFile, migrationConfig *core.MigrationConfig) {
+	fmt.Fprintln(out, "\nMigration Configuration")
+	fmt.Fprintln(out, "======================")
+	fmt.Fprintf(out, "Source Type:    %s\n", config.Source.Type)
+	fmt.Fprintf(out, "Source Path:    %s\n", config.Source.Path)
 	if config.Source.Module != "" {
-		fmt.Printf("Source Module:  %s\n", config.Source.Module)
+		fmt.Fprintf(out, "Source Module:  %s\n", config.Source.Module)
+	}
+	if len(config.Source.ExcludeModules) > 0 {
+		fmt.Fprintf(out, "Excluded:       %s\n", strings.Join(config.Source.ExcludeModules, ", "))
+	}
+	if config.Source.Keywords != "" || len(config.Source.KeywordRules) > 0 {
+		mode := config.Source.Keywords
+		if mode == "" {
+			mode = cvs.KeywordsRaw
+		}
+		fmt.Fprintf(out, "Keywords:       %s\n", mode)
+		for _, rule := range config.Source.KeywordRules {
+			fmt.Fprintf(out, "  %s: %s\n", rule.Pattern, rule.Mode)
+		}
+	}
+	if config.Source.VendorBranch != "" {
+		fmt.Fprintf(out, "Vendor Branch:  %s\n", config.Source.VendorBranch)
+	}
+	if config.Source.Type == "svn" {
+		layout := migrationConfig.SVNLayout
+		if layout.Root != "" {
+			fmt.Fprintf(out, "SVN Root:       %s\n", layout.Root)
+		}
+		fmt.Fprintf(out, "SVN Trunk:      %s\n", layout.Trunk)
+		fmt.Fprintf(out, "SVN Branches:   %s\n", strings.Join(layout.Branches, ", "))
+		fmt.Fprintf(out, "SVN Tags:       %s\n", strings.Join(layout.Tags, ", "))
 	}
-	fmt.Printf("Target Path:    %s\n", config.Target.Path)
+	fmt.Fprintf(out, "Target Path:    %s\n", config.Target.Path)
 	if config.Target.Remote != "" {
-		fmt.Printf("Target Remote:  %s\n", config.Target.Remote)
+		fmt.Fprintf(out, "Target Remote:  %s\n", config.Target.Remote)
+	}
+	if config.Target.InitialBranch != "" {
+		fmt.Fprintf(out, "Initial Branch: %s\n", config.Target.InitialBranch)
+	}
+	if config.Target.Bare {
+		fmt.Fprintf(out, "Bare:           %v\n", config.Target.Bare)
 	}
-	fmt.Printf("Dry Run:        %v\n", config.Options.DryRun)
-	fmt.Printf("Resume:         %v\n", config.Options.Resume)
-	fmt.Printf("Chunk Size:     %d\n", config.Options.ChunkSize)
+	if config.Target.TreeBuilder {
+		fmt.Fprintf(out, "Tree Builder:   %v\n", config.Target.TreeBuilder)
+	}
+	if config.Target.Mailmap != "" {
+		fmt.Fprintf(out, "Mailmap:        %s\n", config.Target.Mailmap)
+	}
+	fmt.Fprintf(out, "Dry Run:        %v\n", config.Options.DryRun)
+	fmt.Fprintf(out, "Resume:         %v\n", config.Options.Resume)
+	fmt.Fprintf(out, "Chunk Size:     %d\n", config.Options.ChunkSize)
 
 	if len(config.Mapping.Authors) > 0 {
-		fmt.Printf("\nAuthor Mappings: %d\n", len(config.Mapping.Authors))
+		fmt.Fprintf(out, "\nAuthor Mappings: %d\n", len(config.Mapping.Authors))
 		if config.Options.Verbose {
 			for cvs, git := range config.Mapping.Authors {
-				fmt.Printf("  %s -> %s\n", cvs, git)
+				fmt.Fprintf(out, "  %s -> %s\n", cvs, git)
+			}
+		}
+	}
+
+	if len(config.Mapping.AuthorHistory) > 0 {
+		fmt.Fprintf(out, "Author Histories: %d\n", len(config.Mapping.AuthorHistory))
+	}
+	if len(config.Mapping.AuthorRules) > 0 {
+		fmt.Fprintf(out, "\nAuthor Rules: %d\n", len(config.Mapping.AuthorRules))
+		if config.Options.Verbose {
+			for _, rule := range config.Mapping.AuthorRules {
+				fmt.Fprintf(out, "  %s -> %s <%s>\n", rule.Pattern, rule.Name, rule.Email)
 			}
 		}
 	}
+	if author := config.Mapping.DefaultAuthor; author != nil {
+		fmt.Fprintf(out, "Default Author: %s <%s>\n", author.Name, author.Email)
+	}
+	if config.Mapping.StrictAuthors {
+		fmt.Fprintf(out, "Strict Authors: %v\n", config.Mapping.StrictAuthors)
+	}
 
 	if len(config.Mapping.Branches) > 0 {
-		fmt.Printf("\nBranch Mappings: %d\n", len(config.Mapping.Branches))
+		fmt.Fprintf(out, "\nBranch Mappings: %d\n", len(config.Mapping.Branches))
 		if config.Options.Verbose {
 			for cvs, git := range config.Mapping.Branches {
-				fmt.Printf("  %s -> %s\n", cvs, git)
+				fmt.Fprintf(out, "  %s -> %s\n", cvs, git)
 			}
 		}
 	}
+	printRefRules(out, "Branch", config.Mapping.BranchRules, config.Mapping.SkipBranches, config.Options.Verbose)
+
+	if config.Mapping.TagType != "" {
+		fmt.Fprintf(out, "\nTag Type: %s\n", config.Mapping.TagType)
+	}
 
 	if len(config.Mapping.Tags) > 0 {
-		fmt.Printf("\nTag Mappings: %d\n", len(config.Mapping.Tags))
+		fmt.Fprintf(out, "\nTag Mappings: %d\n", len(config.Mapping.Tags))
 		if config.Options.Verbose {
 			for cvs, git := range config.Mapping.Tags {
-				fmt.Printf("  %s -> %s\n", cvs, git)
+				fmt.Fprintf(out, "  %s -> %s\n", cvs, git)
 			}
 		}
 	}
+	printRefRules(out, "Tag", config.Mapping.TagRules, config.Mapping.SkipTags, config.Options.Verbose)
+}
+
+// printRefRules writes the renaming rules and skip patterns of branches or tags
+func printRefRules(out io.Writer, kind string, rules []mapping.RefRule, skip []string, verbose bool) {
+	if len(rules) > 0 {
+		fmt.Fprintf(out, "\n%s Rules: %d\n", kind, len(rules))
+		if verbose {
+			for _, rule := range rules {
+				fmt.Fprintf(out, "  %s -> %s\n", rule.Pattern, rule.Replace)
+			}
+		}
+	}
+	if len(skip) > 0 {
+		fmt.Fprintf(out, "\nSkipped %ss: %s\n", kind, strings.Join(skip, ", "))
+	}
 }
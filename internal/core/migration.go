@@ -8,42 +8,98 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/adamf123git/git-migrator/internal/mapping"
 	"github.com/adamf123git/git-migrator/internal/progress"
 	"github.com/adamf123git/git-migrator/internal/storage"
 	"github.com/adamf123git/git-migrator/internal/vcs"
 	"github.com/adamf123git/git-migrator/internal/vcs/cvs"
+	"github.com/adamf123git/git-migrator/internal/vcs/fastimport"
 	"github.com/adamf123git/git-migrator/internal/vcs/git"
+	"github.com/adamf123git/git-migrator/internal/vcs/svn"
 )
 
 // MigrationConfig holds migration configuration
 type MigrationConfig struct {
-	SourceType  string            // cvs, svn
-	SourcePath  string            // Path to source repo
-	TargetPath  string            // Path to target Git repo
-	AuthorMap   map[string]string // CVS user -> "Name <email>"
-	BranchMap   map[string]string // CVS branch -> Git branch
-	TagMap      map[string]string // CVS tag -> Git tag
-	DryRun      bool              // Preview without changes
-	Resume      bool              // Resume from last checkpoint
-	StateFile   string            // Path to state file
-	ChunkSize   int               // Save state every N commits
-	InterruptAt int               // For testing: interrupt after N commits
+	SourceType     string                // cvs, svn
+	SourcePath     string                // Path to source repo
+	FuzzWindow     time.Duration         // Max gap between file revisions of one CVS commit
+	Keywords       cvs.KeywordOptions    // How RCS keywords are written to CVS file contents
+	VendorBranch   string                // Branch that CVS vendor imports are also written to
+	Module         string                // CVS module, directory or file to migrate
+	ExcludeModules []string              // CVS modules, directories or files to leave out
+	SVNLayout      svn.Layout            // Trunk, branch and tag directories of an SVN repository
+	TargetType     string                // git (default), fast-import
+	TargetPath     string                // Path to target Git repo, or fast-import stream file
+	TreeBuilder    bool                  // Write Git objects directly instead of through the worktree
+	Bare           bool                  // Create the target Git repo without a worktree
+	InitialBranch  string                // Git branch for trunk commits (default: master)
+	Mailmap        string                // Write a .mailmap: commit, untracked (default: none)
+	AuthorMap      map[string]string     // CVS user -> "Name <email>"
+	AuthorHistory  mapping.AuthorHistory // CVS user -> identities by commit date
+	AuthorRules    []mapping.AuthorRule  // Ordered author mapping rules
+	DefaultAuthor  *mapping.AuthorRule   // Identity of users no mapping or rule maps
+	StrictAuthors  bool                  // Fail if any user is not mapped
+	BranchMap      map[string]string     // CVS branch -> Git branch; MAIN or HEAD maps the trunk
+	BranchRules    []mapping.RefRule     // Ordered branch renaming rules
+	SkipBranches   []string              // Glob patterns of branches not to migrate
+	TagMap         map[string]string     // CVS tag -> Git tag
+	TagRules       []mapping.RefRule     // Ordered tag renaming rules
+	SkipTags       []string              // Glob patterns of tags not to migrate
+	TagType        string                // lightweight (default), annotated
+	TagMessage     string                // Message template of annotated tags
+	Tagger         string                // "Name <email>" of annotated tags (default: author of the tagged commit)
+	TagDate        time.Time             // Date of annotated tags (default: latest tagged file's date)
+	DryRun         bool                  // Preview without changes
+	Resume         bool                  // Resume from last checkpoint
+	StateFile      string                // Path to state file
+	ChunkSize      int                   // Save state every N commits
+	InterruptAt    int                   // For testing: interrupt after N commits
 }
 
 // Migrator orchestrates the migration process
 type Migrator struct {
 	config    *MigrationConfig
 	source    vcs.VCSReader
-	target    *git.Writer
+	target    targetWriter
 	authorMap *mapping.AuthorMap
+	branchMap *mapping.RefMap
+	tagMap    *mapping.RefMap
 	reporter  *progress.Reporter
 	state     *MigrationState
 	db        *storage.StateDB
+	marks     map[string]string // Source revision -> Git commit hash
 }
 
+// targetWriter is what the migrator needs from a target on top of VCSWriter
+type targetWriter interface {
+	vcs.VCSWriter
+	ApplyCommitToBranch(commit *vcs.Commit, branch, parent string) error
+	CheckoutBranch(name string) error
+	DeleteBranch(name string) error
+	BranchExists(name string) bool
+	LastCommitHash() string
+	CreateAnnotatedTag(name, revision, message string, tagger vcs.Signature) error
+}
+
+const (
+	// defaultTrunkBranch is the Git branch that receives trunk commits
+	// unless configured otherwise
+	defaultTrunkBranch = "master"
+
+	// fixupBranch temporarily holds synthetic commits created for tags
+	fixupBranch = "TAG.FIXUP"
+
+	// defaultTagMessage is the message template of annotated tags
+	defaultTagMessage = "Tag {tag}"
+
+	// migratorAuthor is the source user of commits the migration adds
+	migratorAuthor = "git-migrator"
+)
+
 // NewMigrator creates a new migrator
 func NewMigrator(config *MigrationConfig) *Migrator {
 	return &Migrator{
@@ -67,23 +123,23 @@ func (m *Migrator) Run() error {
 		return fmt.Errorf("source validation failed: %w", err)
 	}
 
-	// Initialize target
-	if !m.config.DryRun {
-		if err := m.initTarget(); err != nil {
-			return fmt.Errorf("failed to init target: %w", err)
-		}
-		defer func() {
-			if err := m.target.Close(); err != nil {
-				// Log error but don't fail - cleanup is best effort
-				log.Printf("Warning: failed to close target repository: %v", err)
-			}
-		}()
+	// Check branch and tag names before anything is written
+	if err := m.checkRefNames(); err != nil {
+		return err
+	}
+	if err := m.checkMailmap(); err != nil {
+		return err
 	}
 
-	// Initialize state
-	if err := m.initState(); err != nil {
-		return fmt.Errorf("failed to init state: %w", err)
+	authorMap, err := mapping.NewAuthorMapWithOptions(m.config.AuthorMap, mapping.AuthorMapOptions{
+		History: m.config.AuthorHistory,
+		Rules:   m.config.AuthorRules,
+		Default: m.config.DefaultAuthor,
+	})
+	if err != nil {
+		return fmt.Errorf("invalid author mapping: %w", err)
 	}
+	m.authorMap = authorMap
 
 	// Get commits from source
 	iter, err := m.source.GetCommits()
@@ -100,6 +156,37 @@ func (m *Migrator) Run() error {
 		return fmt.Errorf("iterator error: %w", err)
 	}
 
+	if m.config.StrictAuthors {
+		if err := m.checkAuthors(commits); err != nil {
+			return err
+		}
+	}
+
+	// Author identities are collected before commits are rewritten
+	var mailmap *mapping.Mailmap
+	if m.config.Mailmap != "" {
+		mailmap = mapping.NewMailmap()
+		mailmap.AddCommits(m.authorMap, commits)
+	}
+
+	// Initialize target
+	if !m.config.DryRun {
+		if err := m.initTarget(); err != nil {
+			return fmt.Errorf("failed to init target: %w", err)
+		}
+		defer func() {
+			if err := m.target.Close(); err != nil {
+				// Log error but don't fail - cleanup is best effort
+				log.Printf("Warning: failed to close target repository: %v", err)
+			}
+		}()
+	}
+
+	// Initialize state
+	if err := m.initState(); err != nil {
+		return fmt.Errorf("failed to init state: %w", err)
+	}
+
 	m.reporter = progress.NewReporter(len(commits))
 	m.reporter.Start()
 	m.reporter.SetOperation("Starting migration")
@@ -127,14 +214,21 @@ func (m *Migrator) Run() error {
 		}
 		m.reporter.SetOperation(fmt.Sprintf("Processing commit %s", rev))
 
-		// Map author
-		name, email := m.authorMap.Get(commit.Author)
+		// Marks are saved with every commit but the state only every chunk,
+		// so commits after the last saved state may already be migrated
+		if _, ok := m.marks[commit.Revision]; ok && m.config.Resume {
+			m.reporter.Increment()
+			continue
+		}
+
+		// Map author as of the commit date
+		name, email := m.authorMap.GetAt(commit.Author, commit.Date)
 		commit.Author = name
 		commit.Email = email
 
 		// Apply commit (if not dry run)
 		if !m.config.DryRun {
-			if err := m.target.ApplyCommit(commit); err != nil {
+			if err := m.applyCommit(commit); err != nil {
 				return fmt.Errorf("failed to apply commit %s: %w", commit.Revision, err)
 			}
 		}
@@ -158,6 +252,13 @@ func (m *Migrator) Run() error {
 		}
 	}
 
+	// Leave the worktree on the trunk once branch commits are applied
+	if trunk := m.trunkBranch(); !m.config.DryRun && m.target.BranchExists(trunk) {
+		if err := m.target.CheckoutBranch(trunk); err != nil {
+			log.Printf("Warning: failed to check out %s: %v", trunk, err)
+		}
+	}
+
 	// Create branches
 	if !m.config.DryRun {
 		if err := m.createBranches(); err != nil {
@@ -172,6 +273,13 @@ func (m *Migrator) Run() error {
 		}
 	}
 
+	// Write the mailmap once history is complete, unless it has no entries
+	if mailmap != nil && mailmap.Len() > 0 && !m.config.DryRun {
+		if err := m.writeMailmap(mailmap.Bytes(), commits); err != nil {
+			return fmt.Errorf("failed to write .mailmap: %w", err)
+		}
+	}
+
 	// Mark complete
 	if !m.config.DryRun {
 		if err := m.markComplete(); err != nil {
@@ -184,10 +292,92 @@ func (m *Migrator) Run() error {
 	return nil
 }
 
+// checkAuthors fails if the author of any commit is not mapped at the
+// time of the commit
+func (m *Migrator) checkAuthors(commits []*vcs.Commit) error {
+	seen := make(map[string]bool)
+	var unmapped []string
+	for _, commit := range commits {
+		if seen[commit.Author] {
+			continue
+		}
+		if _, _, ok := m.authorMap.LookupAt(commit.Author, commit.Date); !ok {
+			seen[commit.Author] = true
+			unmapped = append(unmapped, commit.Author)
+		}
+	}
+
+	if len(unmapped) > 0 {
+		sort.Strings(unmapped)
+		return fmt.Errorf("%d unmapped authors: %s", len(unmapped), strings.Join(unmapped, ", "))
+	}
+	return nil
+}
+
+// checkMailmap checks that the target can take a .mailmap as configured
+func (m *Migrator) checkMailmap() error {
+	switch m.config.Mailmap {
+	case "", "commit":
+		return nil
+	case "untracked":
+		if m.config.Bare || m.config.TargetType == "fast-import" {
+			return fmt.Errorf("an untracked .mailmap needs a target with a worktree")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported mailmap mode: %s", m.config.Mailmap)
+	}
+}
+
+// writeMailmap commits a .mailmap on top of the trunk, dated like the
+// latest source commit so that repeated migrations give the same history,
+// or leaves it untracked in the worktree
+func (m *Migrator) writeMailmap(content []byte, commits []*vcs.Commit) error {
+	if m.config.Mailmap == "untracked" {
+		return os.WriteFile(filepath.Join(m.config.TargetPath, ".mailmap"), content, 0644)
+	}
+
+	trunk := m.trunkBranch()
+	if !m.target.BranchExists(trunk) {
+		return fmt.Errorf("trunk branch %s does not exist", trunk)
+	}
+	var latest time.Time
+	for _, commit := range commits {
+		if commit.Date.After(latest) {
+			latest = commit.Date
+		}
+	}
+
+	commit := &vcs.Commit{
+		Revision: "mailmap",
+		Date:     latest,
+		Message:  "This commit was manufactured by git-migrator to add a .mailmap.",
+		Files:    []vcs.FileChange{{Path: ".mailmap", Action: vcs.ActionAdd, Content: content}},
+	}
+	commit.Author, commit.Email = m.authorMap.Get(migratorAuthor)
+	if err := m.target.CheckoutBranch(trunk); err != nil {
+		return err
+	}
+	return m.target.ApplyCommitToBranch(commit, trunk, "")
+}
+
 func (m *Migrator) initSource() error {
 	switch m.config.SourceType {
 	case "cvs":
-		m.source = cvs.NewReader(m.config.SourcePath)
+		if err := m.config.Keywords.Check(); err != nil {
+			return err
+		}
+		m.source = cvs.NewReaderWithOptions(m.config.SourcePath, cvs.ReaderOptions{
+			FuzzWindow:     m.config.FuzzWindow,
+			Keywords:       m.config.Keywords,
+			VendorBranch:   m.config.VendorBranch,
+			Module:         m.config.Module,
+			ExcludeModules: m.config.ExcludeModules,
+		})
+	case "svn":
+		m.source = svn.NewReaderWithOptions(m.config.SourcePath, svn.ReaderOptions{
+			Layout: m.config.SVNLayout,
+		})
 	default:
 		return fmt.Errorf("unsupported source type: %s", m.config.SourceType)
 	}
@@ -195,21 +385,43 @@ func (m *Migrator) initSource() error {
 }
 
 func (m *Migrator) initTarget() error {
-	m.target = git.NewWriter()
+	switch m.config.TargetType {
+	case "", "git":
+	case "fast-import":
+		// A stream cannot continue where an earlier one stopped
+		if m.config.Resume {
+			return fmt.Errorf("resume is not supported for fast-import output")
+		}
+		writer := fastimport.NewWriter()
+		if err := writer.Init(m.config.TargetPath); err != nil {
+			return err
+		}
+		m.target = writer
+		return nil
+	default:
+		return fmt.Errorf("unsupported target type: %s", m.config.TargetType)
+	}
+
+	writer := git.NewWriterWithOptions(git.WriterOptions{
+		TreeBuilder:   m.config.TreeBuilder,
+		Bare:          m.config.Bare,
+		InitialBranch: m.trunkBranch(),
+	})
 
 	// Check if target exists
 	if _, err := os.Stat(m.config.TargetPath); os.IsNotExist(err) {
 		// Create new repo
-		if err := m.target.Init(m.config.TargetPath); err != nil {
+		if err := writer.Init(m.config.TargetPath); err != nil {
 			return err
 		}
 	} else {
 		// Open existing repo
-		if err := m.target.Open(m.config.TargetPath); err != nil {
+		if err := writer.Open(m.config.TargetPath); err != nil {
 			return err
 		}
 	}
 
+	m.target = writer
 	return nil
 }
 
@@ -243,18 +455,63 @@ func (m *Migrator) initState() error {
 			processed:   state.Processed,
 			total:       state.Total,
 		}
-	} else {
-		m.state = &MigrationState{
-			migrationID: migrationID,
-		}
+		return m.loadMarks()
 	}
 
+	m.state = &MigrationState{
+		migrationID: migrationID,
+	}
+
+	// Marks of an earlier attempt refer to commits that will be recreated
+	return db.DeleteMarks(migrationID)
+}
+
+// loadMarks restores the commit marks recorded by an interrupted migration
+func (m *Migrator) loadMarks() error {
+	marks, err := m.db.Marks(m.state.migrationID)
+	if err != nil {
+		return fmt.Errorf("failed to load marks: %w", err)
+	}
+
+	m.marks = make(map[string]string)
+	for _, mark := range marks {
+		if mark.Path == "" {
+			m.marks[mark.Revision] = mark.Commit
+		}
+	}
 	return nil
 }
 
+// recordMark remembers the Git commit created for a source commit, persisting
+// it together with the file revisions the commit contains
+func (m *Migrator) recordMark(commit *vcs.Commit, hash string) error {
+	if m.marks == nil {
+		m.marks = make(map[string]string)
+	}
+	m.marks[commit.Revision] = hash
+
+	if m.db == nil {
+		return nil
+	}
+
+	marks := []storage.Mark{{Revision: commit.Revision, Commit: hash}}
+	for _, fc := range commit.Files {
+		if fc.Revision != "" {
+			marks = append(marks, storage.Mark{Revision: fc.Revision, Path: fc.Path, Commit: hash})
+		}
+	}
+	return m.db.SaveMarks(m.state.migrationID, marks)
+}
+
 func (m *Migrator) generateMigrationID() string {
+	return MigrationID(m.config.SourcePath, m.config.TargetPath)
+}
+
+// MigrationID returns the identifier under which the state of a migration
+// between the given source and target paths is stored
+func MigrationID(sourcePath, targetPath string) string {
 	// Generate a unique ID based on source and target paths
-	data := m.config.SourcePath + ":" + m.config.TargetPath
+	data := sourcePath + ":" + targetPath
 	hash := sha256.Sum256([]byte(data))
 	return hex.EncodeToString(hash[:8])
 }
@@ -282,20 +539,149 @@ func (m *Migrator) saveState(lastCommit string, processed, total int) error {
 	return m.db.Save(state)
 }
 
+// applyCommit applies a commit onto the Git branch of its source branch,
+// forking that branch from the commit the source says it started at
+func (m *Migrator) applyCommit(commit *vcs.Commit) error {
+	if m.marks == nil {
+		m.marks = make(map[string]string)
+	}
+
+	branch, ok := m.gitBranch(commit.Branch)
+	if !ok {
+		return nil
+	}
+	trunk := m.trunkBranch()
+	parent := ""
+	if commit.Parent != "" {
+		if hash, ok := m.marks[commit.Parent]; ok {
+			parent = hash
+		} else {
+			log.Printf("Warning: branch point %s of %s not migrated, forking from %s", commit.Parent, branch, trunk)
+		}
+	}
+	if parent == "" && branch != trunk {
+		parent = trunk
+	}
+
+	if err := m.target.ApplyCommitToBranch(commit, branch, parent); err != nil {
+		return err
+	}
+	return m.recordMark(commit, m.target.LastCommitHash())
+}
+
+// initRefMaps compiles the branch and tag mappings
+func (m *Migrator) initRefMaps() error {
+	if m.branchMap != nil {
+		return nil
+	}
+
+	branchMap, err := mapping.NewBranchMap(m.config.BranchMap, m.config.BranchRules, m.config.SkipBranches)
+	if err != nil {
+		return fmt.Errorf("invalid branch mapping: %w", err)
+	}
+	tagMap, err := mapping.NewTagMap(m.config.TagMap, m.config.TagRules, m.config.SkipTags)
+	if err != nil {
+		return fmt.Errorf("invalid tag mapping: %w", err)
+	}
+	m.branchMap, m.tagMap = branchMap, tagMap
+	return nil
+}
+
+// checkRefNames maps every branch and tag of the source, failing if any
+// Git name is invalid or shared by several source names. Errors listing
+// branches or tags are left to createBranches and createTags to report.
+func (m *Migrator) checkRefNames() error {
+	if err := m.initRefMaps(); err != nil {
+		return err
+	}
+
+	trunk := m.trunkBranch()
+	if err := mapping.ValidateRefName("refs/heads/", trunk); err != nil {
+		return fmt.Errorf("invalid trunk branch: %w", err)
+	}
+	if branches, err := m.source.GetBranches(); err == nil {
+		mapped, err := m.branchMap.MapNames(branches)
+		if err != nil {
+			return fmt.Errorf("invalid branch mapping: %w", err)
+		}
+		for _, branch := range branches {
+			if gitBranch, ok := mapped[branch]; ok && gitBranch == trunk {
+				return fmt.Errorf("invalid branch mapping: %s maps to the trunk branch %s", branch, trunk)
+			}
+		}
+	}
+
+	if tags, err := m.source.GetTags(); err == nil {
+		names := make([]string, 0, len(tags))
+		for name := range tags {
+			names = append(names, name)
+		}
+		if _, err := m.tagMap.MapNames(names); err != nil {
+			return fmt.Errorf("invalid tag mapping: %w", err)
+		}
+	}
+	return nil
+}
+
+// trunkPseudoBranches are the names under which the trunk can be mapped
+var trunkPseudoBranches = []string{"MAIN", "HEAD"}
+
+// trunkBranch returns the Git branch that receives trunk commits: the
+// mapping of the trunk pseudo-branch if there is one, else the initial
+// branch
+func (m *Migrator) trunkBranch() string {
+	for _, name := range trunkPseudoBranches {
+		if mapped, ok := m.config.BranchMap[name]; ok {
+			return mapped
+		}
+	}
+	if m.config.InitialBranch != "" {
+		return m.config.InitialBranch
+	}
+	return defaultTrunkBranch
+}
+
+// gitBranch returns the Git branch name for a source branch, or false if
+// the branch is skipped
+func (m *Migrator) gitBranch(branch string) (string, bool) {
+	if branch == "" {
+		return m.trunkBranch(), true
+	}
+	return m.branchMap.Get(branch)
+}
+
 func (m *Migrator) createBranches() error {
+	if err := m.initRefMaps(); err != nil {
+		return err
+	}
+
 	branches, err := m.source.GetBranches()
 	if err != nil {
 		return err
 	}
 
+	// Branches without commits of their own still start at their branch point
+	points := make(map[string]string)
+	if reader, ok := m.source.(vcs.BranchPointReader); ok {
+		if points, err = reader.GetBranchPoints(); err != nil {
+			return err
+		}
+	}
+
 	for _, branch := range branches {
-		gitBranch := branch
-		if mapped, ok := m.config.BranchMap[branch]; ok {
-			gitBranch = mapped
+		gitBranch, ok := m.gitBranch(branch)
+		if !ok || m.target.BranchExists(gitBranch) {
+			continue
+		}
+
+		revision, ok := m.marks[points[branch]]
+		if !ok {
+			log.Printf("Warning: skipping branch %s: its branch point was not migrated", gitBranch)
+			continue
 		}
 
 		m.reporter.SetOperation(fmt.Sprintf("Creating branch %s", gitBranch))
-		if err := m.target.CreateBranch(gitBranch, "HEAD"); err != nil {
+		if err := m.target.CreateBranch(gitBranch, revision); err != nil {
 			// Log error but don't fail - branch creation is best effort
 			log.Printf("Warning: failed to create branch %s: %v", gitBranch, err)
 		}
@@ -305,19 +691,74 @@ func (m *Migrator) createBranches() error {
 }
 
 func (m *Migrator) createTags() error {
+	if err := m.initRefMaps(); err != nil {
+		return err
+	}
+
 	tags, err := m.source.GetTags()
 	if err != nil {
 		return err
 	}
 
-	for tagName, commitHash := range tags {
-		gitTag := tagName
-		if mapped, ok := m.config.TagMap[tagName]; ok {
-			gitTag = mapped
+	fixups := make(map[string]*vcs.Commit)
+	if reader, ok := m.source.(vcs.TagFixupReader); ok {
+		if fixups, err = reader.GetTagFixups(); err != nil {
+			return err
+		}
+	}
+
+	annotated := false
+	var tagger vcs.Signature
+	dates := make(map[string]time.Time)
+	switch m.config.TagType {
+	case "", "lightweight":
+	case "annotated":
+		annotated = true
+		if m.config.Tagger != "" {
+			if tagger.Name, tagger.Email, err = mapping.ParseAuthor(m.config.Tagger); err != nil {
+				return fmt.Errorf("invalid tagger: %w", err)
+			}
+		}
+		if reader, ok := m.source.(vcs.TagDateReader); ok && m.config.TagDate.IsZero() {
+			if dates, err = reader.GetTagDates(); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported tag type: %s", m.config.TagType)
+	}
+
+	for tagName, revision := range tags {
+		gitTag, ok := m.tagMap.Get(tagName)
+		if !ok {
+			continue
 		}
 
 		m.reporter.SetOperation(fmt.Sprintf("Creating tag %s", gitTag))
-		if err := m.target.CreateTag(gitTag, commitHash, ""); err != nil {
+		if fixup, ok := fixups[revision]; ok {
+			if err := m.applyTagFixup(fixup); err != nil {
+				log.Printf("Warning: failed to create fixup commit for tag %s: %v", gitTag, err)
+				continue
+			}
+		}
+
+		// Source revisions resolve to the commits migrated for them
+		commitHash, ok := m.marks[revision]
+		if !ok {
+			log.Printf("Warning: skipping tag %s: revision %s was not migrated", gitTag, revision)
+			continue
+		}
+
+		if annotated {
+			tagger.When = m.config.TagDate
+			if tagger.When.IsZero() {
+				tagger.When = dates[tagName]
+			}
+			err = m.target.CreateAnnotatedTag(gitTag, commitHash, m.tagMessage(gitTag, tagName), tagger)
+		} else {
+			err = m.target.CreateTag(gitTag, commitHash, "")
+		}
+		if err != nil {
 			// Log error but don't fail - tag creation is best effort
 			log.Printf("Warning: failed to create tag %s: %v", gitTag, err)
 		}
@@ -326,6 +767,45 @@ func (m *Migrator) createTags() error {
 	return nil
 }
 
+// tagMessage expands the message template of an annotated tag: {tag} is
+// the Git tag name and {cvsTag} the name of the tag in the source
+func (m *Migrator) tagMessage(gitTag, sourceTag string) string {
+	template := m.config.TagMessage
+	if template == "" {
+		template = defaultTagMessage
+	}
+	return strings.NewReplacer("{tag}", gitTag, "{cvsTag}", sourceTag).Replace(template)
+}
+
+// applyTagFixup commits a synthetic tag commit on top of its parent without
+// leaving it on any branch, recording its hash for the tag to point at
+func (m *Migrator) applyTagFixup(fixup *vcs.Commit) error {
+	if _, ok := m.marks[fixup.Revision]; ok {
+		return nil
+	}
+
+	parent, ok := m.marks[fixup.Parent]
+	if !ok {
+		return fmt.Errorf("parent revision %s not migrated", fixup.Parent)
+	}
+
+	commit := *fixup
+	commit.Author, commit.Email = m.authorMap.GetAt(fixup.Author, fixup.Date)
+	if err := m.target.ApplyCommitToBranch(&commit, fixupBranch, parent); err != nil {
+		return err
+	}
+	if err := m.recordMark(&commit, m.target.LastCommitHash()); err != nil {
+		return err
+	}
+
+	if trunk := m.trunkBranch(); m.target.BranchExists(trunk) {
+		if err := m.target.CheckoutBranch(trunk); err != nil {
+			return err
+		}
+	}
+	return m.target.DeleteBranch(fixupBranch)
+}
+
 func (m *Migrator) markComplete() error {
 	m.reporter.SetOperation("Finalizing migration")
 
@@ -24,6 +24,7 @@ func (e *emptyIter) Err() error          { return nil }
 type mockSource struct {
 	branches []string
 	tags     map[string]string
+	points   map[string]string
 }
 
 func (m *mockSource) Validate() error                         { return nil }
@@ -32,6 +33,8 @@ func (m *mockSource) GetBranches() ([]string, error)          { return m.branche
 func (m *mockSource) GetTags() (map[string]string, error)     { return m.tags, nil }
 func (m *mockSource) Close() error                            { return nil }
 
+func (m *mockSource) GetBranchPoints() (map[string]string, error) { return m.points, nil }
+
 func TestCreateBranches_TargetErrorsAndSuccess(t *testing.T) {
 	// Error path: target writer not initialized -> CreateBranch returns error but should not bubble up
 	m := &Migrator{
@@ -62,10 +65,14 @@ func TestCreateBranches_TargetErrorsAndSuccess(t *testing.T) {
 	require.NoError(t, w.ApplyCommit(initialCommit))
 
 	m2 := &Migrator{
-		config:   &MigrationConfig{BranchMap: map[string]string{"b1": "mapped"}},
-		source:   &mockSource{branches: []string{"b1"}},
+		config: &MigrationConfig{BranchMap: map[string]string{"b1": "mapped", "b2": "unmigrated"}},
+		source: &mockSource{
+			branches: []string{"b1", "b2"},
+			points:   map[string]string{"b1": "initial", "b2": "skipped"},
+		},
 		target:   w,
 		reporter: progress.NewReporter(0),
+		marks:    map[string]string{"initial": w.LastCommitHash()},
 	}
 
 	require.NoError(t, m2.createBranches())
@@ -73,6 +80,8 @@ func TestCreateBranches_TargetErrorsAndSuccess(t *testing.T) {
 	branches, err := w.ListBranches()
 	require.NoError(t, err)
 	assert.Contains(t, branches, "mapped")
+	// Branches whose branch point was not migrated are skipped
+	assert.NotContains(t, branches, "unmigrated")
 }
 
 func TestCreateTags_TargetErrorsAndSuccess(t *testing.T) {
@@ -89,20 +98,31 @@ func TestCreateTags_TargetErrorsAndSuccess(t *testing.T) {
 	tmp := t.TempDir()
 	w := git.NewWriter()
 	require.NoError(t, w.Init(tmp))
+	require.NoError(t, w.ApplyCommit(&vcs.Commit{
+		Revision: "r1",
+		Author:   "test",
+		Email:    "test@example.com",
+		Date:     time.Now(),
+		Message:  "Initial commit",
+		Files:    []vcs.FileChange{{Path: "README.md", Action: vcs.ActionAdd, Content: []byte("# Test")}},
+	}))
 
 	m2 := &Migrator{
 		config:   &MigrationConfig{TagMap: map[string]string{"v1": "tagged"}},
-		source:   &mockSource{tags: map[string]string{"v1": "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef"}},
+		source:   &mockSource{tags: map[string]string{"v1": "r1", "v2": "0123456789abcdef"}},
 		target:   w,
 		reporter: progress.NewReporter(0),
+		marks:    map[string]string{"r1": w.LastCommitHash()},
 	}
 
 	require.NoError(t, m2.createTags())
 	tags, err := w.ListTags()
 	require.NoError(t, err)
 	// mapped name should be present
-	_, ok := tags["tagged"]
-	assert.True(t, ok)
+	assert.Equal(t, w.LastCommitHash(), tags["tagged"])
+	// Tags of revisions that were not migrated are skipped
+	_, ok := tags["v2"]
+	assert.False(t, ok)
 }
 
 func TestMarkCompleteAndSaveState(t *testing.T) {
@@ -135,6 +135,23 @@ func TestMigratorInitSourceCVS(t *testing.T) {
 	}
 }
 
+func TestMigratorInitSourceSVN(t *testing.T) {
+	config := &MigrationConfig{
+		SourceType: "svn",
+		SourcePath: "/source/repo.dump",
+		TargetPath: "/target",
+	}
+
+	m := NewMigrator(config)
+	if err := m.initSource(); err != nil {
+		t.Errorf("initSource failed: %v", err)
+	}
+
+	if m.source == nil {
+		t.Error("source should be initialized")
+	}
+}
+
 func TestMigratorInitTargetNew(t *testing.T) {
 	tmpDir, err := os.MkdirTemp("", "git-target")
 	if err != nil {
@@ -207,6 +224,31 @@ func TestMigratorInitTargetExisting(t *testing.T) {
 	m.target.Close()
 }
 
+func TestMigratorInitTargetFastImport(t *testing.T) {
+	streamPath := filepath.Join(t.TempDir(), "out", "repo.fi")
+
+	m := NewMigrator(&MigrationConfig{TargetType: "fast-import", TargetPath: streamPath})
+	if err := m.initTarget(); err != nil {
+		t.Fatalf("initTarget failed: %v", err)
+	}
+	if err := m.target.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if _, err := os.Stat(streamPath); err != nil {
+		t.Errorf("stream file should exist: %v", err)
+	}
+
+	m = NewMigrator(&MigrationConfig{TargetType: "fast-import", TargetPath: streamPath, Resume: true})
+	if err := m.initTarget(); err == nil {
+		t.Error("expected error resuming fast-import output")
+	}
+
+	m = NewMigrator(&MigrationConfig{TargetType: "hg", TargetPath: streamPath})
+	if err := m.initTarget(); err == nil {
+		t.Error("expected error for unsupported target type")
+	}
+}
+
 func TestMigratorGenerateMigrationID(t *testing.T) {
 	config := &MigrationConfig{
 		SourcePath: "/source/path",
@@ -2,10 +2,19 @@ package core
 
 import (
 	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/adamf123git/git-migrator/internal/mapping"
 	"github.com/adamf123git/git-migrator/internal/vcs"
+	"github.com/adamf123git/git-migrator/internal/vcs/svn"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/stretchr/testify/require"
 )
 
@@ -293,3 +302,881 @@ func TestRun_InitSourceError(t *testing.T) {
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "failed to init source")
 }
+
+// Mock reader with a branch forked from an older trunk commit
+type mockReaderWithBranchPoints struct {
+	mockReaderWithCommits
+	points map[string]string
+}
+
+func (m *mockReaderWithBranchPoints) GetBranches() ([]string, error) {
+	return []string{"DEV", "REL"}, nil
+}
+func (m *mockReaderWithBranchPoints) GetBranchPoints() (map[string]string, error) {
+	return m.points, nil
+}
+
+func TestRun_BranchesForkFromBranchPoints(t *testing.T) {
+	file := func(content string) []vcs.FileChange {
+		return []vcs.FileChange{{Path: "file.txt", Action: vcs.ActionModify, Content: []byte(content)}}
+	}
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	commits := []*vcs.Commit{
+		{Revision: "r1", Author: "a", Date: base, Message: "trunk 1", Files: file("one")},
+		{Revision: "r2", Author: "a", Date: base.Add(time.Hour), Message: "trunk 2", Files: file("two")},
+		{Revision: "b1", Author: "a", Date: base.Add(2 * time.Hour), Message: "dev 1", Branch: "DEV", Parent: "r1", Files: file("dev")},
+		{Revision: "r3", Author: "a", Date: base.Add(3 * time.Hour), Message: "trunk 3", Files: file("three")},
+	}
+
+	repoPath := filepath.Join(t.TempDir(), "repo")
+	cfg := &MigrationConfig{
+		SourceType: "cvs",
+		SourcePath: "/src",
+		TargetPath: repoPath,
+		BranchMap:  map[string]string{"DEV": "develop"},
+	}
+	m := NewMigrator(cfg)
+	m.source = &mockReaderWithBranchPoints{
+		mockReaderWithCommits: mockReaderWithCommits{commits: commits},
+		points:                map[string]string{"DEV": "r1", "REL": "r2"},
+	}
+	require.NoError(t, m.Run())
+
+	repo, err := gogit.PlainOpen(repoPath)
+	require.NoError(t, err)
+
+	tip := func(branch string) *object.Commit {
+		ref, err := repo.Reference(plumbing.NewBranchReferenceName(branch), true)
+		require.NoError(t, err)
+		commit, err := repo.CommitObject(ref.Hash())
+		require.NoError(t, err)
+		return commit
+	}
+
+	develop := tip("develop")
+	require.Equal(t, "dev 1", develop.Message)
+	require.Equal(t, m.marks["r1"], develop.ParentHashes[0].String())
+
+	require.Equal(t, m.marks["r2"], tip("REL").Hash.String())
+	require.Equal(t, m.marks["r3"], tip("master").Hash.String())
+
+	head, err := repo.Head()
+	require.NoError(t, err)
+	require.Equal(t, plumbing.NewBranchReferenceName("master"), head.Name())
+	content, err := os.ReadFile(filepath.Join(repoPath, "file.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "three", string(content))
+}
+
+// Mock reader with a tag that needs a synthetic commit
+type mockReaderWithTagFixups struct {
+	mockReaderWithCommits
+	tags   map[string]string
+	fixups map[string]*vcs.Commit
+}
+
+func (m *mockReaderWithTagFixups) GetTags() (map[string]string, error) { return m.tags, nil }
+func (m *mockReaderWithTagFixups) GetTagFixups() (map[string]*vcs.Commit, error) {
+	return m.fixups, nil
+}
+
+func TestRun_TagsResolveToMigratedCommits(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	commits := []*vcs.Commit{
+		{Revision: "r1", Author: "a", Date: base, Message: "one", Files: []vcs.FileChange{
+			{Path: "a.txt", Action: vcs.ActionAdd, Content: []byte("a1")},
+			{Path: "b.txt", Action: vcs.ActionAdd, Content: []byte("b1")},
+		}},
+		{Revision: "r2", Author: "a", Date: base.Add(time.Hour), Message: "two", Files: []vcs.FileChange{
+			{Path: "b.txt", Action: vcs.ActionModify, Content: []byte("b2")},
+		}},
+	}
+	fixup := &vcs.Commit{
+		Revision: "fx", Author: "git-migrator", Date: base.Add(time.Hour), Message: "fixup", Parent: "r2",
+		Files: []vcs.FileChange{{Path: "b.txt", Action: vcs.ActionModify, Content: []byte("b1")}},
+	}
+
+	repoPath := filepath.Join(t.TempDir(), "repo")
+	m := NewMigrator(&MigrationConfig{SourceType: "cvs", SourcePath: "/src", TargetPath: repoPath})
+	m.source = &mockReaderWithTagFixups{
+		mockReaderWithCommits: mockReaderWithCommits{commits: commits},
+		tags:                  map[string]string{"PLAIN": "r1", "MIXED": "fx"},
+		fixups:                map[string]*vcs.Commit{"fx": fixup},
+	}
+	require.NoError(t, m.Run())
+
+	repo, err := gogit.PlainOpen(repoPath)
+	require.NoError(t, err)
+
+	plain, err := repo.Reference(plumbing.NewTagReferenceName("PLAIN"), true)
+	require.NoError(t, err)
+	require.Equal(t, m.marks["r1"], plain.Hash().String())
+
+	mixed, err := repo.Reference(plumbing.NewTagReferenceName("MIXED"), true)
+	require.NoError(t, err)
+	commit, err := repo.CommitObject(mixed.Hash())
+	require.NoError(t, err)
+	require.Equal(t, m.marks["r2"], commit.ParentHashes[0].String())
+	file, err := commit.File("b.txt")
+	require.NoError(t, err)
+	contents, err := file.Contents()
+	require.NoError(t, err)
+	require.Equal(t, "b1", contents)
+
+	// The fixup commit stays off every branch
+	_, err = repo.Reference(plumbing.NewBranchReferenceName(fixupBranch), true)
+	require.Error(t, err)
+	master, err := repo.Reference(plumbing.NewBranchReferenceName("master"), true)
+	require.NoError(t, err)
+	require.Equal(t, m.marks["r2"], master.Hash().String())
+	head, err := repo.Head()
+	require.NoError(t, err)
+	require.Equal(t, plumbing.NewBranchReferenceName("master"), head.Name())
+}
+
+func TestRun_PersistsMarksForResume(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	commits := []*vcs.Commit{
+		{Revision: "r1", Author: "a", Date: base, Message: "one", Files: []vcs.FileChange{
+			{Path: "foo.c", Action: vcs.ActionAdd, Content: []byte("1"), Revision: "1.1"},
+		}},
+		{Revision: "r2", Author: "a", Date: base.Add(time.Hour), Message: "two", Files: []vcs.FileChange{
+			{Path: "foo.c", Action: vcs.ActionModify, Content: []byte("2"), Revision: "1.2"},
+		}},
+	}
+
+	tmp := t.TempDir()
+	cfg := &MigrationConfig{
+		SourceType:  "cvs",
+		SourcePath:  "/src",
+		TargetPath:  filepath.Join(tmp, "repo"),
+		StateFile:   filepath.Join(tmp, "state.db"),
+		InterruptAt: 1,
+	}
+	m := NewMigrator(cfg)
+	m.source = &mockReaderWithCommits{commits: commits}
+	require.Error(t, m.Run())
+	first := m.marks["r1"]
+	require.NoError(t, m.db.Close())
+
+	// The resumed run knows the commit of r1 without re-applying it
+	cfg.InterruptAt = 0
+	cfg.Resume = true
+	m = NewMigrator(cfg)
+	m.source = &mockReaderWithCommits{commits: commits}
+	require.NoError(t, m.Run())
+	require.Equal(t, first, m.marks["r1"])
+	defer m.db.Close()
+
+	hash, err := m.db.LookupMark(MigrationID("/src", cfg.TargetPath), "foo.c", "1.2")
+	require.NoError(t, err)
+	require.Equal(t, m.marks["r2"], hash)
+
+	repo, err := gogit.PlainOpen(cfg.TargetPath)
+	require.NoError(t, err)
+	commit, err := repo.CommitObject(plumbing.NewHash(m.marks["r2"]))
+	require.NoError(t, err)
+	require.Equal(t, first, commit.ParentHashes[0].String())
+}
+
+func TestRun_ResumeSkipsMarkedCommits(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var commits []*vcs.Commit
+	for i := 1; i <= 3; i++ {
+		commits = append(commits, &vcs.Commit{
+			Revision: fmt.Sprintf("r%d", i), Author: "a", Date: base.Add(time.Duration(i) * time.Hour),
+			Message: fmt.Sprintf("commit %d", i),
+			Files:   []vcs.FileChange{{Path: "foo.c", Action: vcs.ActionModify, Content: []byte(fmt.Sprint(i))}},
+		})
+	}
+
+	tmp := t.TempDir()
+	cfg := &MigrationConfig{
+		SourceType:  "cvs",
+		SourcePath:  "/src",
+		TargetPath:  filepath.Join(tmp, "repo"),
+		StateFile:   filepath.Join(tmp, "state.db"),
+		InterruptAt: 2,
+	}
+	m := NewMigrator(cfg)
+	m.source = &mockReaderWithCommits{commits: commits}
+	require.Error(t, m.Run())
+	second := m.marks["r2"]
+
+	// A crash after r2 but before the state of its chunk was saved
+	require.NoError(t, m.saveState("r1", 1, len(commits)))
+	require.NoError(t, m.db.Close())
+
+	cfg.InterruptAt = 0
+	cfg.Resume = true
+	m = NewMigrator(cfg)
+	m.source = &mockReaderWithCommits{commits: commits}
+	require.NoError(t, m.Run())
+	defer m.db.Close()
+	require.Equal(t, second, m.marks["r2"])
+
+	repo, err := gogit.PlainOpen(cfg.TargetPath)
+	require.NoError(t, err)
+	iter, err := repo.Log(&gogit.LogOptions{From: plumbing.NewHash(m.marks["r3"])})
+	require.NoError(t, err)
+	var messages []string
+	require.NoError(t, iter.ForEach(func(c *object.Commit) error {
+		messages = append(messages, strings.TrimSpace(c.Message))
+		return nil
+	}))
+	require.Equal(t, []string{"commit 3", "commit 2", "commit 1"}, messages)
+}
+
+// svnRevision encodes a dump file revision record
+func svnRevision(number int, author, date string) string {
+	props := fmt.Sprintf("K 10\nsvn:author\nV %d\n%s\nK 8\nsvn:date\nV %d\n%s\nPROPS-END\n",
+		len(author), author, len(date), date)
+	return fmt.Sprintf("Revision-number: %d\nProp-content-length: %d\nContent-length: %d\n\n%s\n",
+		number, len(props), len(props), props)
+}
+
+// svnNode encodes a dump file node record with optional file text
+func svnNode(headers, text string) string {
+	if text == "" {
+		return headers + "\n\n"
+	}
+	return fmt.Sprintf("%sText-content-length: %d\nContent-length: %d\n\n%s\n\n", headers, len(text), len(text), text)
+}
+
+func TestRun_SVNDump(t *testing.T) {
+	dump := "SVN-fs-dump-format-version: 2\n\n" +
+		svnRevision(1, "alice", "2024-01-02T10:00:00.000000Z") +
+		svnNode("Node-path: trunk\nNode-kind: dir\nNode-action: add\n", "") +
+		svnNode("Node-path: trunk/README\nNode-kind: file\nNode-action: add\n", "hello\n")
+
+	tmp := t.TempDir()
+	source := filepath.Join(tmp, "repo.dump")
+	require.NoError(t, os.WriteFile(source, []byte(dump), 0644))
+
+	cfg := &MigrationConfig{
+		SourceType: "svn",
+		SourcePath: source,
+		TargetPath: filepath.Join(tmp, "repo"),
+		StateFile:  filepath.Join(tmp, "state.db"),
+	}
+	m := NewMigrator(cfg)
+	require.NoError(t, m.Run())
+	defer m.db.Close()
+
+	content, err := os.ReadFile(filepath.Join(cfg.TargetPath, "trunk", "README"))
+	require.NoError(t, err)
+	require.Equal(t, "hello\n", string(content))
+
+	repo, err := gogit.PlainOpen(cfg.TargetPath)
+	require.NoError(t, err)
+	commit, err := repo.CommitObject(plumbing.NewHash(m.marks["1"]))
+	require.NoError(t, err)
+	require.Equal(t, "alice", commit.Author.Name)
+}
+
+// svnLayoutDump returns a standard layout dump with a trunk commit, a dev
+// branch, an unmodified tag 1.0 and a tag 1.1 changed after the copy
+func svnLayoutDump() string {
+	dir := "Node-kind: dir\nNode-action: add\n"
+	return "SVN-fs-dump-format-version: 2\n\n" +
+		svnRevision(1, "alice", "2024-01-01T00:00:00.000000Z") +
+		svnNode("Node-path: trunk\n"+dir, "") +
+		svnNode("Node-path: branches\n"+dir, "") +
+		svnNode("Node-path: tags\n"+dir, "") +
+		svnNode("Node-path: trunk/README\nNode-kind: file\nNode-action: add\n", "v1") +
+		svnRevision(2, "bob", "2024-01-02T00:00:00.000000Z") +
+		svnNode("Node-path: branches/dev\n"+dir+"Node-copyfrom-rev: 1\nNode-copyfrom-path: trunk\n", "") +
+		svnNode("Node-path: branches/dev/README\nNode-kind: file\nNode-action: change\n", "dev") +
+		svnRevision(3, "alice", "2024-01-03T00:00:00.000000Z") +
+		svnNode("Node-path: tags/1.0\n"+dir+"Node-copyfrom-rev: 1\nNode-copyfrom-path: trunk\n", "") +
+		svnNode("Node-path: tags/1.1\n"+dir+"Node-copyfrom-rev: 1\nNode-copyfrom-path: trunk\n", "") +
+		svnNode("Node-path: tags/1.1/README\nNode-kind: file\nNode-action: change\n", "v1.1")
+}
+
+func TestRun_SVNLayout(t *testing.T) {
+	tmp := t.TempDir()
+	source := filepath.Join(tmp, "repo.dump")
+	require.NoError(t, os.WriteFile(source, []byte(svnLayoutDump()), 0644))
+
+	cfg := &MigrationConfig{
+		SourceType: "svn",
+		SourcePath: source,
+		SVNLayout:  svn.StandardLayout,
+		TargetPath: filepath.Join(tmp, "repo"),
+		StateFile:  filepath.Join(tmp, "state.db"),
+	}
+	m := NewMigrator(cfg)
+	require.NoError(t, m.Run())
+	defer m.db.Close()
+
+	repo, err := gogit.PlainOpen(cfg.TargetPath)
+	require.NoError(t, err)
+
+	trunk := m.marks["trunk@1"]
+	dev, err := repo.Reference(plumbing.NewBranchReferenceName("dev"), true)
+	require.NoError(t, err)
+	devCommit, err := repo.CommitObject(dev.Hash())
+	require.NoError(t, err)
+	require.Equal(t, trunk, devCommit.ParentHashes[0].String())
+
+	tag, err := repo.Reference(plumbing.NewTagReferenceName("1.0"), true)
+	require.NoError(t, err)
+	require.Equal(t, trunk, tag.Hash().String())
+
+	tag, err = repo.Reference(plumbing.NewTagReferenceName("1.1"), true)
+	require.NoError(t, err)
+	fixup, err := repo.CommitObject(tag.Hash())
+	require.NoError(t, err)
+	require.Equal(t, trunk, fixup.ParentHashes[0].String())
+	file, err := fixup.File("README")
+	require.NoError(t, err)
+	content, err := file.Contents()
+	require.NoError(t, err)
+	require.Equal(t, "v1.1", content)
+}
+
+func TestRun_FastImport(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	tmp := t.TempDir()
+	source := filepath.Join(tmp, "repo.dump")
+	require.NoError(t, os.WriteFile(source, []byte(svnLayoutDump()), 0644))
+
+	cfg := &MigrationConfig{
+		SourceType: "svn",
+		SourcePath: source,
+		SVNLayout:  svn.StandardLayout,
+		TargetType: "fast-import",
+		TargetPath: filepath.Join(tmp, "repo.fi"),
+		StateFile:  filepath.Join(tmp, "state.db"),
+	}
+	m := NewMigrator(cfg)
+	require.NoError(t, m.Run())
+	defer m.db.Close()
+	require.Equal(t, ":2", m.marks["trunk@1"])
+
+	// Import the stream into a fresh repository
+	stream, err := os.Open(cfg.TargetPath)
+	require.NoError(t, err)
+	defer stream.Close()
+	repoPath := filepath.Join(tmp, "repo")
+	_, err = gogit.PlainInit(repoPath, false)
+	require.NoError(t, err)
+	cmd := exec.Command("git", "fast-import", "--quiet")
+	cmd.Dir = repoPath
+	cmd.Stdin = stream
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	repo, err := gogit.PlainOpen(repoPath)
+	require.NoError(t, err)
+	master, err := repo.Reference(plumbing.NewBranchReferenceName("master"), true)
+	require.NoError(t, err)
+	dev, err := repo.Reference(plumbing.NewBranchReferenceName("dev"), true)
+	require.NoError(t, err)
+	devCommit, err := repo.CommitObject(dev.Hash())
+	require.NoError(t, err)
+	require.Equal(t, master.Hash(), devCommit.ParentHashes[0])
+
+	tag, err := repo.Reference(plumbing.NewTagReferenceName("1.0"), true)
+	require.NoError(t, err)
+	require.Equal(t, master.Hash(), tag.Hash())
+
+	tag, err = repo.Reference(plumbing.NewTagReferenceName("1.1"), true)
+	require.NoError(t, err)
+	fixup, err := repo.CommitObject(tag.Hash())
+	require.NoError(t, err)
+	require.Equal(t, master.Hash(), fixup.ParentHashes[0])
+
+	_, err = repo.Reference(plumbing.NewBranchReferenceName(fixupBranch), true)
+	require.Error(t, err)
+}
+
+func TestRun_TreeBuilder(t *testing.T) {
+	tmp := t.TempDir()
+	source := filepath.Join(tmp, "repo.dump")
+	require.NoError(t, os.WriteFile(source, []byte(svnLayoutDump()), 0644))
+
+	cfg := &MigrationConfig{
+		SourceType:  "svn",
+		SourcePath:  source,
+		SVNLayout:   svn.StandardLayout,
+		TargetPath:  filepath.Join(tmp, "repo"),
+		TreeBuilder: true,
+		StateFile:   filepath.Join(tmp, "state.db"),
+	}
+	m := NewMigrator(cfg)
+	require.NoError(t, m.Run())
+	defer m.db.Close()
+
+	repo, err := gogit.PlainOpen(cfg.TargetPath)
+	require.NoError(t, err)
+	readme := func(hash plumbing.Hash) string {
+		commit, err := repo.CommitObject(hash)
+		require.NoError(t, err)
+		file, err := commit.File("README")
+		require.NoError(t, err)
+		content, err := file.Contents()
+		require.NoError(t, err)
+		return content
+	}
+
+	trunk := m.marks["trunk@1"]
+	require.Equal(t, "v1", readme(plumbing.NewHash(trunk)))
+	require.Equal(t, "dev", readme(plumbing.NewHash(m.marks["branches/dev@2"])))
+
+	tag, err := repo.Reference(plumbing.NewTagReferenceName("1.1"), true)
+	require.NoError(t, err)
+	require.Equal(t, "v1.1", readme(tag.Hash()))
+
+	head, err := repo.Head()
+	require.NoError(t, err)
+	require.Equal(t, plumbing.NewBranchReferenceName("master"), head.Name())
+	require.Equal(t, trunk, head.Hash().String())
+
+	// The worktree is checked out at the trunk once the migration ends
+	content, err := os.ReadFile(filepath.Join(cfg.TargetPath, "README"))
+	require.NoError(t, err)
+	require.Equal(t, "v1", string(content))
+	worktree, err := repo.Worktree()
+	require.NoError(t, err)
+	status, err := worktree.Status()
+	require.NoError(t, err)
+	for path, file := range status {
+		require.Equal(t, gogit.Untracked, file.Worktree, path)
+	}
+}
+
+func TestRun_BareTarget(t *testing.T) {
+	tmp := t.TempDir()
+	source := filepath.Join(tmp, "repo.dump")
+	require.NoError(t, os.WriteFile(source, []byte(svnLayoutDump()), 0644))
+
+	cfg := &MigrationConfig{
+		SourceType: "svn",
+		SourcePath: source,
+		SVNLayout:  svn.StandardLayout,
+		TargetPath: filepath.Join(tmp, "repo.git"),
+		Bare:       true,
+		StateFile:  filepath.Join(tmp, "state.db"),
+	}
+	m := NewMigrator(cfg)
+	require.NoError(t, m.Run())
+	defer m.db.Close()
+
+	_, err := os.Stat(filepath.Join(cfg.TargetPath, "HEAD"))
+	require.NoError(t, err)
+	_, err = os.Stat(filepath.Join(cfg.TargetPath, ".git"))
+	require.True(t, os.IsNotExist(err))
+
+	repo, err := gogit.PlainOpen(cfg.TargetPath)
+	require.NoError(t, err)
+	_, err = repo.Worktree()
+	require.ErrorIs(t, err, gogit.ErrIsBareRepository)
+
+	master, err := repo.Reference(plumbing.NewBranchReferenceName("master"), true)
+	require.NoError(t, err)
+	require.Equal(t, m.marks["trunk@1"], master.Hash().String())
+	dev, err := repo.Reference(plumbing.NewBranchReferenceName("dev"), true)
+	require.NoError(t, err)
+	require.Equal(t, m.marks["branches/dev@2"], dev.Hash().String())
+	_, err = repo.Reference(plumbing.NewTagReferenceName("1.1"), true)
+	require.NoError(t, err)
+}
+
+func TestRun_TrunkBranch(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	commits := []*vcs.Commit{
+		{Revision: "r1", Author: "a", Date: base, Message: "trunk", Files: []vcs.FileChange{
+			{Path: "file.txt", Action: vcs.ActionAdd, Content: []byte("one")},
+		}},
+		{Revision: "b1", Author: "a", Date: base.Add(time.Hour), Message: "dev", Branch: "DEV", Parent: "r1", Files: []vcs.FileChange{
+			{Path: "file.txt", Action: vcs.ActionModify, Content: []byte("dev")},
+		}},
+	}
+
+	tests := []struct {
+		name      string
+		initial   string
+		branchMap map[string]string
+		expected  string
+	}{
+		{"default", "", nil, "master"},
+		{"initial branch", "main", nil, "main"},
+		{"MAIN mapping", "main", map[string]string{"MAIN": "trunk"}, "trunk"},
+		{"HEAD mapping", "", map[string]string{"HEAD": "develop"}, "develop"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repoPath := filepath.Join(t.TempDir(), "repo")
+			m := NewMigrator(&MigrationConfig{
+				SourceType:    "cvs",
+				SourcePath:    "/src",
+				TargetPath:    repoPath,
+				InitialBranch: tt.initial,
+				BranchMap:     tt.branchMap,
+			})
+			m.source = &mockReaderWithCommits{commits: commits}
+			require.NoError(t, m.Run())
+			defer m.db.Close()
+
+			repo, err := gogit.PlainOpen(repoPath)
+			require.NoError(t, err)
+			head, err := repo.Head()
+			require.NoError(t, err)
+			require.Equal(t, plumbing.NewBranchReferenceName(tt.expected), head.Name())
+			require.Equal(t, m.marks["r1"], head.Hash().String())
+
+			dev, err := repo.Reference(plumbing.NewBranchReferenceName("DEV"), true)
+			require.NoError(t, err)
+			devCommit, err := repo.CommitObject(dev.Hash())
+			require.NoError(t, err)
+			require.Equal(t, m.marks["r1"], devCommit.ParentHashes[0].String())
+
+			if tt.expected != "master" {
+				_, err = repo.Reference(plumbing.NewBranchReferenceName("master"), true)
+				require.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestRun_AnnotatedTags(t *testing.T) {
+	tmp := t.TempDir()
+	source := filepath.Join(tmp, "repo.dump")
+	require.NoError(t, os.WriteFile(source, []byte(svnLayoutDump()), 0644))
+
+	fixed := time.Date(2030, 5, 6, 7, 8, 9, 0, time.UTC)
+	tests := []struct {
+		name         string
+		tagger       string
+		tagDate      time.Time
+		expectTagger string
+		expectDate   time.Time
+	}{
+		{"defaults", "", time.Time{}, "alice", time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)},
+		{"configured", "Release Bot <bot@example.com>", fixed, "Release Bot", fixed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &MigrationConfig{
+				SourceType: "svn",
+				SourcePath: source,
+				SVNLayout:  svn.StandardLayout,
+				TargetPath: filepath.Join(t.TempDir(), "repo"),
+				TagMap:     map[string]string{"1.0": "v1.0"},
+				TagType:    "annotated",
+				TagMessage: "Release {tag} (CVS {cvsTag})",
+				Tagger:     tt.tagger,
+				TagDate:    tt.tagDate,
+			}
+			m := NewMigrator(cfg)
+			require.NoError(t, m.Run())
+			defer m.db.Close()
+
+			repo, err := gogit.PlainOpen(cfg.TargetPath)
+			require.NoError(t, err)
+			ref, err := repo.Reference(plumbing.NewTagReferenceName("v1.0"), true)
+			require.NoError(t, err)
+			tag, err := repo.TagObject(ref.Hash())
+			require.NoError(t, err)
+
+			require.Equal(t, "Release v1.0 (CVS 1.0)", tag.Message)
+			require.Equal(t, m.marks["trunk@1"], tag.Target.String())
+			require.Equal(t, tt.expectTagger, tag.Tagger.Name)
+			require.True(t, tt.expectDate.Equal(tag.Tagger.When), "tag date %v", tag.Tagger.When)
+		})
+	}
+
+	m := NewMigrator(&MigrationConfig{
+		SourceType: "svn",
+		SourcePath: source,
+		TargetPath: filepath.Join(t.TempDir(), "repo"),
+		TagType:    "signed",
+	})
+	require.ErrorContains(t, m.Run(), "unsupported tag type")
+	m.db.Close()
+}
+
+// Mock reader with named branches and tags
+type mockReaderWithRefs struct {
+	mockReaderWithCommits
+	branches []string
+	tags     map[string]string
+}
+
+func (m *mockReaderWithRefs) GetBranches() ([]string, error)      { return m.branches, nil }
+func (m *mockReaderWithRefs) GetTags() (map[string]string, error) { return m.tags, nil }
+
+func TestRun_RefRules(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	file := func(content string) []vcs.FileChange {
+		return []vcs.FileChange{{Path: "file.txt", Action: vcs.ActionModify, Content: []byte(content)}}
+	}
+	commits := []*vcs.Commit{
+		{Revision: "r1", Author: "a", Date: base, Message: "trunk", Files: file("one")},
+		{Revision: "b1", Author: "a", Date: base.Add(time.Hour), Message: "release", Branch: "RELEASE_1_0", Parent: "r1", Files: file("rel")},
+		{Revision: "t1", Author: "a", Date: base.Add(2 * time.Hour), Message: "temp", Branch: "temp-x", Parent: "r1", Files: file("tmp")},
+	}
+	reader := func() *mockReaderWithRefs {
+		return &mockReaderWithRefs{
+			mockReaderWithCommits: mockReaderWithCommits{commits: commits},
+			branches:              []string{"RELEASE_1_0", "temp-x"},
+			tags:                  map[string]string{"REL_1_0": "b1", "NIGHTLY_20240101": "r1"},
+		}
+	}
+
+	repoPath := filepath.Join(t.TempDir(), "repo")
+	m := NewMigrator(&MigrationConfig{
+		SourceType:   "cvs",
+		SourcePath:   "/src",
+		TargetPath:   repoPath,
+		BranchRules:  []mapping.RefRule{{Pattern: `^RELEASE_(\d+)_(\d+)$`, Replace: "release/$1.$2"}},
+		SkipBranches: []string{"temp-*"},
+		TagRules:     []mapping.RefRule{{Pattern: `^REL_(\d+)_(\d+)$`, Replace: "v$1.$2"}},
+		SkipTags:     []string{"NIGHTLY_*"},
+	})
+	m.source = reader()
+	require.NoError(t, m.Run())
+	defer m.db.Close()
+
+	repo, err := gogit.PlainOpen(repoPath)
+	require.NoError(t, err)
+	release, err := repo.Reference(plumbing.NewBranchReferenceName("release/1.0"), true)
+	require.NoError(t, err)
+	require.Equal(t, m.marks["b1"], release.Hash().String())
+	tag, err := repo.Reference(plumbing.NewTagReferenceName("v1.0"), true)
+	require.NoError(t, err)
+	require.Equal(t, m.marks["b1"], tag.Hash().String())
+
+	// Skipped branches and tags are not migrated at all
+	_, ok := m.marks["t1"]
+	require.False(t, ok)
+	refs, err := repo.References()
+	require.NoError(t, err)
+	var names []string
+	require.NoError(t, refs.ForEach(func(ref *plumbing.Reference) error {
+		names = append(names, ref.Name().String())
+		return nil
+	}))
+	require.ElementsMatch(t, []string{"HEAD", "refs/heads/master", "refs/heads/release/1.0", "refs/tags/v1.0"}, names)
+
+	// Colliding and invalid names stop the migration before anything is written
+	tests := []struct {
+		name   string
+		config MigrationConfig
+		errMsg string
+	}{
+		{"collision", MigrationConfig{BranchRules: []mapping.RefRule{{Pattern: ".*", Replace: "same"}}}, "RELEASE_1_0, temp-x all map to same"},
+		{"trunk", MigrationConfig{BranchMap: map[string]string{"RELEASE_1_0": "master"}}, "maps to the trunk branch master"},
+		{"invalid", MigrationConfig{TagRules: []mapping.RefRule{{Pattern: "_", Replace: ".."}}}, "is not a valid Git ref name"},
+		{"bad pattern", MigrationConfig{SkipTags: []string{"["}}, "invalid tag mapping"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.config
+			cfg.SourceType, cfg.SourcePath = "cvs", "/src"
+			cfg.TargetPath = filepath.Join(t.TempDir(), "repo")
+			m := NewMigrator(&cfg)
+			m.source = reader()
+			err := m.Run()
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.errMsg)
+			_, err = os.Stat(cfg.TargetPath)
+			require.True(t, os.IsNotExist(err))
+		})
+	}
+}
+
+func TestRun_AuthorRules(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	commits := func() []*vcs.Commit {
+		return []*vcs.Commit{
+			{Revision: "r1", Author: "jsmith", Date: base, Message: "one", Files: []vcs.FileChange{
+				{Path: "file.txt", Action: vcs.ActionAdd, Content: []byte("one")},
+			}},
+			{Revision: "r2", Author: "buildbot", Date: base.Add(time.Hour), Message: "two", Files: []vcs.FileChange{
+				{Path: "file.txt", Action: vcs.ActionModify, Content: []byte("two")},
+			}},
+			{Revision: "r3", Author: "olddev", Date: base.Add(2 * time.Hour), Message: "three", Files: []vcs.FileChange{
+				{Path: "file.txt", Action: vcs.ActionModify, Content: []byte("three")},
+			}},
+		}
+	}
+	rules := []mapping.AuthorRule{{Pattern: "^(buildbot|jenkins)$", Name: "CI ($1)", Email: "ci@example.com"}}
+
+	repoPath := filepath.Join(t.TempDir(), "repo")
+	m := NewMigrator(&MigrationConfig{
+		SourceType:    "cvs",
+		SourcePath:    "/src",
+		TargetPath:    repoPath,
+		AuthorMap:     map[string]string{"jsmith": "John Smith <john@example.com>"},
+		AuthorRules:   rules,
+		DefaultAuthor: &mapping.AuthorRule{Name: "Unknown ($1)", Email: "unknown@example.com"},
+		StrictAuthors: true,
+	})
+	m.source = &mockReaderWithCommits{commits: commits()}
+	require.NoError(t, m.Run())
+	defer m.db.Close()
+
+	repo, err := gogit.PlainOpen(repoPath)
+	require.NoError(t, err)
+	log, err := repo.Log(&gogit.LogOptions{})
+	require.NoError(t, err)
+	var authors []string
+	require.NoError(t, log.ForEach(func(c *object.Commit) error {
+		authors = append(authors, c.Author.Name+" <"+c.Author.Email+">")
+		return nil
+	}))
+	require.Equal(t, []string{
+		"Unknown (olddev) <unknown@example.com>",
+		"CI (buildbot) <ci@example.com>",
+		"John Smith <john@example.com>",
+	}, authors)
+
+	// Without a default author, strict mode lists every unmapped user
+	repoPath = filepath.Join(t.TempDir(), "repo")
+	m = NewMigrator(&MigrationConfig{
+		SourceType:    "cvs",
+		SourcePath:    "/src",
+		TargetPath:    repoPath,
+		StrictAuthors: true,
+		AuthorRules:   rules,
+	})
+	m.source = &mockReaderWithCommits{commits: commits()}
+	err = m.Run()
+	require.EqualError(t, err, "2 unmapped authors: jsmith, olddev")
+	_, err = os.Stat(repoPath)
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestRun_AuthorHistory(t *testing.T) {
+	switchover := time.Date(2009, 1, 1, 0, 0, 0, 0, time.UTC)
+	commits := []*vcs.Commit{
+		{Revision: "r1", Author: "jsmith", Date: switchover.AddDate(-1, 0, 0), Message: "old", Files: []vcs.FileChange{
+			{Path: "file.txt", Action: vcs.ActionAdd, Content: []byte("one")},
+		}},
+		{Revision: "r2", Author: "jsmith", Date: switchover.AddDate(1, 0, 0), Message: "new", Files: []vcs.FileChange{
+			{Path: "file.txt", Action: vcs.ActionModify, Content: []byte("two")},
+		}},
+	}
+
+	repoPath := filepath.Join(t.TempDir(), "repo")
+	m := NewMigrator(&MigrationConfig{
+		SourceType: "cvs",
+		SourcePath: "/src",
+		TargetPath: repoPath,
+		AuthorMap:  map[string]string{"jsmith": "John Smith <john.smith@newcorp.com>"},
+		AuthorHistory: mapping.AuthorHistory{
+			"jsmith": {{Author: "John Smith <jsmith@oldcorp.com>", Until: switchover}},
+		},
+		StrictAuthors: true,
+	})
+	m.source = &mockReaderWithCommits{commits: commits}
+	require.NoError(t, m.Run())
+	defer m.db.Close()
+
+	repo, err := gogit.PlainOpen(repoPath)
+	require.NoError(t, err)
+	for revision, email := range map[string]string{"r1": "jsmith@oldcorp.com", "r2": "john.smith@newcorp.com"} {
+		commit, err := repo.CommitObject(plumbing.NewHash(m.marks[revision]))
+		require.NoError(t, err)
+		require.Equal(t, email, commit.Author.Email, revision)
+	}
+}
+
+func TestRun_Mailmap(t *testing.T) {
+	switchover := time.Date(2009, 1, 1, 0, 0, 0, 0, time.UTC)
+	commits := func() []*vcs.Commit {
+		return []*vcs.Commit{
+			{Revision: "r1", Author: "jsmith", Date: switchover.AddDate(-1, 0, 0), Message: "old", Files: []vcs.FileChange{
+				{Path: "file.txt", Action: vcs.ActionAdd, Content: []byte("one")},
+			}},
+			{Revision: "r2", Author: "jsmith", Date: switchover.AddDate(1, 0, 0), Message: "new", Files: []vcs.FileChange{
+				{Path: "file.txt", Action: vcs.ActionModify, Content: []byte("two")},
+			}},
+		}
+	}
+	config := func(repoPath, mode string) *MigrationConfig {
+		return &MigrationConfig{
+			SourceType: "cvs",
+			SourcePath: "/src",
+			TargetPath: repoPath,
+			Mailmap:    mode,
+			AuthorMap:  map[string]string{"jsmith": "John Smith <john.smith@newcorp.com>"},
+			AuthorHistory: mapping.AuthorHistory{
+				"jsmith": {{Author: "John Smith <jsmith@oldcorp.com>", Until: switchover}},
+			},
+		}
+	}
+	entry := "John Smith <john.smith@newcorp.com> John Smith <jsmith@oldcorp.com>\n"
+
+	t.Run("commit", func(t *testing.T) {
+		repoPath := filepath.Join(t.TempDir(), "repo")
+		m := NewMigrator(config(repoPath, "commit"))
+		m.source = &mockReaderWithCommits{commits: commits()}
+		require.NoError(t, m.Run())
+		defer m.db.Close()
+
+		repo, err := gogit.PlainOpen(repoPath)
+		require.NoError(t, err)
+		head, err := repo.Head()
+		require.NoError(t, err)
+		require.Equal(t, plumbing.NewBranchReferenceName("master"), head.Name())
+		commit, err := repo.CommitObject(head.Hash())
+		require.NoError(t, err)
+		require.Equal(t, m.marks["r2"], commit.ParentHashes[0].String())
+		require.Equal(t, switchover.AddDate(1, 0, 0).Unix(), commit.Author.When.Unix())
+
+		file, err := commit.File(".mailmap")
+		require.NoError(t, err)
+		content, err := file.Contents()
+		require.NoError(t, err)
+		require.Contains(t, content, entry)
+	})
+
+	t.Run("untracked", func(t *testing.T) {
+		repoPath := filepath.Join(t.TempDir(), "repo")
+		m := NewMigrator(config(repoPath, "untracked"))
+		m.source = &mockReaderWithCommits{commits: commits()}
+		require.NoError(t, m.Run())
+		defer m.db.Close()
+
+		content, err := os.ReadFile(filepath.Join(repoPath, ".mailmap"))
+		require.NoError(t, err)
+		require.Contains(t, string(content), entry)
+
+		repo, err := gogit.PlainOpen(repoPath)
+		require.NoError(t, err)
+		head, err := repo.Head()
+		require.NoError(t, err)
+		require.Equal(t, m.marks["r2"], head.Hash().String())
+	})
+
+	t.Run("untracked bare", func(t *testing.T) {
+		cfg := config(filepath.Join(t.TempDir(), "repo.git"), "untracked")
+		cfg.Bare = true
+		m := NewMigrator(cfg)
+		m.source = &mockReaderWithCommits{commits: commits()}
+		require.ErrorContains(t, m.Run(), "needs a target with a worktree")
+	})
+
+	t.Run("no entries", func(t *testing.T) {
+		repoPath := filepath.Join(t.TempDir(), "repo")
+		cfg := config(repoPath, "commit")
+		cfg.AuthorHistory = nil
+		m := NewMigrator(cfg)
+		m.source = &mockReaderWithCommits{commits: commits()}
+		require.NoError(t, m.Run())
+		defer m.db.Close()
+
+		// Every user has one identity, so no .mailmap commit is made
+		repo, err := gogit.PlainOpen(repoPath)
+		require.NoError(t, err)
+		head, err := repo.Head()
+		require.NoError(t, err)
+		require.Equal(t, m.marks["r2"], head.Hash().String())
+	})
+}
@@ -4,15 +4,62 @@ package mapping
 import (
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
+	"time"
 )
 
 // AuthorMap maps CVS usernames to Git author info
 type AuthorMap struct {
 	mapping      map[string]string
+	history      AuthorHistory
+	rules        []authorRule
 	defaultEmail string
 }
 
+// AuthorHistory maps usernames to their identities over time
+type AuthorHistory map[string][]AuthorPeriod
+
+// AuthorPeriod is the identity of a user during a span of time. From is
+// inclusive and Until exclusive; a zero time leaves that end open.
+type AuthorPeriod struct {
+	Author string    `yaml:"author" json:"author"` // "Name <email>"
+	From   time.Time `yaml:"from" json:"from"`
+	Until  time.Time `yaml:"until" json:"until"`
+}
+
+// contains reports whether a time falls within the period
+func (p AuthorPeriod) contains(when time.Time) bool {
+	return (p.From.IsZero() || !when.Before(p.From)) && (p.Until.IsZero() || when.Before(p.Until))
+}
+
+// AuthorRule maps usernames matching a regular expression. Name and Email
+// are templates that may refer to capture groups as $1 or ${name}; an
+// empty Name is the username and an empty Email is the username at the
+// default domain.
+type AuthorRule struct {
+	Pattern string `yaml:"pattern" json:"pattern"`
+	Name    string `yaml:"name" json:"name"`
+	Email   string `yaml:"email" json:"email"`
+}
+
+// AuthorMapOptions configures how usernames without a direct mapping are
+// mapped
+type AuthorMapOptions struct {
+	History       AuthorHistory // Identities of users over time, taking precedence over other mappings
+	Rules         []AuthorRule  // Tried in order, the first match wins
+	Default       *AuthorRule   // Identity of users nothing else maps; Pattern is ignored and $1 is the username
+	DefaultDomain string        // Email domain of unmapped users
+}
+
+type authorRule struct {
+	re          *regexp.Regexp
+	name, email string
+}
+
+// defaultAuthorPattern captures the whole username for the default identity
+var defaultAuthorPattern = regexp.MustCompile(`^(.*)$`)
+
 // NewAuthorMap creates a new author map
 func NewAuthorMap(config map[string]string) *AuthorMap {
 	return &AuthorMap{
@@ -29,17 +76,109 @@ func NewAuthorMapWithDefault(config map[string]string, defaultDomain string) *Au
 	}
 }
 
+// NewAuthorMapWithOptions creates an author map with mapping rules
+func NewAuthorMapWithOptions(config map[string]string, options AuthorMapOptions) (*AuthorMap, error) {
+	am := NewAuthorMap(config)
+	if options.DefaultDomain != "" {
+		am.defaultEmail = options.DefaultDomain
+	}
+
+	for username, periods := range options.History {
+		for _, period := range periods {
+			if _, _, err := ParseAuthor(period.Author); err != nil {
+				return nil, fmt.Errorf("author history of %s: %w", username, err)
+			}
+			if !period.From.IsZero() && !period.Until.IsZero() && !period.From.Before(period.Until) {
+				return nil, fmt.Errorf("author history of %s: %s ends before it starts", username, period.Author)
+			}
+		}
+	}
+	am.history = options.History
+
+	for _, rule := range options.Rules {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid author pattern %q: %w", rule.Pattern, err)
+		}
+		am.rules = append(am.rules, authorRule{re: re, name: rule.Name, email: rule.Email})
+	}
+	if options.Default != nil {
+		am.rules = append(am.rules, authorRule{re: defaultAuthorPattern, name: options.Default.Name, email: options.Default.Email})
+	}
+	return am, nil
+}
+
 // Get returns the Git author name and email for a CVS username
 func (am *AuthorMap) Get(username string) (string, string) {
+	if name, email, ok := am.Lookup(username); ok {
+		return name, email
+	}
+
+	// Default format: username <username@default>
+	return username, fmt.Sprintf("%s@%s", username, am.defaultEmail)
+}
+
+// GetAt returns the Git author name and email of a CVS username at the
+// time of a commit
+func (am *AuthorMap) GetAt(username string, when time.Time) (string, string) {
+	if name, email, ok := am.LookupAt(username, when); ok {
+		return name, email
+	}
+	return am.Get(username)
+}
+
+// LookupAt returns the Git author name and email of a CVS username at the
+// time of a commit. The first period of its history containing the time
+// wins; otherwise the username is looked up as by Lookup.
+func (am *AuthorMap) LookupAt(username string, when time.Time) (string, string, bool) {
+	for _, period := range am.history[username] {
+		if period.contains(when) {
+			if name, email, err := ParseAuthor(period.Author); err == nil {
+				return name, email, true
+			}
+		}
+	}
+	return am.Lookup(username)
+}
+
+// Lookup returns the Git author name and email for a CVS username from
+// its mapping or the first rule matching it, or false if neither does
+func (am *AuthorMap) Lookup(username string) (string, string, bool) {
 	if format, ok := am.mapping[username]; ok {
 		name, email, err := ParseAuthor(format)
 		if err == nil {
-			return name, email
+			return name, email, true
 		}
 	}
 
-	// Default format: username <username@default>
-	return username, fmt.Sprintf("%s@%s", username, am.defaultEmail)
+	for _, rule := range am.rules {
+		match := rule.re.FindStringSubmatchIndex(username)
+		if match == nil {
+			continue
+		}
+		name := username
+		if rule.name != "" {
+			name = string(rule.re.ExpandString(nil, rule.name, username, match))
+		}
+		email := fmt.Sprintf("%s@%s", username, am.defaultEmail)
+		if rule.email != "" {
+			email = string(rule.re.ExpandString(nil, rule.email, username, match))
+		}
+		return strings.TrimSpace(name), strings.TrimSpace(email), true
+	}
+	return "", "", false
+}
+
+// Unmapped returns the sorted usernames that no mapping or rule maps
+func (am *AuthorMap) Unmapped(usernames []string) []string {
+	var unmapped []string
+	for _, username := range usernames {
+		if _, _, ok := am.Lookup(username); !ok {
+			unmapped = append(unmapped, username)
+		}
+	}
+	sort.Strings(unmapped)
+	return unmapped
 }
 
 // ParseAuthor parses a "Name <email>" string
@@ -64,12 +203,22 @@ func ParseAuthor(format string) (string, string, error) {
 // AuthorExtractor extracts unique authors from a repository
 type AuthorExtractor struct {
 	authors map[string]bool
+	stats   map[string]*AuthorStats
+}
+
+// AuthorStats summarizes the commits of a user
+type AuthorStats struct {
+	Username string
+	Commits  int
+	First    time.Time
+	Last     time.Time
 }
 
 // NewAuthorExtractor creates a new author extractor
 func NewAuthorExtractor() *AuthorExtractor {
 	return &AuthorExtractor{
 		authors: make(map[string]bool),
+		stats:   make(map[string]*AuthorStats),
 	}
 }
 
@@ -78,6 +227,39 @@ func (ae *AuthorExtractor) Add(username string) {
 	ae.authors[username] = true
 }
 
+// AddCommit adds the author of a commit and counts the commit
+func (ae *AuthorExtractor) AddCommit(username string, when time.Time) {
+	ae.Add(username)
+
+	stats, ok := ae.stats[username]
+	if !ok {
+		stats = &AuthorStats{Username: username, First: when, Last: when}
+		ae.stats[username] = stats
+	}
+	stats.Commits++
+	if when.Before(stats.First) {
+		stats.First = when
+	}
+	if when.After(stats.Last) {
+		stats.Last = when
+	}
+}
+
+// Stats returns the commit statistics of all authors, sorted by username.
+// Authors added without a commit have none.
+func (ae *AuthorExtractor) Stats() []AuthorStats {
+	result := make([]AuthorStats, 0, len(ae.authors))
+	for author := range ae.authors {
+		if stats, ok := ae.stats[author]; ok {
+			result = append(result, *stats)
+		} else {
+			result = append(result, AuthorStats{Username: author})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Username < result[j].Username })
+	return result
+}
+
 // List returns all unique authors
 func (ae *AuthorExtractor) List() []string {
 	var result []string
@@ -1,7 +1,9 @@
 package mapping
 
 import (
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestNewAuthorMap(t *testing.T) {
@@ -393,3 +395,153 @@ func TestParseAuthorSpecialCharacters(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthorMapRules(t *testing.T) {
+	am, err := NewAuthorMapWithOptions(map[string]string{
+		"jsmith": "John Smith <john@example.com>",
+		"broken": "not an author",
+	}, AuthorMapOptions{
+		Rules: []AuthorRule{
+			{Pattern: `^(buildbot|jenkins)$`, Name: "CI System", Email: "ci@example.com"},
+			{Pattern: `^(?P<first>[a-z])(?P<last>[a-z]+)_ext$`, Name: "${last}", Email: "${first}.${last}@partner.example.com"},
+			{Pattern: `^svc-(.+)$`, Email: "$1@services.example.com"},
+		},
+		DefaultDomain: "example.org",
+	})
+	if err != nil {
+		t.Fatalf("NewAuthorMapWithOptions failed: %v", err)
+	}
+
+	tests := []struct {
+		username, name, email string
+		ok                    bool
+	}{
+		{"jsmith", "John Smith", "john@example.com", true},
+		{"jenkins", "CI System", "ci@example.com", true},
+		{"mjones_ext", "jones", "m.jones@partner.example.com", true},
+		{"svc-backup", "svc-backup", "backup@services.example.com", true},
+		{"broken", "", "", false},
+		{"nobody", "", "", false},
+	}
+	for _, tt := range tests {
+		name, email, ok := am.Lookup(tt.username)
+		if name != tt.name || email != tt.email || ok != tt.ok {
+			t.Errorf("Lookup(%q) = %q, %q, %v, want %q, %q, %v", tt.username, name, email, ok, tt.name, tt.email, tt.ok)
+		}
+	}
+
+	// Unmapped users keep the default format
+	name, email := am.Get("nobody")
+	if name != "nobody" || email != "nobody@example.org" {
+		t.Errorf("Get(nobody) = %q, %q", name, email)
+	}
+
+	unmapped := am.Unmapped([]string{"nobody", "jsmith", "broken", "jenkins"})
+	if strings.Join(unmapped, ",") != "broken,nobody" {
+		t.Errorf("Unmapped = %v, want [broken nobody]", unmapped)
+	}
+}
+
+func TestAuthorMapDefaultAuthor(t *testing.T) {
+	am, err := NewAuthorMapWithOptions(nil, AuthorMapOptions{
+		Default: &AuthorRule{Name: "Former Developer ($1)", Email: "$1@former.example.com"},
+	})
+	if err != nil {
+		t.Fatalf("NewAuthorMapWithOptions failed: %v", err)
+	}
+
+	name, email := am.Get("olddev")
+	if name != "Former Developer (olddev)" || email != "olddev@former.example.com" {
+		t.Errorf("Get(olddev) = %q, %q", name, email)
+	}
+	if unmapped := am.Unmapped([]string{"olddev"}); len(unmapped) != 0 {
+		t.Errorf("Unmapped = %v, want none", unmapped)
+	}
+}
+
+func TestAuthorMapInvalidRule(t *testing.T) {
+	if _, err := NewAuthorMapWithOptions(nil, AuthorMapOptions{Rules: []AuthorRule{{Pattern: "("}}}); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+}
+
+func TestAuthorMapHistory(t *testing.T) {
+	switchover := time.Date(2009, 1, 1, 0, 0, 0, 0, time.UTC)
+	am, err := NewAuthorMapWithOptions(map[string]string{
+		"jsmith": "John Smith <john@example.com>",
+	}, AuthorMapOptions{
+		History: AuthorHistory{
+			"jsmith": {
+				{Author: "John Smith <jsmith@oldcorp.com>", Until: switchover},
+				{Author: "Jane Smith <jane@newcorp.com>", From: switchover.AddDate(5, 0, 0)},
+			},
+			"mjones": {
+				{Author: "Mary Jones <mary@oldcorp.com>", Until: switchover},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewAuthorMapWithOptions failed: %v", err)
+	}
+
+	tests := []struct {
+		username string
+		when     time.Time
+		name     string
+		email    string
+	}{
+		{"jsmith", switchover.Add(-time.Second), "John Smith", "jsmith@oldcorp.com"},
+		{"jsmith", switchover, "John Smith", "john@example.com"},
+		{"jsmith", switchover.AddDate(6, 0, 0), "Jane Smith", "jane@newcorp.com"},
+		{"mjones", switchover.AddDate(-1, 0, 0), "Mary Jones", "mary@oldcorp.com"},
+		{"mjones", switchover, "mjones", "mjones@users.noreply.cvs.example.org"},
+	}
+	for _, tt := range tests {
+		name, email := am.GetAt(tt.username, tt.when)
+		if name != tt.name || email != tt.email {
+			t.Errorf("GetAt(%q, %s) = %q, %q, want %q, %q", tt.username, tt.when, name, email, tt.name, tt.email)
+		}
+	}
+
+	if _, _, ok := am.LookupAt("mjones", switchover); ok {
+		t.Error("mjones should be unmapped after the end of their history")
+	}
+}
+
+func TestAuthorMapHistoryInvalid(t *testing.T) {
+	when := time.Date(2009, 1, 1, 0, 0, 0, 0, time.UTC)
+	invalid := []AuthorHistory{
+		{"jsmith": {{Author: "John Smith"}}},
+		{"jsmith": {{Author: "John Smith <john@example.com>", From: when, Until: when}}},
+	}
+	for _, history := range invalid {
+		if _, err := NewAuthorMapWithOptions(nil, AuthorMapOptions{History: history}); err == nil {
+			t.Errorf("expected error for %v", history)
+		}
+	}
+}
+
+func TestAuthorExtractorStats(t *testing.T) {
+	day := time.Date(2005, 3, 1, 0, 0, 0, 0, time.UTC)
+	ae := NewAuthorExtractor()
+	ae.AddCommit("jsmith", day)
+	ae.AddCommit("mjones", day.AddDate(0, 0, 1))
+	ae.AddCommit("jsmith", day.AddDate(1, 0, 0))
+	ae.AddCommit("jsmith", day.AddDate(0, -1, 0))
+	ae.Add("tom")
+
+	stats := ae.Stats()
+	expected := []AuthorStats{
+		{Username: "jsmith", Commits: 3, First: day.AddDate(0, -1, 0), Last: day.AddDate(1, 0, 0)},
+		{Username: "mjones", Commits: 1, First: day.AddDate(0, 0, 1), Last: day.AddDate(0, 0, 1)},
+		{Username: "tom"},
+	}
+	if len(stats) != len(expected) {
+		t.Fatalf("Stats() = %v, want %v", stats, expected)
+	}
+	for i := range expected {
+		if stats[i] != expected[i] {
+			t.Errorf("Stats()[%d] = %v, want %v", i, stats[i], expected[i])
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package mapping
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Author file formats
+const (
+	AuthorsYAML    = "yaml"    // username: "Name <email>"
+	AuthorsText    = "text"    // username = Name <email>, as used by cvs2svn and git-svn
+	AuthorsCSV     = "csv"     // username, name and email columns
+	AuthorsMailmap = "mailmap" // Git .mailmap
+)
+
+// AuthorEntry is one username mapping read from an author file
+type AuthorEntry struct {
+	Username string
+	Author   string // "Name <email>"
+	Source   string // file:line the entry was read from
+}
+
+// AuthorsFileFormat guesses the format of an author file from its name:
+// .yaml and .yml files are YAML, .csv files CSV and .mailmap files mailmaps.
+// Anything else is read as an authors.txt file.
+func AuthorsFileFormat(path string) string {
+	base := strings.ToLower(filepath.Base(path))
+	switch {
+	case strings.HasSuffix(base, ".yaml"), strings.HasSuffix(base, ".yml"):
+		return AuthorsYAML
+	case strings.HasSuffix(base, ".csv"):
+		return AuthorsCSV
+	case strings.HasSuffix(base, ".mailmap"), base == "mailmap":
+		return AuthorsMailmap
+	default:
+		return AuthorsText
+	}
+}
+
+// LoadAuthorsFile reads the author mappings of a file in the format its
+// name suggests, failing on entries that are not "Name <email>"
+func LoadAuthorsFile(path string) ([]AuthorEntry, error) {
+	entries, err := ReadAuthorsFile(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if _, _, err := ParseAuthor(entry.Author); err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Source, err)
+		}
+	}
+	return entries, nil
+}
+
+// ReadAuthorsFile reads the author mappings of a file like LoadAuthorsFile
+// but leaves malformed authors for the caller to report
+func ReadAuthorsFile(path string) ([]AuthorEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read authors file: %w", err)
+	}
+
+	var entries []AuthorEntry
+	switch AuthorsFileFormat(path) {
+	case AuthorsYAML:
+		entries, err = parseAuthorsYAML(data, path)
+	case AuthorsCSV:
+		entries, err = parseAuthorsCSV(data, path)
+	case AuthorsMailmap:
+		entries, err = parseMailmap(data, path)
+	default:
+		entries, err = parseAuthorsText(data, path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// MergeAuthors merges author entries into one mapping. Later entries take
+// precedence over earlier ones. It also reports usernames that are listed
+// more than once, and whether the repeated entries agree.
+func MergeAuthors(entries []AuthorEntry) (map[string]string, []string) {
+	merged := make(map[string]string)
+	sources := make(map[string]string)
+	var problems []string
+	for _, entry := range entries {
+		if previous, ok := merged[entry.Username]; ok {
+			if previous == entry.Author {
+				problems = append(problems, fmt.Sprintf("%s: duplicate entry for %s, also at %s",
+					entry.Source, entry.Username, sources[entry.Username]))
+			} else {
+				problems = append(problems, fmt.Sprintf("%s: %s maps to %s, overriding %s from %s",
+					entry.Source, entry.Username, entry.Author, previous, sources[entry.Username]))
+			}
+		}
+		merged[entry.Username] = entry.Author
+		sources[entry.Username] = entry.Source
+	}
+	return merged, problems
+}
+
+func parseAuthorsYAML(data []byte, path string) ([]AuthorEntry, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+
+	// Walk the nodes rather than decoding into a map to keep line
+	// numbers and repeated keys
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s: expected a map of usernames to authors", path)
+	}
+	var entries []AuthorEntry
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		if value.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("%s:%d: author of %s is not a string", path, value.Line, key.Value)
+		}
+		entries = append(entries, AuthorEntry{
+			Username: key.Value,
+			Author:   value.Value,
+			Source:   fmt.Sprintf("%s:%d", path, key.Line),
+		})
+	}
+	return entries, nil
+}
+
+// parseAuthorsText reads "username = Name <email>" lines. A line without
+// "=" maps its first word to the rest of the line, leaving the malformed
+// author to be reported.
+func parseAuthorsText(data []byte, path string) ([]AuthorEntry, error) {
+	var entries []AuthorEntry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		username, author, ok := strings.Cut(text, "=")
+		if !ok {
+			username = strings.Fields(text)[0]
+			author = strings.TrimPrefix(text, username)
+		}
+		entries = append(entries, AuthorEntry{
+			Username: strings.TrimSpace(username),
+			Author:   strings.TrimSpace(author),
+			Source:   fmt.Sprintf("%s:%d", path, line),
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// csvColumns are the header names accepted for each CSV column
+var csvColumns = map[string][]string{
+	"username": {"username", "user", "login", "cvs", "account"},
+	"name":     {"name", "full name", "fullname", "display name"},
+	"email":    {"email", "e-mail", "mail"},
+}
+
+// parseAuthorsCSV reads username, name and email columns. A header row
+// naming the columns lets them appear in any order among other columns;
+// without one the first three columns are used. A first row whose third
+// column is not an email address is a header and must name every column.
+func parseAuthorsCSV(data []byte, path string) ([]AuthorEntry, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	columns := map[string]int{"username": 0, "name": 1, "email": 2}
+	var entries []AuthorEntry
+	for first := true; ; first = false {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		if first && !(len(record) >= 3 && strings.Contains(record[2], "@")) {
+			header, err := csvHeader(record)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+			}
+			columns = header
+			continue
+		}
+
+		field := func(column string) string {
+			if i := columns[column]; i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		if field("username") == "" {
+			continue
+		}
+		entries = append(entries, AuthorEntry{
+			Username: field("username"),
+			Author:   fmt.Sprintf("%s <%s>", field("name"), field("email")),
+			Source:   fmt.Sprintf("%s:%d", path, line),
+		})
+	}
+	return entries, nil
+}
+
+// csvHeader finds the columns of a header row, which must name all of them
+func csvHeader(record []string) (map[string]int, error) {
+	columns := make(map[string]int)
+	for i, cell := range record {
+		cell = strings.ToLower(strings.TrimSpace(cell))
+		for column, names := range csvColumns {
+			for _, name := range names {
+				if _, ok := columns[column]; !ok && cell == name {
+					columns[column] = i
+				}
+			}
+		}
+	}
+
+	var missing []string
+	for _, column := range []string{"username", "name", "email"} {
+		if _, ok := columns[column]; !ok {
+			missing = append(missing, column)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("header %q has no %s column (accepted names: %s)",
+			strings.Join(record, ","), missing[0], strings.Join(csvColumns[missing[0]], ", "))
+	}
+	return columns, nil
+}
+
+// mailmapIdentity matches one "Name <email>" of a mailmap line; the name
+// is optional
+var mailmapIdentity = regexp.MustCompile(`\s*([^<]*?)\s*<([^>]*)>`)
+
+// parseMailmap reads a Git .mailmap. The commit identity of each line
+// names the source user: its name if it has one, otherwise the local part
+// of its email. Lines with a single identity map the user of its email.
+func parseMailmap(data []byte, path string) ([]AuthorEntry, error) {
+	var entries []AuthorEntry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if i := strings.Index(text, "#"); i >= 0 {
+			text = text[:i]
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		identities := mailmapIdentity.FindAllStringSubmatch(text, -1)
+		if len(identities) == 0 || len(identities) > 2 {
+			return nil, fmt.Errorf("%s:%d: expected \"Name <email> [Name] <email>\"", path, line)
+		}
+		proper := identities[0]
+		commit := identities[len(identities)-1]
+
+		username := commit[1]
+		if len(identities) == 1 || username == "" {
+			username, _, _ = strings.Cut(commit[2], "@")
+		}
+		name := proper[1]
+		if name == "" {
+			// A line that only corrects the email keeps the user's name
+			name = username
+		}
+		entries = append(entries, AuthorEntry{
+			Username: username,
+			Author:   fmt.Sprintf("%s <%s>", name, proper[2]),
+			Source:   fmt.Sprintf("%s:%d", path, line),
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,177 @@
+package mapping
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeAuthorsFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// authorsOf returns the mapping of entries, which must not repeat usernames
+func authorsOf(t *testing.T, entries []AuthorEntry) map[string]string {
+	t.Helper()
+
+	authors, problems := MergeAuthors(entries)
+	if len(problems) > 0 {
+		t.Errorf("unexpected problems: %v", problems)
+	}
+	return authors
+}
+
+func TestAuthorsFileFormat(t *testing.T) {
+	tests := map[string]string{
+		"authors.yaml":        AuthorsYAML,
+		"AUTHORS.YML":         AuthorsYAML,
+		"export.csv":          AuthorsCSV,
+		".mailmap":            AuthorsMailmap,
+		"old/project.mailmap": AuthorsMailmap,
+		"authors.txt":         AuthorsText,
+		"authors":             AuthorsText,
+	}
+	for path, expected := range tests {
+		if got := AuthorsFileFormat(path); got != expected {
+			t.Errorf("AuthorsFileFormat(%q) = %q, want %q", path, got, expected)
+		}
+	}
+}
+
+func TestLoadAuthorsFileYAML(t *testing.T) {
+	path := writeAuthorsFile(t, "authors.yaml", `# Authors
+jsmith: "John Smith <john@example.com>"
+mjones: Mary Jones <mary@example.com>
+`)
+	entries, err := LoadAuthorsFile(path)
+	if err != nil {
+		t.Fatalf("LoadAuthorsFile failed: %v", err)
+	}
+	if entries[1].Source != path+":3" {
+		t.Errorf("Source = %q, want %q", entries[1].Source, path+":3")
+	}
+	authors := authorsOf(t, entries)
+	if len(authors) != 2 || authors["mjones"] != "Mary Jones <mary@example.com>" {
+		t.Errorf("authors = %v", authors)
+	}
+}
+
+func TestLoadAuthorsFileText(t *testing.T) {
+	path := writeAuthorsFile(t, "authors.txt", `# git-svn authors
+jsmith = John Smith <john@example.com>
+
+mjones=Mary Jones <mary@example.com>
+`)
+	entries, err := LoadAuthorsFile(path)
+	if err != nil {
+		t.Fatalf("LoadAuthorsFile failed: %v", err)
+	}
+	authors := authorsOf(t, entries)
+	if len(authors) != 2 || authors["jsmith"] != "John Smith <john@example.com>" || authors["mjones"] != "Mary Jones <mary@example.com>" {
+		t.Errorf("authors = %v", authors)
+	}
+
+	_, err = LoadAuthorsFile(writeAuthorsFile(t, "authors.txt", "jsmith John Smith\n"))
+	if err == nil || !strings.Contains(err.Error(), "authors.txt:1") {
+		t.Errorf("expected error with line number, got %v", err)
+	}
+}
+
+func TestLoadAuthorsFileCSV(t *testing.T) {
+	// Header columns in any order among others
+	path := writeAuthorsFile(t, "hr.csv", `Employee ID,E-Mail,Full Name,Login
+1001,john@example.com,"Smith, John",jsmith
+1002,mary@example.com,Mary Jones,mjones
+1003,left@example.com,Left Company,
+`)
+	entries, err := LoadAuthorsFile(path)
+	if err != nil {
+		t.Fatalf("LoadAuthorsFile failed: %v", err)
+	}
+	authors := authorsOf(t, entries)
+	if len(authors) != 2 || authors["jsmith"] != "Smith, John <john@example.com>" {
+		t.Errorf("authors = %v", authors)
+	}
+	if entries[1].Source != path+":3" {
+		t.Errorf("Source = %q, want %q", entries[1].Source, path+":3")
+	}
+
+	// Without a header the columns are username, name and email
+	entries, err = LoadAuthorsFile(writeAuthorsFile(t, "plain.csv", "jsmith, John Smith, john@example.com\n"))
+	if err != nil {
+		t.Fatalf("LoadAuthorsFile failed: %v", err)
+	}
+	if authors := authorsOf(t, entries); authors["jsmith"] != "John Smith <john@example.com>" {
+		t.Errorf("authors = %v", authors)
+	}
+
+	_, err = LoadAuthorsFile(writeAuthorsFile(t, "bad.csv", "jsmith,John Smith,\n"))
+	if err == nil || !strings.Contains(err.Error(), "bad.csv:1") {
+		t.Errorf("expected error for missing email, got %v", err)
+	}
+
+	// A header with unknown column names is not read as an entry
+	_, err = LoadAuthorsFile(writeAuthorsFile(t, "unknown.csv", "User ID,Display,Mail Address\njsmith,John Smith,john@example.com\n"))
+	if err == nil || !strings.Contains(err.Error(), "unknown.csv:1") || !strings.Contains(err.Error(), "no username column") {
+		t.Errorf("expected error for unknown header, got %v", err)
+	}
+	_, err = LoadAuthorsFile(writeAuthorsFile(t, "partial.csv", "Login,Full Name,Address\njsmith,John Smith,john@example.com\n"))
+	if err == nil || !strings.Contains(err.Error(), "no email column") {
+		t.Errorf("expected error for partial header, got %v", err)
+	}
+}
+
+func TestLoadAuthorsFileMailmap(t *testing.T) {
+	path := writeAuthorsFile(t, ".mailmap", `# Canonical identities
+John Smith <john@example.com> <jsmith@users.noreply.cvs.example.org>
+Mary Jones <mary@example.com> mjones <mary@old.example.com>
+<tom@example.com> <twilliams@cvs.example.com>
+Ann Lee <alee@example.com>
+`)
+	entries, err := LoadAuthorsFile(path)
+	if err != nil {
+		t.Fatalf("LoadAuthorsFile failed: %v", err)
+	}
+	authors := authorsOf(t, entries)
+	expected := map[string]string{
+		"jsmith":    "John Smith <john@example.com>",
+		"mjones":    "Mary Jones <mary@example.com>",
+		"twilliams": "twilliams <tom@example.com>",
+		"alee":      "Ann Lee <alee@example.com>",
+	}
+	for username, author := range expected {
+		if authors[username] != author {
+			t.Errorf("authors[%q] = %q, want %q", username, authors[username], author)
+		}
+	}
+	if len(authors) != len(expected) {
+		t.Errorf("authors = %v", authors)
+	}
+}
+
+func TestMergeAuthors(t *testing.T) {
+	authors, problems := MergeAuthors([]AuthorEntry{
+		{Username: "jsmith", Author: "John Smith <old@example.com>", Source: "a.txt:1"},
+		{Username: "mjones", Author: "Mary Jones <mary@example.com>", Source: "a.txt:2"},
+		{Username: "jsmith", Author: "John Smith <john@example.com>", Source: "b.csv:2"},
+		{Username: "mjones", Author: "Mary Jones <mary@example.com>", Source: "b.csv:3"},
+	})
+
+	if authors["jsmith"] != "John Smith <john@example.com>" || authors["mjones"] != "Mary Jones <mary@example.com>" {
+		t.Errorf("authors = %v", authors)
+	}
+	expected := []string{
+		"b.csv:2: jsmith maps to John Smith <john@example.com>, overriding John Smith <old@example.com> from a.txt:1",
+		"b.csv:3: duplicate entry for mjones, also at a.txt:2",
+	}
+	if strings.Join(problems, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("problems = %q, want %q", problems, expected)
+	}
+}
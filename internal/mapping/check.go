@@ -0,0 +1,103 @@
+package mapping
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AuthorReport compares an author mapping with the users of a repository
+type AuthorReport struct {
+	Authors   map[string]string // Merged mapping, later entries taking precedence
+	Resolved  map[string]string // Author of the latest commit of each mapped user
+	Users     []AuthorStats     // Users of the repository
+	Malformed []AuthorEntry     // Entries whose author is not "Name <email>"
+	Repeated  []string          // Usernames listed more than once, as reported by MergeAuthors
+	Unmapped  []string          // Users with commits no mapping, rule or history maps
+	Unused    []AuthorEntry     // Effective entries of usernames without commits
+}
+
+// CheckAuthors merges author entries and compares them with the users of
+// a repository. Users are resolved as a migration resolves them, with the
+// history, rules and default identity of the options applied to the
+// merged entries.
+func CheckAuthors(entries []AuthorEntry, users []AuthorStats, options AuthorMapOptions) (*AuthorReport, error) {
+	report := &AuthorReport{Users: users, Resolved: make(map[string]string)}
+	report.Authors, report.Repeated = MergeAuthors(entries)
+	am, err := NewAuthorMapWithOptions(report.Authors, options)
+	if err != nil {
+		return nil, err
+	}
+
+	effective := make(map[string]AuthorEntry)
+	for _, entry := range entries {
+		if _, _, err := ParseAuthor(entry.Author); err != nil {
+			report.Malformed = append(report.Malformed, entry)
+		}
+		effective[entry.Username] = entry
+	}
+
+	committers := make(map[string]bool)
+	for _, user := range users {
+		committers[user.Username] = true
+		// History may map a user for only part of their commits
+		_, _, first := am.LookupAt(user.Username, user.First)
+		name, email, last := am.LookupAt(user.Username, user.Last)
+		if last {
+			report.Resolved[user.Username] = fmt.Sprintf("%s <%s>", name, email)
+		}
+		if !first || !last {
+			report.Unmapped = append(report.Unmapped, user.Username)
+		}
+	}
+	sort.Strings(report.Unmapped)
+
+	for username, entry := range effective {
+		if !committers[username] {
+			report.Unused = append(report.Unused, entry)
+		}
+	}
+	sort.Slice(report.Unused, func(i, j int) bool { return report.Unused[i].Username < report.Unused[j].Username })
+	return report, nil
+}
+
+// Updated returns the merged mapping with a placeholder for every
+// unmapped user that has no entry. Malformed entries are kept so that they
+// are fixed rather than replaced.
+func (r *AuthorReport) Updated() map[string]string {
+	updated := make(map[string]string, len(r.Authors))
+	for username, author := range r.Authors {
+		updated[username] = author
+	}
+	for _, username := range r.Unmapped {
+		if _, ok := updated[username]; !ok {
+			updated[username] = fmt.Sprintf("%s <%s@example.com>", username, username)
+		}
+	}
+	return updated
+}
+
+// FormatAuthors renders a mapping as an author file of the given format.
+// Only the YAML and authors.txt formats can be written.
+func FormatAuthors(authors map[string]string, format string) ([]byte, error) {
+	switch format {
+	case AuthorsYAML:
+		return yaml.Marshal(authors)
+	case AuthorsText:
+		usernames := make([]string, 0, len(authors))
+		for username := range authors {
+			usernames = append(usernames, username)
+		}
+		sort.Strings(usernames)
+
+		var b bytes.Buffer
+		for _, username := range usernames {
+			fmt.Fprintf(&b, "%s = %s\n", username, authors[username])
+		}
+		return b.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("cannot write %s author files (supported: %s, %s)", format, AuthorsYAML, AuthorsText)
+	}
+}
@@ -0,0 +1,165 @@
+package mapping
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckAuthors(t *testing.T) {
+	report, err := CheckAuthors([]AuthorEntry{
+		{Username: "jsmith", Author: "John Smith <john@example.com>", Source: "a.txt:1"},
+		{Username: "mjones", Author: "Mary Jones", Source: "a.txt:2"},
+		{Username: "olduser", Author: "Old User <old@example.com>", Source: "a.txt:3"},
+		{Username: "olduser", Author: "Old User <old@example.org>", Source: "b.yaml:1"},
+	}, []AuthorStats{{Username: "jsmith"}, {Username: "mjones"}, {Username: "tom"}}, AuthorMapOptions{})
+	if err != nil {
+		t.Fatalf("CheckAuthors failed: %v", err)
+	}
+
+	if len(report.Malformed) != 1 || report.Malformed[0].Source != "a.txt:2" {
+		t.Errorf("Malformed = %v", report.Malformed)
+	}
+	if strings.Join(report.Unmapped, ",") != "mjones,tom" {
+		t.Errorf("Unmapped = %v, want [mjones tom]", report.Unmapped)
+	}
+	if len(report.Unused) != 1 || report.Unused[0].Source != "b.yaml:1" {
+		t.Errorf("Unused = %v", report.Unused)
+	}
+	if len(report.Repeated) != 1 || !strings.HasPrefix(report.Repeated[0], "b.yaml:1: olduser maps to") {
+		t.Errorf("Repeated = %v", report.Repeated)
+	}
+
+	updated := report.Updated()
+	expected := map[string]string{
+		"jsmith":  "John Smith <john@example.com>",
+		"mjones":  "Mary Jones",
+		"olduser": "Old User <old@example.org>",
+		"tom":     "tom <tom@example.com>",
+	}
+	for username, author := range expected {
+		if updated[username] != author {
+			t.Errorf("Updated()[%q] = %q, want %q", username, updated[username], author)
+		}
+	}
+	if len(updated) != len(expected) {
+		t.Errorf("Updated() = %v", updated)
+	}
+}
+
+func TestCheckAuthorsOptions(t *testing.T) {
+	switchover := time.Date(2009, 1, 1, 0, 0, 0, 0, time.UTC)
+	report, err := CheckAuthors([]AuthorEntry{
+		{Username: "jsmith", Author: "John Smith <john@example.com>", Source: "a.txt:1"},
+	}, []AuthorStats{
+		{Username: "jsmith", First: switchover, Last: switchover},
+		{Username: "svc-build", First: switchover, Last: switchover},
+		{Username: "mjones", First: switchover.AddDate(-1, 0, 0), Last: switchover.AddDate(1, 0, 0)},
+		{Username: "tom", First: switchover, Last: switchover},
+	}, AuthorMapOptions{
+		History: AuthorHistory{"mjones": {{Author: "Mary Jones <mary@oldcorp.com>", Until: switchover}}},
+		Rules:   []AuthorRule{{Pattern: `^svc-(.*)$`, Name: "$1 bot", Email: "$1@bots.example.com"}},
+	})
+	if err != nil {
+		t.Fatalf("CheckAuthors failed: %v", err)
+	}
+
+	// mjones is only mapped until the switchover
+	if strings.Join(report.Unmapped, ",") != "mjones,tom" {
+		t.Errorf("Unmapped = %v, want [mjones tom]", report.Unmapped)
+	}
+	expected := map[string]string{
+		"jsmith":    "John Smith <john@example.com>",
+		"svc-build": "build bot <build@bots.example.com>",
+	}
+	if len(report.Resolved) != len(expected) {
+		t.Errorf("Resolved = %v, want %v", report.Resolved, expected)
+	}
+	for username, author := range expected {
+		if report.Resolved[username] != author {
+			t.Errorf("Resolved[%q] = %q, want %q", username, report.Resolved[username], author)
+		}
+	}
+	if updated := report.Updated(); len(updated) != 3 || updated["svc-build"] != "" {
+		t.Errorf("Updated() = %v, want placeholders for mjones and tom only", updated)
+	}
+
+	// The default identity maps everyone else
+	report, err = CheckAuthors(nil, []AuthorStats{{Username: "tom"}}, AuthorMapOptions{
+		Default: &AuthorRule{Name: "$1", Email: "$1@example.org"},
+	})
+	if err != nil {
+		t.Fatalf("CheckAuthors failed: %v", err)
+	}
+	if len(report.Unmapped) != 0 || report.Resolved["tom"] != "tom <tom@example.org>" {
+		t.Errorf("Unmapped = %v, Resolved = %v", report.Unmapped, report.Resolved)
+	}
+
+	if _, err := CheckAuthors(nil, nil, AuthorMapOptions{Rules: []AuthorRule{{Pattern: "("}}}); err == nil {
+		t.Error("expected error for invalid rule")
+	}
+}
+
+func TestFormatAuthors(t *testing.T) {
+	authors := map[string]string{
+		"mjones": "Mary Jones <mary@example.com>",
+		"jsmith": "John Smith <john@example.com>",
+	}
+
+	text, err := FormatAuthors(authors, AuthorsText)
+	if err != nil {
+		t.Fatalf("FormatAuthors failed: %v", err)
+	}
+	expected := "jsmith = John Smith <john@example.com>\nmjones = Mary Jones <mary@example.com>\n"
+	if string(text) != expected {
+		t.Errorf("FormatAuthors(text) = %q, want %q", text, expected)
+	}
+
+	// Written files read back to the same mapping
+	for _, name := range []string{"authors.yaml", "authors.txt"} {
+		content, err := FormatAuthors(authors, AuthorsFileFormat(name))
+		if err != nil {
+			t.Fatalf("FormatAuthors failed: %v", err)
+		}
+		entries, err := LoadAuthorsFile(writeAuthorsFile(t, name, string(content)))
+		if err != nil {
+			t.Fatalf("LoadAuthorsFile failed: %v", err)
+		}
+		read := authorsOf(t, entries)
+		if len(read) != 2 || read["jsmith"] != authors["jsmith"] || read["mjones"] != authors["mjones"] {
+			t.Errorf("%s read back as %v", name, read)
+		}
+	}
+
+	if _, err := FormatAuthors(authors, AuthorsMailmap); err == nil {
+		t.Error("expected error for mailmap format")
+	}
+}
+
+func TestReadAuthorsFileMalformed(t *testing.T) {
+	path := writeAuthorsFile(t, "authors.txt", "jsmith = John Smith\n")
+	if _, err := LoadAuthorsFile(path); err == nil {
+		t.Error("LoadAuthorsFile should reject malformed authors")
+	}
+	entries, err := ReadAuthorsFile(path)
+	if err != nil {
+		t.Fatalf("ReadAuthorsFile failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Author != "John Smith" {
+		t.Errorf("entries = %v", entries)
+	}
+}
+
+func TestReadAuthorsFileMissingSeparator(t *testing.T) {
+	path := writeAuthorsFile(t, "authors.txt", "jsmith John Smith\n")
+	if _, err := LoadAuthorsFile(path); err == nil {
+		t.Error("LoadAuthorsFile should reject a line without \"=\"")
+	}
+	entries, err := ReadAuthorsFile(path)
+	if err != nil {
+		t.Fatalf("ReadAuthorsFile failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Username != "jsmith" || entries[0].Author != "John Smith" {
+		t.Errorf("entries = %v", entries)
+	}
+}
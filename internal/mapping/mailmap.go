@@ -0,0 +1,151 @@
+package mapping
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+)
+
+// Mailmap collects the Git identities the commits of each source user are
+// written with and renders them as a .mailmap that maps every identity of
+// a user to the identity of their latest commit. Users whose identities
+// share an email are taken to be the same person.
+type Mailmap struct {
+	users map[string]*mailmapUser
+}
+
+type mailmapUser struct {
+	latest     time.Time
+	canonical  identity
+	identities map[identity]bool
+}
+
+type identity struct {
+	name, email string
+}
+
+// NewMailmap creates an empty mailmap
+func NewMailmap() *Mailmap {
+	return &Mailmap{users: make(map[string]*mailmapUser)}
+}
+
+// Add records the identity a commit of a user is written with
+func (mm *Mailmap) Add(username, name, email string, when time.Time) {
+	user, ok := mm.users[username]
+	if !ok {
+		user = &mailmapUser{identities: make(map[identity]bool)}
+		mm.users[username] = user
+	}
+
+	id := identity{name: name, email: email}
+	user.identities[id] = true
+	if len(user.identities) == 1 || !when.Before(user.latest) {
+		user.latest = when
+		user.canonical = id
+	}
+}
+
+// AddCommits records the identities an author map gives to the authors of
+// source commits
+func (mm *Mailmap) AddCommits(am *AuthorMap, commits []*vcs.Commit) {
+	for _, commit := range commits {
+		name, email := am.GetAt(commit.Author, commit.Date)
+		mm.Add(commit.Author, name, email, commit.Date)
+	}
+}
+
+// people merges the users whose identities share an email, ordered by
+// their first username
+func (mm *Mailmap) people() []*mailmapUser {
+	usernames := make([]string, 0, len(mm.users))
+	for username := range mm.users {
+		usernames = append(usernames, username)
+	}
+	sort.Strings(usernames)
+
+	// Union the users of every email into the first user that has it
+	parent := make(map[string]string)
+	var find func(string) string
+	find = func(username string) string {
+		if p, ok := parent[username]; ok && p != username {
+			root := find(p)
+			parent[username] = root
+			return root
+		}
+		return username
+	}
+	owners := make(map[string]string)
+	for _, username := range usernames {
+		for id := range mm.users[username].identities {
+			owner, ok := owners[id.email]
+			if !ok {
+				owners[id.email] = username
+				continue
+			}
+			if a, b := find(owner), find(username); a != b {
+				if b < a {
+					a, b = b, a
+				}
+				parent[b] = a
+			}
+		}
+	}
+
+	var people []*mailmapUser
+	merged := make(map[string]*mailmapUser)
+	for _, username := range usernames {
+		user := mm.users[username]
+		root := find(username)
+		person, ok := merged[root]
+		if !ok {
+			person = &mailmapUser{latest: user.latest, canonical: user.canonical, identities: make(map[identity]bool)}
+			merged[root] = person
+			people = append(people, person)
+		} else if !user.latest.Before(person.latest) {
+			person.latest = user.latest
+			person.canonical = user.canonical
+		}
+		for id := range user.identities {
+			person.identities[id] = true
+		}
+	}
+	return people
+}
+
+// Len returns the number of entries of the mailmap
+func (mm *Mailmap) Len() int {
+	n := 0
+	for _, person := range mm.people() {
+		n += len(person.identities) - 1
+	}
+	return n
+}
+
+// Bytes renders the mailmap, one line for every identity of a person other
+// than the canonical one
+func (mm *Mailmap) Bytes() []byte {
+	var b bytes.Buffer
+	b.WriteString("# Generated by git-migrator: maps every identity of a source user to\n")
+	b.WriteString("# the identity of their latest commit\n")
+	for _, person := range mm.people() {
+		var others []identity
+		for id := range person.identities {
+			if id != person.canonical {
+				others = append(others, id)
+			}
+		}
+		sort.Slice(others, func(i, j int) bool {
+			if others[i].email != others[j].email {
+				return others[i].email < others[j].email
+			}
+			return others[i].name < others[j].name
+		})
+		for _, id := range others {
+			fmt.Fprintf(&b, "%s <%s> %s <%s>\n", person.canonical.name, person.canonical.email, id.name, id.email)
+		}
+	}
+	return b.Bytes()
+}
@@ -0,0 +1,73 @@
+package mapping
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+)
+
+func TestMailmap(t *testing.T) {
+	switchover := time.Date(2009, 1, 1, 0, 0, 0, 0, time.UTC)
+	am, err := NewAuthorMapWithOptions(map[string]string{
+		"jsmith": "John Smith <john.smith@newcorp.com>",
+		"mjones": "Mary Jones <mary@example.com>",
+	}, AuthorMapOptions{
+		History: AuthorHistory{
+			"jsmith": {
+				{Author: "John Smith <jsmith@oldcorp.com>", Until: switchover},
+				{Author: "Johnny Smith <jsmith@oldcorp.com>", From: switchover, Until: switchover.AddDate(1, 0, 0)},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewAuthorMapWithOptions failed: %v", err)
+	}
+
+	mailmap := NewMailmap()
+	mailmap.AddCommits(am, []*vcs.Commit{
+		{Author: "jsmith", Date: switchover.AddDate(2, 0, 0)},
+		{Author: "jsmith", Date: switchover.AddDate(-1, 0, 0)},
+		{Author: "mjones", Date: switchover},
+		{Author: "jsmith", Date: switchover.AddDate(0, 6, 0)},
+		{Author: "jsmith", Date: switchover.AddDate(-2, 0, 0)},
+	})
+
+	expected := "# Generated by git-migrator: maps every identity of a source user to\n" +
+		"# the identity of their latest commit\n" +
+		"John Smith <john.smith@newcorp.com> John Smith <jsmith@oldcorp.com>\n" +
+		"John Smith <john.smith@newcorp.com> Johnny Smith <jsmith@oldcorp.com>\n"
+	if got := string(mailmap.Bytes()); got != expected {
+		t.Errorf("Bytes() = %q, want %q", got, expected)
+	}
+	if mailmap.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", mailmap.Len())
+	}
+}
+
+func TestMailmapSharedEmail(t *testing.T) {
+	base := time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)
+	mailmap := NewMailmap()
+	mailmap.Add("jsmith", "jsmith", "john@example.com", base)
+	mailmap.Add("john", "John Smith", "john@example.com", base.Add(time.Hour))
+	mailmap.Add("john", "John Smith", "john.smith@example.com", base.Add(2*time.Hour))
+	mailmap.Add("mjones", "Mary Jones", "mary@example.com", base)
+
+	// All identities of jsmith and john map to the latest one
+	expected := "# Generated by git-migrator: maps every identity of a source user to\n" +
+		"# the identity of their latest commit\n" +
+		"John Smith <john.smith@example.com> John Smith <john@example.com>\n" +
+		"John Smith <john.smith@example.com> jsmith <john@example.com>\n"
+	if got := string(mailmap.Bytes()); got != expected {
+		t.Errorf("Bytes() = %q, want %q", got, expected)
+	}
+	if mailmap.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", mailmap.Len())
+	}
+
+	empty := NewMailmap()
+	empty.Add("mjones", "Mary Jones", "mary@example.com", base)
+	if empty.Len() != 0 {
+		t.Errorf("Len() = %d, want 0", empty.Len())
+	}
+}
@@ -0,0 +1,138 @@
+package mapping
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// RefRule renames source names matching a regular expression. Replace may
+// refer to capture groups as $1 or ${name}.
+type RefRule struct {
+	Pattern string `yaml:"pattern" json:"pattern"`
+	Replace string `yaml:"replace" json:"replace"`
+}
+
+// RefMap maps source branch or tag names to Git branch or tag names.
+// Exact mappings take precedence over rules, which are tried in order; the
+// first matching rule wins. Names matching a skip pattern are not migrated.
+type RefMap struct {
+	prefix string // Ref namespace, such as "refs/heads/"
+	names  map[string]string
+	rules  []compiledRule
+	skip   []string
+}
+
+type compiledRule struct {
+	re      *regexp.Regexp
+	replace string
+}
+
+// NewBranchMap creates a map of source branch names to Git branch names
+func NewBranchMap(names map[string]string, rules []RefRule, skip []string) (*RefMap, error) {
+	return newRefMap("refs/heads/", names, rules, skip)
+}
+
+// NewTagMap creates a map of source tag names to Git tag names
+func NewTagMap(names map[string]string, rules []RefRule, skip []string) (*RefMap, error) {
+	return newRefMap("refs/tags/", names, rules, skip)
+}
+
+func newRefMap(prefix string, names map[string]string, rules []RefRule, skip []string) (*RefMap, error) {
+	m := &RefMap{prefix: prefix, names: names, skip: skip}
+	for _, rule := range rules {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", rule.Pattern, err)
+		}
+		m.rules = append(m.rules, compiledRule{re: re, replace: rule.Replace})
+	}
+	for _, pattern := range skip {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid skip pattern %q: %w", pattern, err)
+		}
+	}
+	return m, nil
+}
+
+// Get returns the Git name for a source name, or false if it is skipped
+func (m *RefMap) Get(name string) (string, bool) {
+	if m == nil {
+		return name, true
+	}
+	if m.Skipped(name) {
+		return "", false
+	}
+	if mapped, ok := m.names[name]; ok {
+		return mapped, true
+	}
+	for _, rule := range m.rules {
+		if rule.re.MatchString(name) {
+			return rule.re.ReplaceAllString(name, rule.replace), true
+		}
+	}
+	return name, true
+}
+
+// Skipped reports whether a source name matches a skip pattern
+func (m *RefMap) Skipped(name string) bool {
+	if m == nil {
+		return false
+	}
+	for _, pattern := range m.skip {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// MapNames maps every source name that is not skipped. It reports Git
+// names that are not valid ref names and Git names that more than one
+// source name maps to.
+func (m *RefMap) MapNames(names []string) (map[string]string, error) {
+	mapped := make(map[string]string)
+	sources := make(map[string][]string) // Git name -> source names
+	for _, name := range names {
+		gitName, ok := m.Get(name)
+		if !ok {
+			continue
+		}
+		mapped[name] = gitName
+		sources[gitName] = append(sources[gitName], name)
+	}
+
+	gitNames := make([]string, 0, len(sources))
+	for gitName := range sources {
+		gitNames = append(gitNames, gitName)
+	}
+	sort.Strings(gitNames)
+
+	var problems []string
+	for _, gitName := range gitNames {
+		if err := ValidateRefName(m.prefix, gitName); err != nil {
+			problems = append(problems, err.Error())
+		}
+		if names := sources[gitName]; len(names) > 1 {
+			sort.Strings(names)
+			problems = append(problems, fmt.Sprintf("%s all map to %s", strings.Join(names, ", "), gitName))
+		}
+	}
+	if len(problems) > 0 {
+		return mapped, fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return mapped, nil
+}
+
+// ValidateRefName checks that a branch or tag name is a legal Git ref name
+// within the given namespace
+func ValidateRefName(prefix, name string) error {
+	if err := plumbing.ReferenceName(prefix + name).Validate(); err != nil {
+		return fmt.Errorf("%q is not a valid Git ref name", name)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package mapping
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRefMapGet(t *testing.T) {
+	m, err := NewBranchMap(
+		map[string]string{"DEV": "develop"},
+		[]RefRule{
+			{Pattern: `^RELEASE_(\d+)_(\d+)$`, Replace: "release/$1.$2"},
+			{Pattern: `^(?P<name>.*)_BRANCH$`, Replace: "${name}"},
+			{Pattern: `^DEV.*`, Replace: "never"},
+		},
+		[]string{"temp-*", "*_OLD"},
+	)
+	if err != nil {
+		t.Fatalf("NewBranchMap failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		expected string
+		ok       bool
+	}{
+		{"DEV", "develop", true},
+		{"RELEASE_1_2", "release/1.2", true},
+		{"FEATURE_BRANCH", "FEATURE", true},
+		{"DEVEL", "never", true},
+		{"other", "other", true},
+		{"temp-fix", "", false},
+		{"RELEASE_1_0_OLD", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := m.Get(tt.name)
+		if got != tt.expected || ok != tt.ok {
+			t.Errorf("Get(%q) = %q, %v, want %q, %v", tt.name, got, ok, tt.expected, tt.ok)
+		}
+	}
+}
+
+func TestRefMapNil(t *testing.T) {
+	var m *RefMap
+	if got, ok := m.Get("DEV"); got != "DEV" || !ok {
+		t.Errorf("Get(DEV) = %q, %v, want DEV, true", got, ok)
+	}
+	if m.Skipped("DEV") {
+		t.Error("nil map should not skip names")
+	}
+}
+
+func TestNewRefMapInvalid(t *testing.T) {
+	if _, err := NewTagMap(nil, []RefRule{{Pattern: "("}}, nil); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+	if _, err := NewTagMap(nil, nil, []string{"["}); err == nil {
+		t.Error("expected error for invalid skip pattern")
+	}
+}
+
+func TestRefMapMapNames(t *testing.T) {
+	m, err := NewTagMap(nil, []RefRule{{Pattern: `^REL_(\d+)_(\d+)$`, Replace: "v$1.$2"}}, []string{"SKIP*"})
+	if err != nil {
+		t.Fatalf("NewTagMap failed: %v", err)
+	}
+
+	mapped, err := m.MapNames([]string{"REL_1_0", "SKIP_ME", "v1.1"})
+	if err != nil {
+		t.Fatalf("MapNames failed: %v", err)
+	}
+	if len(mapped) != 2 || mapped["REL_1_0"] != "v1.0" || mapped["v1.1"] != "v1.1" {
+		t.Errorf("MapNames = %v", mapped)
+	}
+
+	// Two names mapping to one tag and a name Git does not accept
+	_, err = m.MapNames([]string{"REL_1_1", "v1.1", "bad..name"})
+	if err == nil {
+		t.Fatal("expected error for collision and invalid name")
+	}
+	for _, want := range []string{"REL_1_1, v1.1 all map to v1.1", `"bad..name" is not a valid Git ref name`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain %q", err, want)
+		}
+	}
+}
+
+func TestValidateRefName(t *testing.T) {
+	valid := []string{"master", "release/1.0", "feature-x", "v1.0"}
+	for _, name := range valid {
+		if err := ValidateRefName("refs/heads/", name); err != nil {
+			t.Errorf("ValidateRefName(%q) failed: %v", name, err)
+		}
+	}
+
+	invalid := []string{"", "a..b", "with space", "end.lock", "trailing/", "a:b", "-dash/../x"}
+	for _, name := range invalid {
+		if err := ValidateRefName("refs/heads/", name); err == nil {
+			t.Errorf("ValidateRefName(%q) should fail", name)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"fmt"
+	"log"
+)
+
+// Mark records the Git commit produced for a source revision
+type Mark struct {
+	Revision string // Source commit identifier or file revision (e.g. 1.42)
+	Path     string // File path for file revisions, empty for commits
+	Commit   string // Git commit hash, or a mark such as :12 for fast-import output
+}
+
+// SaveMarks stores marks for a migration, replacing existing marks for the
+// same revisions
+func (sdb *StateDB) SaveMarks(migrationID string, marks []Mark) error {
+	tx, err := sdb.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	query := `
+	INSERT OR REPLACE INTO marks (migration_id, revision, path, git_commit)
+	VALUES (?, ?, ?, ?)
+	`
+	for _, mark := range marks {
+		if _, err := tx.Exec(query, migrationID, mark.Revision, mark.Path, mark.Commit); err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				log.Printf("Warning: failed to roll back marks: %v", rbErr)
+			}
+			return fmt.Errorf("failed to save mark %s: %w", mark.Revision, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// LookupMark returns the Git commit produced for a source revision. Pass an
+// empty path for commit identifiers. It returns sql.ErrNoRows if the
+// revision has not been migrated.
+func (sdb *StateDB) LookupMark(migrationID, path, revision string) (string, error) {
+	query := `
+	SELECT git_commit FROM marks
+	WHERE migration_id = ? AND path = ? AND revision = ?
+	`
+
+	var commit string
+	err := sdb.db.QueryRow(query, migrationID, path, revision).Scan(&commit)
+	if err != nil {
+		return "", err
+	}
+	return commit, nil
+}
+
+// Marks returns all marks of a migration in the order they were recorded
+func (sdb *StateDB) Marks(migrationID string) ([]Mark, error) {
+	query := `
+	SELECT revision, path, git_commit FROM marks
+	WHERE migration_id = ?
+	ORDER BY rowid
+	`
+
+	rows, err := sdb.db.Query(query, migrationID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Printf("Warning: failed to close rows: %v", err)
+		}
+	}()
+
+	var marks []Mark
+	for rows.Next() {
+		var mark Mark
+		if err := rows.Scan(&mark.Revision, &mark.Path, &mark.Commit); err != nil {
+			return nil, err
+		}
+		marks = append(marks, mark)
+	}
+
+	return marks, rows.Err()
+}
+
+// DeleteMarks deletes all marks of a migration
+func (sdb *StateDB) DeleteMarks(migrationID string) error {
+	_, err := sdb.db.Exec("DELETE FROM marks WHERE migration_id = ?", migrationID)
+	return err
+}
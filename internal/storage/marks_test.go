@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateDB_Marks(t *testing.T) {
+	sdb, err := NewStateDB(filepath.Join(t.TempDir(), "state.db"))
+	require.NoError(t, err)
+	defer sdb.Close()
+
+	require.NoError(t, sdb.SaveMarks("m1", []Mark{
+		{Revision: "cs1", Commit: "aaaa"},
+		{Revision: "1.1", Path: "foo.c", Commit: "aaaa"},
+		{Revision: "1.1", Path: "bar.c", Commit: "aaaa"},
+	}))
+	require.NoError(t, sdb.SaveMarks("m1", []Mark{
+		{Revision: "cs2", Commit: "bbbb"},
+		{Revision: "1.2", Path: "foo.c", Commit: "bbbb"},
+	}))
+	require.NoError(t, sdb.SaveMarks("m2", []Mark{{Revision: "cs1", Commit: "cccc"}}))
+
+	commit, err := sdb.LookupMark("m1", "foo.c", "1.2")
+	require.NoError(t, err)
+	require.Equal(t, "bbbb", commit)
+
+	commit, err = sdb.LookupMark("m1", "", "cs1")
+	require.NoError(t, err)
+	require.Equal(t, "aaaa", commit)
+
+	_, err = sdb.LookupMark("m1", "foo.c", "1.3")
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	marks, err := sdb.Marks("m1")
+	require.NoError(t, err)
+	require.Len(t, marks, 5)
+	require.Equal(t, Mark{Revision: "cs1", Commit: "aaaa"}, marks[0])
+	require.Equal(t, Mark{Revision: "1.2", Path: "foo.c", Commit: "bbbb"}, marks[4])
+
+	// Saving a revision again replaces its commit
+	require.NoError(t, sdb.SaveMarks("m1", []Mark{{Revision: "cs1", Commit: "dddd"}}))
+	commit, err = sdb.LookupMark("m1", "", "cs1")
+	require.NoError(t, err)
+	require.Equal(t, "dddd", commit)
+
+	// Deleting a migration removes its marks only
+	require.NoError(t, sdb.Delete("m1"))
+	marks, err = sdb.Marks("m1")
+	require.NoError(t, err)
+	require.Empty(t, marks)
+	marks, err = sdb.Marks("m2")
+	require.NoError(t, err)
+	require.Len(t, marks, 1)
+}
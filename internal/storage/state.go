@@ -84,6 +84,14 @@ func NewStateDB(path string) (*StateDB, error) {
 		)`,
 		`CREATE INDEX IF NOT EXISTS idx_status ON migration_state(status)`,
 		`CREATE INDEX IF NOT EXISTS idx_last_updated ON migration_state(last_updated)`,
+		`CREATE TABLE IF NOT EXISTS marks (
+			migration_id TEXT NOT NULL,
+			revision TEXT NOT NULL,
+			path TEXT NOT NULL DEFAULT '',
+			git_commit TEXT NOT NULL,
+			PRIMARY KEY (migration_id, path, revision)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_marks_commit ON marks(migration_id, git_commit)`,
 	}
 
 	for _, stmt := range schemaStatements {
@@ -172,10 +180,12 @@ func (sdb *StateDB) Complete(migrationID string) error {
 	return err
 }
 
-// Delete deletes migration state
+// Delete deletes migration state and its marks
 func (sdb *StateDB) Delete(migrationID string) error {
-	_, err := sdb.db.Exec("DELETE FROM migration_state WHERE migration_id = ?", migrationID)
-	return err
+	if _, err := sdb.db.Exec("DELETE FROM migration_state WHERE migration_id = ?", migrationID); err != nil {
+		return err
+	}
+	return sdb.DeleteMarks(migrationID)
 }
 
 // History returns migration history
@@ -0,0 +1,242 @@
+package cvs
+
+import (
+	"container/heap"
+	"crypto/sha1"
+	"encoding/hex"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+)
+
+// DefaultFuzzWindow is the maximum time between two file revisions of the
+// same author and log message for them to be considered one CVS commit
+const DefaultFuzzWindow = 5 * time.Minute
+
+// fileRevision is a single revision of a single RCS file
+type fileRevision struct {
+	path        string
+	revision    string
+	predecessor string // Revision this one was derived from (empty for the first)
+	author      string
+	date        time.Time
+	message     string
+	branch      string
+	commitID    string
+	action      vcs.Action
+	content     []byte
+	hasContent  bool
+	vendorCopy  bool // Copy of a trunk revision on the vendor branch
+}
+
+// changeset is a group of file revisions that were committed together
+type changeset struct {
+	id        string
+	author    string
+	message   string
+	branch    string
+	commitID  string
+	date      time.Time // Latest date of any member revision
+	revisions []*fileRevision
+	paths     map[string]bool
+}
+
+func (cs *changeset) add(rev *fileRevision) {
+	cs.revisions = append(cs.revisions, rev)
+	cs.paths[rev.path] = true
+	if rev.date.After(cs.date) {
+		cs.date = rev.date
+	}
+}
+
+// groupChangesets reconstructs CVS commits from individual file revisions.
+//
+// Revisions carrying a commitid are grouped by that identifier. Otherwise
+// revisions with the same author, log message and branch belong together as
+// long as each one follows the previous member within the fuzz window. A
+// changeset is split whenever the same file would appear in it twice.
+func groupChangesets(revs []*fileRevision, fuzz time.Duration) []*changeset {
+	sorted := make([]*fileRevision, len(revs))
+	copy(sorted, revs)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if !sorted[i].date.Equal(sorted[j].date) {
+			return sorted[i].date.Before(sorted[j].date)
+		}
+		if sorted[i].path != sorted[j].path {
+			return sorted[i].path < sorted[j].path
+		}
+		return sorted[i].revision < sorted[j].revision
+	})
+
+	var changesets []*changeset
+	open := make(map[string]*changeset)
+
+	for _, rev := range sorted {
+		key := "meta|" + rev.author + "|" + rev.branch + "|" + rev.message
+		if rev.commitID != "" {
+			key = "id|" + rev.commitID
+		}
+
+		cs := open[key]
+		if cs != nil && !cs.paths[rev.path] && (rev.commitID != "" || rev.date.Sub(cs.date) <= fuzz) {
+			cs.add(rev)
+			continue
+		}
+
+		cs = &changeset{
+			author:   rev.author,
+			message:  rev.message,
+			branch:   rev.branch,
+			commitID: rev.commitID,
+			paths:    make(map[string]bool),
+		}
+		cs.add(rev)
+		open[key] = cs
+		changesets = append(changesets, cs)
+	}
+
+	for _, cs := range changesets {
+		sort.Slice(cs.revisions, func(i, j int) bool {
+			return cs.revisions[i].path < cs.revisions[j].path
+		})
+		cs.id = changesetID(cs)
+	}
+
+	return changesets
+}
+
+// changesetID derives a stable identifier from the file revisions in a changeset
+func changesetID(cs *changeset) string {
+	h := sha1.New()
+	for _, rev := range cs.revisions {
+		if rev.vendorCopy {
+			h.Write([]byte("vendor:"))
+		}
+		h.Write([]byte(rev.path + ":" + rev.revision + "\n"))
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// orderChangesets sorts changesets chronologically while guaranteeing that
+// every file revision is emitted after the revision it was derived from.
+// Should the dependencies form a cycle, the oldest blocked changeset is
+// emitted first and a warning is logged.
+func orderChangesets(changesets []*changeset) []*changeset {
+	commits := make([]*vcs.Commit, len(changesets))
+	byID := make(map[string]*changeset, len(changesets))
+	for i, cs := range changesets {
+		commits[i] = &vcs.Commit{Revision: cs.id, Date: cs.date}
+		byID[cs.id] = cs
+	}
+	sortCommitsByDate(commits)
+
+	ordered := make([]*changeset, len(commits))
+	for i, c := range commits {
+		ordered[i] = byID[c.Revision]
+	}
+
+	// Map each file revision to the position of the changeset containing it
+	owner := make(map[string]int)
+	for i, cs := range ordered {
+		for _, rev := range cs.revisions {
+			if !rev.vendorCopy {
+				owner[rev.path+":"+rev.revision] = i
+			}
+		}
+	}
+
+	successors := make([][]int, len(ordered))
+	pending := make([]int, len(ordered))
+	for i, cs := range ordered {
+		deps := make(map[int]bool)
+		for _, rev := range cs.revisions {
+			if rev.predecessor == "" {
+				continue
+			}
+			if j, ok := owner[rev.path+":"+rev.predecessor]; ok && j != i && !deps[j] {
+				deps[j] = true
+				successors[j] = append(successors[j], i)
+				pending[i]++
+			}
+		}
+	}
+
+	ready := &intHeap{}
+	for i := range ordered {
+		if pending[i] == 0 {
+			heap.Push(ready, i)
+		}
+	}
+
+	result := make([]*changeset, 0, len(ordered))
+	emitted := make([]bool, len(ordered))
+	next := 0 // oldest changeset that may still be unemitted
+	for len(result) < len(ordered) {
+		if ready.Len() == 0 {
+			for emitted[next] {
+				next++
+			}
+			log.Printf("Warning: dependency cycle between CVS changesets, emitting %s early", ordered[next].id)
+			pending[next] = 0
+			heap.Push(ready, next)
+		}
+
+		i := heap.Pop(ready).(int)
+		if emitted[i] {
+			continue
+		}
+		emitted[i] = true
+		result = append(result, ordered[i])
+		for _, s := range successors[i] {
+			pending[s]--
+			if pending[s] == 0 && !emitted[s] {
+				heap.Push(ready, s)
+			}
+		}
+	}
+
+	return result
+}
+
+// toCommit converts a changeset into a VCS commit
+func (cs *changeset) toCommit() *vcs.Commit {
+	commit := &vcs.Commit{
+		Revision: cs.id,
+		Author:   cs.author,
+		Date:     cs.date,
+		Message:  cs.message,
+		Branch:   cs.branch,
+	}
+	for _, rev := range cs.revisions {
+		if rev.action != vcs.ActionDelete && !rev.hasContent {
+			continue
+		}
+		change := vcs.FileChange{Path: rev.path, Action: rev.action, Revision: rev.revision}
+		if rev.vendorCopy {
+			// The revision is recorded for its commit on the trunk
+			change.Revision = ""
+		}
+		if rev.action != vcs.ActionDelete {
+			change.Content = rev.content
+		}
+		commit.Files = append(commit.Files, change)
+	}
+	return commit
+}
+
+// intHeap is a min-heap of changeset positions
+type intHeap []int
+
+func (h intHeap) Len() int            { return len(h) }
+func (h intHeap) Less(i, j int) bool  { return h[i] < h[j] }
+func (h intHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *intHeap) Push(x interface{}) { *h = append(*h, x.(int)) }
+func (h *intHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
@@ -0,0 +1,171 @@
+package cvs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var changesetBase = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func rev(path, revision, author, message string, offset time.Duration) *fileRevision {
+	return &fileRevision{
+		path:     path,
+		revision: revision,
+		author:   author,
+		message:  message,
+		date:     changesetBase.Add(offset),
+	}
+}
+
+func TestGroupChangesets_MultiFileCommit(t *testing.T) {
+	revs := []*fileRevision{
+		rev("a.c", "1.1", "alice", "Initial import", 0),
+		rev("b.c", "1.1", "alice", "Initial import", 2*time.Second),
+		rev("c.c", "1.1", "alice", "Initial import", 4*time.Second),
+	}
+
+	changesets := groupChangesets(revs, DefaultFuzzWindow)
+	require.Len(t, changesets, 1)
+	require.Len(t, changesets[0].revisions, 3)
+	require.Equal(t, changesetBase.Add(4*time.Second), changesets[0].date)
+}
+
+func TestGroupChangesets_SameRevisionDifferentCommits(t *testing.T) {
+	// Unrelated files that happen to share revision 1.1 stay separate
+	revs := []*fileRevision{
+		rev("a.c", "1.1", "alice", "Add a", 0),
+		rev("b.c", "1.1", "bob", "Add b", 0),
+		rev("c.c", "1.1", "alice", "Add c", time.Second),
+	}
+
+	changesets := groupChangesets(revs, DefaultFuzzWindow)
+	require.Len(t, changesets, 3)
+}
+
+func TestGroupChangesets_FuzzWindow(t *testing.T) {
+	revs := []*fileRevision{
+		rev("a.c", "1.2", "alice", "Fix", 0),
+		rev("b.c", "1.2", "alice", "Fix", 4*time.Minute),
+		rev("c.c", "1.2", "alice", "Fix", 8*time.Minute), // within window of b.c
+		rev("d.c", "1.2", "alice", "Fix", 20*time.Minute),
+	}
+
+	changesets := groupChangesets(revs, DefaultFuzzWindow)
+	require.Len(t, changesets, 2)
+	require.Len(t, changesets[0].revisions, 3)
+	require.Len(t, changesets[1].revisions, 1)
+
+	changesets = groupChangesets(revs, time.Minute)
+	require.Len(t, changesets, 4)
+}
+
+func TestGroupChangesets_SplitsWhenFileRepeats(t *testing.T) {
+	revs := []*fileRevision{
+		rev("a.c", "1.1", "alice", "Typo", 0),
+		rev("a.c", "1.2", "alice", "Typo", time.Second),
+		rev("b.c", "1.1", "alice", "Typo", 2*time.Second),
+	}
+
+	changesets := groupChangesets(revs, DefaultFuzzWindow)
+	require.Len(t, changesets, 2)
+	require.Len(t, changesets[0].revisions, 1)
+	require.Len(t, changesets[1].revisions, 2)
+}
+
+func TestGroupChangesets_BranchSeparatesCommits(t *testing.T) {
+	trunk := rev("a.c", "1.2", "alice", "Merge", 0)
+	branch := rev("b.c", "1.1.2.1", "alice", "Merge", 0)
+	branch.branch = "DEV"
+
+	changesets := groupChangesets([]*fileRevision{trunk, branch}, DefaultFuzzWindow)
+	require.Len(t, changesets, 2)
+}
+
+func TestGroupChangesets_CommitID(t *testing.T) {
+	a := rev("a.c", "1.2", "alice", "Change", 0)
+	b := rev("b.c", "1.2", "alice", "Change", time.Hour) // far outside the fuzz window
+	c := rev("c.c", "1.2", "alice", "Change", time.Second)
+	a.commitID = "100abc"
+	b.commitID = "100abc"
+	c.commitID = "200def"
+
+	changesets := groupChangesets([]*fileRevision{a, b, c}, DefaultFuzzWindow)
+	require.Len(t, changesets, 2)
+	for _, cs := range changesets {
+		if cs.commitID == "100abc" {
+			require.Len(t, cs.revisions, 2)
+		} else {
+			require.Len(t, cs.revisions, 1)
+		}
+	}
+}
+
+func TestChangesetID_Stable(t *testing.T) {
+	revs := []*fileRevision{
+		rev("b.c", "1.1", "alice", "Initial", 0),
+		rev("a.c", "1.1", "alice", "Initial", 0),
+	}
+
+	first := groupChangesets(revs, DefaultFuzzWindow)
+	second := groupChangesets([]*fileRevision{revs[1], revs[0]}, DefaultFuzzWindow)
+	require.Equal(t, first[0].id, second[0].id)
+	require.Len(t, first[0].id, 16)
+}
+
+func TestOrderChangesets_Chronological(t *testing.T) {
+	revs := []*fileRevision{
+		rev("a.c", "1.1", "alice", "one", 2*time.Hour),
+		rev("b.c", "1.1", "alice", "two", time.Hour),
+		rev("c.c", "1.1", "alice", "three", 0),
+	}
+
+	ordered := orderChangesets(groupChangesets(revs, DefaultFuzzWindow))
+	require.Len(t, ordered, 3)
+	require.Equal(t, "three", ordered[0].message)
+	require.Equal(t, "two", ordered[1].message)
+	require.Equal(t, "one", ordered[2].message)
+}
+
+func TestOrderChangesets_RespectsFileHistory(t *testing.T) {
+	// A skewed clock made 1.2 appear older than 1.1
+	first := rev("a.c", "1.1", "alice", "first", time.Hour)
+	second := rev("a.c", "1.2", "bob", "second", 0)
+	second.predecessor = "1.1"
+
+	ordered := orderChangesets(groupChangesets([]*fileRevision{first, second}, DefaultFuzzWindow))
+	require.Len(t, ordered, 2)
+	require.Equal(t, "first", ordered[0].message)
+	require.Equal(t, "second", ordered[1].message)
+}
+
+func TestOrderChangesets_BreaksCycles(t *testing.T) {
+	// Two changesets that each depend on the other
+	a1 := rev("a.c", "1.1", "alice", "x", 0)
+	b2 := rev("b.c", "1.2", "alice", "x", time.Second)
+	b2.predecessor = "1.1"
+	b1 := rev("b.c", "1.1", "bob", "y", 2*time.Second)
+	a2 := rev("a.c", "1.2", "bob", "y", 3*time.Second)
+	a2.predecessor = "1.1"
+
+	ordered := orderChangesets(groupChangesets([]*fileRevision{a1, b2, b1, a2}, DefaultFuzzWindow))
+	require.Len(t, ordered, 2)
+}
+
+func TestChangesetToCommit(t *testing.T) {
+	withContent := rev("a.c", "1.1", "alice", "msg", 0)
+	withContent.content = []byte("data")
+	withContent.hasContent = true
+	withoutContent := rev("b.c", "1.1", "alice", "msg", 0)
+
+	changesets := groupChangesets([]*fileRevision{withContent, withoutContent}, DefaultFuzzWindow)
+	require.Len(t, changesets, 1)
+
+	commit := changesets[0].toCommit()
+	require.Equal(t, changesets[0].id, commit.Revision)
+	require.Equal(t, "alice", commit.Author)
+	require.Equal(t, "msg", commit.Message)
+	require.Len(t, commit.Files, 1)
+	require.Equal(t, "a.c", commit.Files[0].Path)
+}
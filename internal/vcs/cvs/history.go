@@ -0,0 +1,204 @@
+package cvs
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// History record types
+const (
+	HistoryCheckout = 'O' // cvs checkout
+	HistoryExport   = 'E' // cvs export
+	HistoryRelease  = 'F' // cvs release
+	HistoryTag      = 'T' // cvs rtag
+	HistoryModified = 'M' // Commit of a modified file
+	HistoryAdded    = 'A' // Commit of an added file
+	HistoryRemoved  = 'R' // Commit of a removed file
+)
+
+// HistoryRecord is one line of CVSROOT/history. CVS writes a record for
+// every checkout, commit, update and rtag, unless LogHistory in
+// CVSROOT/config leaves some out.
+type HistoryRecord struct {
+	Type       byte
+	Time       time.Time
+	User       string
+	Dir        string // Working directory, "<remote>" for client/server
+	Repository string // Repository directory; for rtag A (add), D (delete), or the revision or date tagged
+	Revision   string // Revision; for rtag the tag name
+	Name       string // File name; for checkout, export and rtag the module
+}
+
+// History is what CVSROOT/history and CVSROOT/val-tags record about a
+// repository
+type History struct {
+	Records []HistoryRecord
+	ValTags []string // Tags CVS has seen in the repository
+}
+
+// TagHistory is what the history records about a tag or branch symbol
+type TagHistory struct {
+	Name    string
+	Applied time.Time // Latest rtag that applied it, zero if none is recorded
+	User    string    // User of that rtag
+	Module  string    // Module of that rtag
+	Deleted bool      // A later rtag deleted it
+	Valid   bool      // Listed in val-tags
+}
+
+// ReadHistory reads the history and val-tags files of a CVSROOT
+// directory. Missing files are treated as empty: both are optional.
+func ReadHistory(cvsroot string) (*History, error) {
+	h := &History{}
+
+	if file, err := os.Open(filepath.Join(cvsroot, "history")); err == nil {
+		defer func() {
+			if err := file.Close(); err != nil {
+				log.Printf("Warning: failed to close CVSROOT/history: %v", err)
+			}
+		}()
+		skipped := 0
+		if h.Records, skipped, err = parseHistory(file); err != nil {
+			return nil, fmt.Errorf("failed to read CVSROOT/history: %w", err)
+		}
+		if skipped > 0 {
+			log.Printf("Warning: skipped %d malformed lines of CVSROOT/history", skipped)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to open CVSROOT/history: %w", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(cvsroot, "val-tags"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read CVSROOT/val-tags: %w", err)
+	}
+	h.ValTags = parseValTags(string(data))
+
+	return h, nil
+}
+
+// parseHistory parses history records, one per line:
+//
+//	<type><hex time>|<user>|<dir>|<repository>|<revision>|<name>
+//
+// It returns the number of malformed lines it skipped.
+func parseHistory(r io.Reader) ([]HistoryRecord, int, error) {
+	var records []HistoryRecord
+	skipped := 0
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		fields := strings.SplitN(line, "|", 6)
+		if len(fields) < 6 || len(fields[0]) < 2 {
+			skipped++
+			continue
+		}
+		seconds, err := strconv.ParseInt(fields[0][1:], 16, 64)
+		if err != nil {
+			skipped++
+			continue
+		}
+		records = append(records, HistoryRecord{
+			Type:       fields[0][0],
+			Time:       time.Unix(seconds, 0).UTC(),
+			User:       fields[1],
+			Dir:        fields[2],
+			Repository: fields[3],
+			Revision:   fields[4],
+			Name:       fields[5],
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, err
+	}
+	return records, skipped, nil
+}
+
+// parseValTags parses val-tags, whose lines are a tag name and "y"
+func parseValTags(data string) []string {
+	var tags []string
+	for _, line := range strings.Split(data, "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			tags = append(tags, fields[0])
+		}
+	}
+	return tags
+}
+
+// Count returns the number of records of the given types
+func (h *History) Count(types ...byte) int {
+	n := 0
+	for _, record := range h.Records {
+		for _, t := range types {
+			if record.Type == t {
+				n++
+				break
+			}
+		}
+	}
+	return n
+}
+
+// Tags returns, sorted by name, every symbol an rtag applied or deleted or
+// val-tags lists
+func (h *History) Tags() []TagHistory {
+	tags := make(map[string]*TagHistory)
+	get := func(name string) *TagHistory {
+		if tags[name] == nil {
+			tags[name] = &TagHistory{Name: name}
+		}
+		return tags[name]
+	}
+
+	for _, record := range h.Records {
+		if record.Type != HistoryTag || record.Revision == "" {
+			continue
+		}
+		tag := get(record.Revision)
+		if record.Time.Before(tag.Applied) {
+			continue
+		}
+		if record.Repository == "D" {
+			tag.Deleted = true
+			continue
+		}
+		tag.Applied = record.Time
+		tag.User = record.User
+		tag.Module = record.Name
+		tag.Deleted = false
+	}
+	for _, name := range h.ValTags {
+		get(name).Valid = true
+	}
+
+	result := make([]TagHistory, 0, len(tags))
+	for _, tag := range tags {
+		result = append(result, *tag)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// TagDates returns when each symbol still present was last applied
+func (h *History) TagDates() map[string]time.Time {
+	dates := make(map[string]time.Time)
+	for _, tag := range h.Tags() {
+		if !tag.Applied.IsZero() && !tag.Deleted {
+			dates[tag.Name] = tag.Applied
+		}
+	}
+	return dates
+}
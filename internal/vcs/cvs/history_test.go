@@ -0,0 +1,78 @@
+package cvs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testHistory = `O65920080|alice|~/work/*0|project||project
+M65920080|alice|~/work/project|project/src|1.2|main.c
+A65920080|alice|<remote>/src|project/src|1.1|util.c
+T65920080|alice|<remote>/*0|A|REL_1_0|project
+T65badf00|bob|<remote>/*0|1.2|REL_1_0|project
+T65badf00|bob|<remote>/*0|A|BETA|project
+T65e11a80|bob|<remote>/*0|D|BETA|project
+this line is not a record
+Xnothex|carol|dir|repo|rev|name
+`
+
+func TestParseHistory(t *testing.T) {
+	records, skipped, err := parseHistory(strings.NewReader(testHistory))
+	require.NoError(t, err)
+	require.Equal(t, 2, skipped)
+	require.Len(t, records, 7)
+
+	require.Equal(t, HistoryRecord{
+		Type:       HistoryModified,
+		Time:       time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		User:       "alice",
+		Dir:        "~/work/project",
+		Repository: "project/src",
+		Revision:   "1.2",
+		Name:       "main.c",
+	}, records[1])
+	require.Equal(t, "REL_1_0", records[3].Revision)
+	require.Equal(t, "A", records[3].Repository)
+}
+
+func TestHistoryTags(t *testing.T) {
+	records, _, err := parseHistory(strings.NewReader(testHistory))
+	require.NoError(t, err)
+	history := &History{Records: records, ValTags: parseValTags("REL_1_0 y\nOLD y\n\n")}
+
+	require.Equal(t, 1, history.Count(HistoryCheckout, HistoryExport))
+	require.Equal(t, 2, history.Count(HistoryModified, HistoryAdded, HistoryRemoved))
+	require.Equal(t, 4, history.Count(HistoryTag))
+
+	require.Equal(t, []TagHistory{
+		{Name: "BETA", Applied: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), User: "bob", Module: "project", Deleted: true},
+		{Name: "OLD", Valid: true},
+		{Name: "REL_1_0", Applied: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), User: "bob", Module: "project", Valid: true},
+	}, history.Tags())
+
+	require.Equal(t, map[string]time.Time{
+		"REL_1_0": time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+	}, history.TagDates())
+}
+
+func TestReadHistory(t *testing.T) {
+	dir := t.TempDir()
+
+	// Both files are optional
+	history, err := ReadHistory(dir)
+	require.NoError(t, err)
+	require.Empty(t, history.Records)
+	require.Empty(t, history.ValTags)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "history"), []byte(testHistory), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "val-tags"), []byte("REL_1_0 y\n"), 0644))
+	history, err = ReadHistory(dir)
+	require.NoError(t, err)
+	require.Len(t, history.Records, 7)
+	require.Equal(t, []string{"REL_1_0"}, history.ValTags)
+}
@@ -0,0 +1,228 @@
+package cvs
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Keyword modes
+const (
+	KeywordsRaw      = "raw"      // Keep keywords as stored in the RCS file
+	KeywordsCollapse = "collapse" // Write keywords without values, e.g. $Id$
+	KeywordsExpand   = "expand"   // Expand keywords as a CVS checkout would
+	KeywordsStrip    = "strip"    // Remove keywords altogether
+
+	// keywordsValue writes only the values of keywords, as CVS does for
+	// files checked in with -kv
+	keywordsValue = "value"
+)
+
+// KeywordOptions configures how RCS keywords such as $Id$ are written to
+// file contents. Files checked in with -kb or -ko keep their contents
+// regardless of the mode.
+type KeywordOptions struct {
+	Mode  string        // Mode of all files (default: raw)
+	Rules []KeywordRule // Modes of matching paths, the first match wins
+}
+
+// KeywordRule sets the keyword mode of files whose path matches a glob
+// pattern. Patterns without a slash match the file name in any directory.
+type KeywordRule struct {
+	Pattern string `yaml:"pattern" json:"pattern"`
+	Mode    string `yaml:"mode" json:"mode"`
+}
+
+// Check validates the modes and patterns
+func (o KeywordOptions) Check() error {
+	if err := checkKeywordMode(o.Mode); err != nil {
+		return err
+	}
+	for _, rule := range o.Rules {
+		if _, err := path.Match(rule.Pattern, ""); err != nil {
+			return fmt.Errorf("invalid keyword pattern %q: %w", rule.Pattern, err)
+		}
+		if err := checkKeywordMode(rule.Mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkKeywordMode(mode string) error {
+	switch mode {
+	case "", KeywordsRaw, KeywordsCollapse, KeywordsExpand, KeywordsStrip:
+		return nil
+	default:
+		return fmt.Errorf("unsupported keyword mode: %s (supported: %s, %s, %s, %s)",
+			mode, KeywordsRaw, KeywordsCollapse, KeywordsExpand, KeywordsStrip)
+	}
+}
+
+// modeOf returns the keyword mode of a working file path
+func (o KeywordOptions) modeOf(file string) string {
+	for _, rule := range o.Rules {
+		name := file
+		if !strings.Contains(rule.Pattern, "/") {
+			name = path.Base(file)
+		}
+		if matched, _ := path.Match(rule.Pattern, name); matched {
+			return rule.Mode
+		}
+	}
+	return o.Mode
+}
+
+// keywordPattern matches an RCS keyword, collapsed or with a value
+var keywordPattern = regexp.MustCompile(`\$(Author|CVSHeader|Date|Header|Id|Locker|Log|Name|RCSfile|Revision|Source|State)(?::[^$\n]*)?\$`)
+
+// logRevisionLine matches the first line of an entry CVS inserted after a
+// $Log$ keyword
+var logRevisionLine = regexp.MustCompile(`^Revision \d+(\.\d+)+\s`)
+
+// applyKeywords writes the keywords of the text of a revision according to
+// mode and the expansion mode of the file. root is the repository the RCS
+// file lives in, as named by the $Header$ and $Source$ keywords.
+//
+// Collapsing or stripping keywords also drops the revision entries CVS
+// inserted after $Log$ at earlier checkouts, which would otherwise show up
+// as changes. Expanding inserts the entry of the revision, as CVS does.
+func (r *RCSFile) applyKeywords(text []byte, rev, mode, root string) []byte {
+	delta := r.Deltas[rev]
+	if delta == nil || r.Expand == "b" || r.Expand == "o" {
+		return text
+	}
+
+	// The expansion mode decides what a CVS checkout looks like
+	keepHistory := false
+	if mode == KeywordsExpand {
+		switch r.Expand {
+		case "k":
+			mode, keepHistory = KeywordsCollapse, true
+		case "v":
+			mode = keywordsValue
+		}
+	}
+	if mode == "" || mode == KeywordsRaw {
+		return text
+	}
+
+	var out bytes.Buffer
+	for {
+		loc := keywordPattern.FindSubmatchIndex(text)
+		if loc == nil {
+			out.Write(text)
+			break
+		}
+		keyword := string(text[loc[2]:loc[3]])
+		out.Write(text[:loc[0]])
+		prefix := out.Bytes()[bytes.LastIndexByte(out.Bytes(), '\n')+1:]
+		prefix = append([]byte(nil), prefix...)
+
+		switch mode {
+		case KeywordsCollapse:
+			out.WriteString("$" + keyword + "$")
+		case KeywordsExpand:
+			out.WriteString("$" + keyword + ": " + r.keywordValue(keyword, delta, root) + " $")
+		case keywordsValue:
+			out.WriteString(r.keywordValue(keyword, delta, root))
+		}
+		text = text[loc[1]:]
+
+		if keyword != "Log" {
+			continue
+		}
+		end := bytes.IndexByte(text, '\n')
+		if end < 0 {
+			continue
+		}
+		out.Write(text[:end+1])
+		text = text[end+1:]
+		switch {
+		case mode == KeywordsExpand || mode == keywordsValue:
+			out.Write(logEntry(prefix, delta))
+		case !keepHistory:
+			text = skipLogHistory(text, prefix)
+		}
+	}
+	return out.Bytes()
+}
+
+// keywordValue returns the value CVS gives a keyword at checkout
+func (r *RCSFile) keywordValue(keyword string, delta *Delta, root string) string {
+	rcsPath := r.RCSPath
+	if rcsPath == "" {
+		rcsPath = r.Path + ",v"
+	}
+	source := filepath.ToSlash(filepath.Join(root, rcsPath))
+	date := delta.Date.Format("2006/01/02 15:04:05")
+	details := fmt.Sprintf("%s %s %s %s", delta.Revision, date, delta.Author, delta.State)
+
+	switch keyword {
+	case "Author":
+		return delta.Author
+	case "CVSHeader":
+		return rcsPath + " " + details
+	case "Date":
+		return date
+	case "Header":
+		return source + " " + details
+	case "Id":
+		return path.Base(rcsPath) + " " + details
+	case "Log", "RCSfile":
+		return path.Base(rcsPath)
+	case "Revision":
+		return delta.Revision
+	case "Source":
+		return source
+	case "State":
+		return delta.State
+	default:
+		// Locker and Name: no locks and no sticky tag
+		return ""
+	}
+}
+
+// logEntry formats the entry CVS inserts after a $Log$ keyword, each line
+// starting with the text that precedes the keyword on its line
+func logEntry(prefix []byte, delta *Delta) []byte {
+	leader := bytes.TrimRight(prefix, " \t")
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%sRevision %s  %s  %s\n", prefix, delta.Revision, delta.Date.Format("2006/01/02 15:04:05"), delta.Author)
+	for _, line := range strings.Split(strings.TrimRight(delta.Log, "\n"), "\n") {
+		if line == "" {
+			b.Write(leader)
+		} else {
+			b.Write(prefix)
+			b.WriteString(line)
+		}
+		b.WriteByte('\n')
+	}
+	b.Write(leader)
+	b.WriteByte('\n')
+	return b.Bytes()
+}
+
+// skipLogHistory skips the revision entries following a $Log$ line. Each
+// entry is a "Revision" line and its log message, ended by a line holding
+// only the prefix.
+func skipLogHistory(text, prefix []byte) []byte {
+	leader := bytes.TrimRight(prefix, " \t")
+	for bytes.HasPrefix(text, prefix) && logRevisionLine.Match(text[len(prefix):]) {
+		for len(text) > 0 {
+			line := text
+			if end := bytes.IndexByte(text, '\n'); end >= 0 {
+				line = text[:end+1]
+			}
+			text = text[len(line):]
+			if bytes.Equal(bytes.TrimRight(line, " \t\r\n"), leader) {
+				break
+			}
+		}
+	}
+	return text
+}
@@ -0,0 +1,133 @@
+package cvs
+
+import (
+	"testing"
+	"time"
+)
+
+func keywordFile(expand string) *RCSFile {
+	return &RCSFile{
+		Path:    "src/main.c",
+		RCSPath: "src/main.c,v",
+		Expand:  expand,
+		Deltas: map[string]*Delta{
+			"1.3": {
+				Revision: "1.3",
+				Date:     time.Date(2004, 5, 6, 7, 8, 9, 0, time.UTC),
+				Author:   "jsmith",
+				State:    "Exp",
+				Log:      "Fix crash\n\non exit\n",
+			},
+		},
+	}
+}
+
+// stored is the text of revision 1.3 as CVS stores it: keywords carry the
+// values of the checkout it was committed from, and $Log$ the entry of 1.2
+const stored = `/*
+ * $Id: main.c,v 1.2 2004/01/01 00:00:00 jsmith Exp $
+ * $Log: main.c,v $
+ * Revision 1.2  2004/01/01 00:00:00  jsmith
+ * Add main
+ *
+ */
+char *rev = "$Revision: 1.2 $";
+`
+
+func TestApplyKeywords(t *testing.T) {
+	tests := []struct {
+		mode, expand, expected string
+	}{
+		{KeywordsRaw, "", stored},
+		{"", "", stored},
+		{KeywordsCollapse, "", "/*\n * $Id$\n * $Log$\n */\nchar *rev = \"$Revision$\";\n"},
+		{KeywordsStrip, "", "/*\n * \n * \n */\nchar *rev = \"\";\n"},
+		{KeywordsExpand, "kv", `/*
+ * $Id: main.c,v 1.3 2004/05/06 07:08:09 jsmith Exp $
+ * $Log: main.c,v $
+ * Revision 1.3  2004/05/06 07:08:09  jsmith
+ * Fix crash
+ *
+ * on exit
+ *
+ * Revision 1.2  2004/01/01 00:00:00  jsmith
+ * Add main
+ *
+ */
+char *rev = "$Revision: 1.3 $";
+`},
+		// -kk checkouts collapse keywords but keep the log history
+		{KeywordsExpand, "k", `/*
+ * $Id$
+ * $Log$
+ * Revision 1.2  2004/01/01 00:00:00  jsmith
+ * Add main
+ *
+ */
+char *rev = "$Revision$";
+`},
+		{KeywordsCollapse, "o", stored},
+		{KeywordsExpand, "b", stored},
+	}
+	for _, tt := range tests {
+		got := string(keywordFile(tt.expand).applyKeywords([]byte(stored), "1.3", tt.mode, "/cvsroot"))
+		if got != tt.expected {
+			t.Errorf("mode %q, expand %q:\ngot  %q\nwant %q", tt.mode, tt.expand, got, tt.expected)
+		}
+	}
+}
+
+func TestKeywordValues(t *testing.T) {
+	rcs := keywordFile("")
+	text := "$Header$ $Source$ $CVSHeader$ $RCSfile$ $Author$ $Date$ $State$ $Name$ $Locker$"
+	expected := "$Header: /cvsroot/src/main.c,v 1.3 2004/05/06 07:08:09 jsmith Exp $ " +
+		"$Source: /cvsroot/src/main.c,v $ " +
+		"$CVSHeader: src/main.c,v 1.3 2004/05/06 07:08:09 jsmith Exp $ " +
+		"$RCSfile: main.c,v $ $Author: jsmith $ $Date: 2004/05/06 07:08:09 $ " +
+		"$State: Exp $ $Name:  $ $Locker:  $"
+	if got := string(rcs.applyKeywords([]byte(text), "1.3", KeywordsExpand, "/cvsroot")); got != expected {
+		t.Errorf("got  %q\nwant %q", got, expected)
+	}
+
+	// -kv writes values only
+	rcs.Expand = "v"
+	if got := string(rcs.applyKeywords([]byte("$Revision$ $Author$"), "1.3", KeywordsExpand, "/cvsroot")); got != "1.3 jsmith" {
+		t.Errorf("-kv expansion = %q", got)
+	}
+}
+
+func TestKeywordOptions(t *testing.T) {
+	options := KeywordOptions{
+		Mode: KeywordsCollapse,
+		Rules: []KeywordRule{
+			{Pattern: "doc/*", Mode: KeywordsRaw},
+			{Pattern: "*.c", Mode: KeywordsExpand},
+		},
+	}
+	if err := options.Check(); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+
+	tests := map[string]string{
+		"src/main.c": KeywordsExpand,
+		"doc/a.c":    KeywordsRaw,
+		"doc/sub/a":  KeywordsCollapse,
+		"README":     KeywordsCollapse,
+	}
+	for file, expected := range tests {
+		if got := options.modeOf(file); got != expected {
+			t.Errorf("modeOf(%q) = %q, want %q", file, got, expected)
+		}
+	}
+
+	invalid := []KeywordOptions{
+		{Mode: "value"},
+		{Rules: []KeywordRule{{Pattern: "*.c", Mode: "kv"}}},
+		{Rules: []KeywordRule{{Pattern: "[", Mode: KeywordsRaw}}},
+	}
+	for _, options := range invalid {
+		if err := options.Check(); err == nil {
+			t.Errorf("Check(%v) should fail", options)
+		}
+	}
+}
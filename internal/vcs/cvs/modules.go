@@ -0,0 +1,298 @@
+package cvs
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// moduleDef is one module of CVSROOT/modules
+type moduleDef struct {
+	name  string
+	alias bool     // -a: args list paths and modules, ! excludes
+	dir   string   // -d: directory the module is checked out to
+	local bool     // -l: only the files directly in the directory
+	args  []string // Directory and files, or &modules
+}
+
+// checkoutDir returns the directory a module is checked out to
+func (d *moduleDef) checkoutDir() string {
+	if d.dir != "" {
+		return d.dir
+	}
+	return d.name
+}
+
+// parseModules reads a CVSROOT/modules file. Lines ending in a backslash
+// continue on the next line.
+func parseModules(data []byte) (map[string]*moduleDef, error) {
+	defs := make(map[string]*moduleDef)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	var line string
+	for number := 1; scanner.Scan(); number++ {
+		text := scanner.Text()
+		if strings.HasSuffix(text, "\\") {
+			line += strings.TrimSuffix(text, "\\") + " "
+			continue
+		}
+		line += text
+		fields := strings.Fields(line)
+		line = ""
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		def := &moduleDef{name: fields[0]}
+		args := fields[1:]
+	options:
+		for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+			option := args[0]
+			args = args[1:]
+			switch option {
+			case "-a":
+				def.alias = true
+				break options
+			case "-l":
+				def.local = true
+			case "-d", "-e", "-i", "-o", "-s", "-t", "-u":
+				if len(args) == 0 {
+					return nil, fmt.Errorf("CVSROOT/modules:%d: option %s of %s needs an argument", number, option, def.name)
+				}
+				if option == "-d" {
+					def.dir = args[0]
+				}
+				args = args[1:]
+			default:
+				return nil, fmt.Errorf("CVSROOT/modules:%d: unknown option %s of %s", number, option, def.name)
+			}
+		}
+		if len(args) == 0 {
+			return nil, fmt.Errorf("CVSROOT/modules:%d: module %s has no directory", number, def.name)
+		}
+		def.args = args
+		defs[def.name] = def
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read CVSROOT/modules: %w", err)
+	}
+	return defs, nil
+}
+
+// modulePath is a directory or file of the repository that a module checks
+// out, and the path it is checked out to relative to the module root
+type modulePath struct {
+	source string // Slash-separated repository path; empty for the whole repository
+	target string
+	file   bool // The source is a file rather than a directory
+	local  bool // Only files directly in the directory
+}
+
+// moduleSelection is the part of a repository a module checks out
+type moduleSelection struct {
+	paths    []modulePath
+	excludes []string // Repository paths not to check out
+}
+
+// selectModules resolves a module and the modules to exclude from it in the
+// repository at root. Names not defined in CVSROOT/modules are paths of
+// directories or files in the repository. Without a module the whole
+// repository is selected.
+func selectModules(root, module string, exclude []string) (*moduleSelection, error) {
+	defs := make(map[string]*moduleDef)
+	if data, err := os.ReadFile(filepath.Join(root, "CVSROOT", "modules")); err == nil {
+		if defs, err = parseModules(data); err != nil {
+			return nil, err
+		}
+	}
+
+	r := &moduleResolver{root: root, defs: defs, seen: make(map[string]bool)}
+	selection := &moduleSelection{}
+	if module == "" {
+		selection.paths = []modulePath{{}}
+	} else if err := r.resolve(module, "", selection); err != nil {
+		return nil, err
+	}
+
+	for _, name := range exclude {
+		excluded := &moduleSelection{}
+		r.seen = make(map[string]bool)
+		if err := r.resolve(name, "", excluded); err != nil {
+			return nil, fmt.Errorf("invalid excluded module: %w", err)
+		}
+		for _, p := range excluded.paths {
+			selection.excludes = append(selection.excludes, p.source)
+		}
+	}
+	return selection, nil
+}
+
+type moduleResolver struct {
+	root string
+	defs map[string]*moduleDef
+	seen map[string]bool
+}
+
+// resolve adds the paths of a module, checked out to target, to selection
+func (r *moduleResolver) resolve(name, target string, selection *moduleSelection) error {
+	def, ok := r.defs[name]
+	if !ok {
+		source := path.Clean(strings.Trim(name, "/"))
+		found, dir := r.exists(source)
+		if !found {
+			return fmt.Errorf("module %s is neither defined in CVSROOT/modules nor a path of the repository", name)
+		}
+		selection.paths = append(selection.paths, modulePath{source: source, target: target, file: !dir})
+		return nil
+	}
+	if r.seen[name] {
+		return fmt.Errorf("module %s includes itself", name)
+	}
+	r.seen[name] = true
+	defer delete(r.seen, name)
+
+	if def.alias {
+		// Aliases check out every path under its own name
+		for _, arg := range def.args {
+			if strings.HasPrefix(arg, "!") {
+				selection.excludes = append(selection.excludes, path.Clean(strings.Trim(arg[1:], "/")))
+				continue
+			}
+			sub := arg
+			if other, ok := r.defs[arg]; ok && arg != name {
+				sub = other.checkoutDir()
+			} else if arg == name {
+				delete(r.defs, name)
+				defer func() { r.defs[name] = def }()
+			}
+			if err := r.resolve(arg, path.Join(target, sub), selection); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	args := def.args
+	if !strings.HasPrefix(args[0], "&") {
+		dir := path.Clean(strings.Trim(args[0], "/"))
+		args = args[1:]
+		var files []string
+		for _, arg := range args {
+			if !strings.HasPrefix(arg, "&") {
+				files = append(files, arg)
+			}
+		}
+		if _, isDir := r.exists(dir); !isDir {
+			return fmt.Errorf("directory %s of module %s does not exist", dir, name)
+		}
+		if len(files) == 0 {
+			selection.paths = append(selection.paths, modulePath{source: dir, target: target, local: def.local})
+		}
+		for _, file := range files {
+			selection.paths = append(selection.paths, modulePath{source: path.Join(dir, file), target: path.Join(target, file), file: true})
+		}
+	}
+
+	// Ampersand modules are checked out into subdirectories
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "&") {
+			continue
+		}
+		included := arg[1:]
+		sub := included
+		if other, ok := r.defs[included]; ok {
+			sub = other.checkoutDir()
+		}
+		if err := r.resolve(included, path.Join(target, sub), selection); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exists reports whether a repository path is a directory or a file, alive
+// or in the Attic, and whether it is a directory
+func (r *moduleResolver) exists(source string) (found, dir bool) {
+	full := filepath.Join(r.root, filepath.FromSlash(source))
+	if info, err := os.Stat(full); err == nil && info.IsDir() {
+		return true, true
+	}
+	parent, file := path.Split(source)
+	for _, candidate := range []string{source + ",v", path.Join(parent, "Attic", file+",v")} {
+		if _, err := os.Stat(filepath.Join(r.root, filepath.FromSlash(candidate))); err == nil {
+			return true, false
+		}
+	}
+	return false, false
+}
+
+// roots returns the repository directories that hold the selected files,
+// without directories nested in others
+func (s *moduleSelection) roots() []string {
+	var dirs []string
+	for _, p := range s.paths {
+		dir := p.source
+		if p.file {
+			// Alive or in the Attic, the RCS file is in the parent
+			dir = path.Dir(dir)
+			if dir == "." {
+				dir = ""
+			}
+		}
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	var roots []string
+dirs:
+	for _, dir := range dirs {
+		for _, root := range roots {
+			if within(dir, root) {
+				continue dirs
+			}
+		}
+		roots = append(roots, dir)
+	}
+	return roots
+}
+
+// workingPath maps the repository path of a working file to its path
+// relative to the module root, or returns false if the module does not
+// check it out
+func (s *moduleSelection) workingPath(file string) (string, bool) {
+	for _, exclude := range s.excludes {
+		if within(file, exclude) {
+			return "", false
+		}
+	}
+
+	best := -1
+	for i, p := range s.paths {
+		if !within(file, p.source) {
+			continue
+		}
+		if p.local && strings.Contains(strings.TrimPrefix(file, p.source+"/"), "/") {
+			continue
+		}
+		if best < 0 || len(p.source) > len(s.paths[best].source) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return "", false
+	}
+
+	p := s.paths[best]
+	rel := strings.TrimPrefix(strings.TrimPrefix(file, p.source), "/")
+	return path.Join(p.target, rel), true
+}
+
+// within reports whether a slash-separated path is dir or inside it. Every
+// path is within the empty directory.
+func within(file, dir string) bool {
+	return dir == "" || file == dir || strings.HasPrefix(file, dir+"/")
+}
@@ -0,0 +1,188 @@
+package cvs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseModules(t *testing.T) {
+	defs, err := parseModules([]byte(`# Modules
+app -d application project/app
+lib project/lib util.c \
+	util.h
+all -a app lib !project/lib/old
+world &app &lib
+top -l project
+hooks -i /usr/bin/notify project/hooks
+`))
+	require.NoError(t, err)
+	require.Len(t, defs, 6)
+
+	require.Equal(t, "application", defs["app"].checkoutDir())
+	require.Equal(t, []string{"project/app"}, defs["app"].args)
+	require.Equal(t, []string{"project/lib", "util.c", "util.h"}, defs["lib"].args)
+	require.True(t, defs["all"].alias)
+	require.Equal(t, []string{"app", "lib", "!project/lib/old"}, defs["all"].args)
+	require.Equal(t, "world", defs["world"].checkoutDir())
+	require.True(t, defs["top"].local)
+	require.Equal(t, []string{"project/hooks"}, defs["hooks"].args)
+
+	_, err = parseModules([]byte("broken -x project\n"))
+	require.Error(t, err)
+	_, err = parseModules([]byte("empty -d dir\n"))
+	require.Error(t, err)
+}
+
+func TestSelectModules(t *testing.T) {
+	dir := t.TempDir()
+	for _, file := range []string{
+		"project/top.txt,v",
+		"project/app/main.c,v",
+		"project/app/Attic/gone.c,v",
+		"project/lib/util.c,v",
+		"project/lib/util.h,v",
+		"project/lib/list.c,v",
+		"project/lib/old/legacy.c,v",
+		"other/readme,v",
+	} {
+		writeRCSFile(t, filepath.Join(dir, filepath.FromSlash(file)), "alice", "2024.01.01.10.00.00", "Import", "x\n")
+	}
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "CVSROOT"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "CVSROOT", "modules"), []byte(strings.Join([]string{
+		"app -d application project/app",
+		"lib project/lib util.c util.h",
+		"all -a app project/lib !project/lib/old",
+		"world project &app &lib",
+		"top -l project",
+		"loop &loop",
+		"missing nowhere",
+	}, "\n")), 0644))
+
+	tests := []struct {
+		name     string
+		module   string
+		exclude  []string
+		expected map[string]string // Repository path -> working path, "" if not selected
+	}{
+		{
+			name:   "whole repository",
+			module: "",
+			expected: map[string]string{
+				"project/app/main.c": "project/app/main.c",
+				"other/readme":       "other/readme",
+			},
+		},
+		{
+			name:   "directory",
+			module: "project/app",
+			expected: map[string]string{
+				"project/app/main.c": "main.c",
+				"project/app/gone.c": "gone.c",
+				"project/lib/util.c": "",
+			},
+		},
+		{
+			name:   "regular module",
+			module: "app",
+			expected: map[string]string{
+				"project/app/main.c": "main.c",
+				"other/readme":       "",
+			},
+		},
+		{
+			name:   "module of files",
+			module: "lib",
+			expected: map[string]string{
+				"project/lib/util.c": "util.c",
+				"project/lib/util.h": "util.h",
+				"project/lib/list.c": "",
+			},
+		},
+		{
+			name:   "alias module",
+			module: "all",
+			expected: map[string]string{
+				"project/app/main.c":       "application/main.c",
+				"project/lib/list.c":       "project/lib/list.c",
+				"project/lib/old/legacy.c": "",
+				"project/top.txt":          "",
+				"other/readme":             "",
+				"project/lib/util.c":       "project/lib/util.c",
+				"project/app/gone.c":       "application/gone.c",
+			},
+		},
+		{
+			name:   "ampersand module",
+			module: "world",
+			expected: map[string]string{
+				"project/top.txt":    "top.txt",
+				"project/app/main.c": "application/main.c",
+				"project/lib/util.c": "lib/util.c",
+				"project/lib/list.c": "lib/list.c",
+			},
+		},
+		{
+			name:   "local module",
+			module: "top",
+			expected: map[string]string{
+				"project/top.txt":    "top.txt",
+				"project/app/main.c": "",
+			},
+		},
+		{
+			name:    "excluded modules",
+			module:  "project",
+			exclude: []string{"app", "project/lib/old"},
+			expected: map[string]string{
+				"project/top.txt":          "top.txt",
+				"project/app/main.c":       "",
+				"project/lib/list.c":       "lib/list.c",
+				"project/lib/old/legacy.c": "",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selection, err := selectModules(dir, tt.module, tt.exclude)
+			require.NoError(t, err)
+			for file, expected := range tt.expected {
+				got, ok := selection.workingPath(file)
+				if expected == "" {
+					if ok {
+						t.Errorf("workingPath(%q) = %q, want not selected", file, got)
+					}
+					continue
+				}
+				if !ok || got != expected {
+					t.Errorf("workingPath(%q) = %q, %v, want %q", file, got, ok, expected)
+				}
+			}
+		})
+	}
+
+	for _, module := range []string{"loop", "missing", "project/nowhere"} {
+		if _, err := selectModules(dir, module, nil); err == nil {
+			t.Errorf("selectModules(%q) succeeded, want an error", module)
+		}
+	}
+}
+
+func TestModuleSelectionRoots(t *testing.T) {
+	selection := &moduleSelection{paths: []modulePath{
+		{source: "project/lib"},
+		{source: "project/app/main.c", file: true},
+		{source: "project"},
+		{source: "other"},
+		{source: "project-old"},
+		{source: "project-old/src"},
+	}}
+	require.Equal(t, []string{"other", "project", "project-old"}, selection.roots())
+
+	selection = &moduleSelection{paths: []modulePath{{source: "top.txt", file: true}}}
+	require.Equal(t, []string{""}, selection.roots())
+}
@@ -0,0 +1,162 @@
+package cvs
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+// Contents reconstructs the full text of every revision in the RCS file.
+//
+// The head revision stores its complete text. Every other trunk revision
+// stores a reverse diff against the revision above it (its "next" points
+// further down the trunk), while branch revisions store forward diffs
+// against the revision they sprout from or precede on the branch.
+func (r *RCSFile) Contents() (map[string][]byte, error) {
+	contents := make(map[string][]byte)
+	if r.Head == "" {
+		return contents, nil
+	}
+
+	head := r.Deltas[r.Head]
+	if head == nil {
+		return nil, fmt.Errorf("head revision %s has no delta", r.Head)
+	}
+
+	seen := make(map[string]bool)
+	lines := splitLines([]byte(head.Text))
+	for rev := r.Head; ; {
+		seen[rev] = true
+		contents[rev] = bytes.Join(lines, nil)
+
+		delta := r.Deltas[rev]
+		for _, branchRev := range delta.Branches {
+			if err := r.walkBranch(branchRev, lines, contents, seen); err != nil {
+				return nil, err
+			}
+		}
+
+		// Going down the trunk: the next revision's text is a reverse diff
+		next := delta.Next
+		if next == "" || seen[next] || r.Deltas[next] == nil || !isTrunkRevision(next) {
+			break
+		}
+		var err error
+		lines, err = applyEditScript(lines, []byte(r.Deltas[next].Text))
+		if err != nil {
+			return nil, fmt.Errorf("revision %s: %w", next, err)
+		}
+		rev = next
+	}
+
+	return contents, nil
+}
+
+// walkBranch follows a branch line forward from its first revision, applying
+// each forward diff in turn and descending into any nested branches.
+func (r *RCSFile) walkBranch(rev string, base [][]byte, contents map[string][]byte, seen map[string]bool) error {
+	lines := base
+	for rev != "" && !seen[rev] {
+		delta := r.Deltas[rev]
+		if delta == nil {
+			return nil
+		}
+
+		var err error
+		lines, err = applyEditScript(lines, []byte(delta.Text))
+		if err != nil {
+			return fmt.Errorf("revision %s: %w", rev, err)
+		}
+		seen[rev] = true
+		contents[rev] = bytes.Join(lines, nil)
+
+		for _, branchRev := range delta.Branches {
+			if err := r.walkBranch(branchRev, lines, contents, seen); err != nil {
+				return err
+			}
+		}
+		rev = delta.Next
+	}
+	return nil
+}
+
+// applyEditScript applies an RCS edit script to the given lines.
+//
+// The script consists of "dL N" commands (delete N lines starting at line L)
+// and "aL N" commands (append the N following script lines after line L).
+// Line numbers always refer to the original text, and commands are sorted
+// by line number, so the script can be applied in a single forward pass.
+func applyEditScript(base [][]byte, script []byte) ([][]byte, error) {
+	result := make([][]byte, 0, len(base))
+	pos := 0 // number of base lines already consumed
+
+	cmds := splitLines(script)
+	for i := 0; i < len(cmds); i++ {
+		cmd := bytes.TrimRight(cmds[i], "\r\n")
+		if len(cmd) == 0 {
+			continue
+		}
+
+		op := cmd[0]
+		if op != 'a' && op != 'd' {
+			return nil, fmt.Errorf("invalid edit command %q", cmd)
+		}
+		fields := bytes.Fields(cmd[1:])
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid edit command %q", cmd)
+		}
+		line, err := strconv.Atoi(string(fields[0]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid line number in edit command %q", cmd)
+		}
+		count, err := strconv.Atoi(string(fields[1]))
+		if err != nil || count < 0 {
+			return nil, fmt.Errorf("invalid line count in edit command %q", cmd)
+		}
+
+		switch op {
+		case 'd':
+			start := line - 1
+			if start < pos || start+count > len(base) {
+				return nil, fmt.Errorf("delete of lines %d-%d out of range", line, line+count-1)
+			}
+			result = append(result, base[pos:start]...)
+			pos = start + count
+
+		case 'a':
+			if line < pos || line > len(base) {
+				return nil, fmt.Errorf("append after line %d out of range", line)
+			}
+			if i+1+count > len(cmds) {
+				return nil, fmt.Errorf("append of %d lines after line %d exceeds script", count, line)
+			}
+			result = append(result, base[pos:line]...)
+			pos = line
+			result = append(result, cmds[i+1:i+1+count]...)
+			i += count
+		}
+	}
+
+	return append(result, base[pos:]...), nil
+}
+
+// splitLines splits text into lines, keeping the trailing newline on each
+// line so that joining them reproduces the original text byte for byte.
+func splitLines(text []byte) [][]byte {
+	var lines [][]byte
+	for len(text) > 0 {
+		idx := bytes.IndexByte(text, '\n')
+		if idx < 0 {
+			lines = append(lines, text)
+			break
+		}
+		lines = append(lines, text[:idx+1])
+		text = text[idx+1:]
+	}
+	return lines
+}
+
+// isTrunkRevision reports whether rev is a trunk revision (e.g. 1.3)
+func isTrunkRevision(rev string) bool {
+	return !isBranchNumber(rev)
+}
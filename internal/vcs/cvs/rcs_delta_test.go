@@ -0,0 +1,200 @@
+package cvs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyEditScript_DeleteAndAppend(t *testing.T) {
+	base := splitLines([]byte("one\ntwo\nthree\nfour\n"))
+
+	result, err := applyEditScript(base, []byte("d2 1\na3 2\nthree and a half\nthree and three quarters\n"))
+	require.NoError(t, err)
+	require.Equal(t, "one\nthree\nthree and a half\nthree and three quarters\nfour\n", string(joinLines(result)))
+}
+
+func TestApplyEditScript_ReplaceLine(t *testing.T) {
+	base := splitLines([]byte("a\nb\nc\n"))
+
+	// Replacing a line is a delete followed by an append at the same position
+	result, err := applyEditScript(base, []byte("d2 1\na2 1\nB\n"))
+	require.NoError(t, err)
+	require.Equal(t, "a\nB\nc\n", string(joinLines(result)))
+}
+
+func TestApplyEditScript_AppendAtStart(t *testing.T) {
+	base := splitLines([]byte("b\n"))
+
+	result, err := applyEditScript(base, []byte("a0 1\na\n"))
+	require.NoError(t, err)
+	require.Equal(t, "a\nb\n", string(joinLines(result)))
+}
+
+func TestApplyEditScript_EmptyScript(t *testing.T) {
+	base := splitLines([]byte("unchanged\n"))
+
+	result, err := applyEditScript(base, nil)
+	require.NoError(t, err)
+	require.Equal(t, "unchanged\n", string(joinLines(result)))
+}
+
+func TestApplyEditScript_Errors(t *testing.T) {
+	base := splitLines([]byte("a\nb\n"))
+
+	tests := []struct {
+		name   string
+		script string
+	}{
+		{"unknown command", "x1 1\n"},
+		{"missing count", "d1\n"},
+		{"bad line number", "dX 1\n"},
+		{"bad count", "a1 1debug\n"},
+		{"delete out of range", "d2 5\n"},
+		{"append out of range", "a9 1\nz\n"},
+		{"append past script", "a1 3\nz\n"},
+		{"commands out of order", "d2 1\nd1 1\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := applyEditScript(base, []byte(tt.script))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestSplitLines_KeepsNewlines(t *testing.T) {
+	lines := splitLines([]byte("a\n\nb"))
+	require.Equal(t, [][]byte{[]byte("a\n"), []byte("\n"), []byte("b")}, lines)
+	require.Empty(t, splitLines(nil))
+}
+
+func TestRCSFileContents_TrunkAndBranches(t *testing.T) {
+	input := `head	1.3;
+access;
+symbols
+	DEV:1.2.0.2;
+locks; strict;
+
+1.3
+date	2024.01.03.00.00.00;	author alice;	state Exp;
+branches;
+next	1.2;
+
+1.2
+date	2024.01.02.00.00.00;	author alice;	state Exp;
+branches
+	1.2.2.1;
+next	1.1;
+
+1.1
+date	2024.01.01.00.00.00;	author alice;	state Exp;
+branches;
+next	;
+
+1.2.2.1
+date	2024.01.04.00.00.00;	author bob;	state Exp;
+branches;
+next	1.2.2.2;
+
+1.2.2.2
+date	2024.01.05.00.00.00;	author bob;	state Exp;
+branches;
+next	;
+
+desc
+@@
+
+1.3
+log
+@third@
+text
+@line 1
+line 2 changed
+line 3
+@
+
+1.2
+log
+@second@
+text
+@d2 1
+a2 1
+line 2
+@
+
+1.1
+log
+@first@
+text
+@d3 1
+@
+
+1.2.2.1
+log
+@branch one@
+text
+@a3 1
+branch line
+@
+
+1.2.2.2
+log
+@branch two@
+text
+@d1 1
+a1 1
+LINE 1
+@
+`
+	rcs, err := NewRCSParser(strings.NewReader(input)).Parse()
+	require.NoError(t, err)
+
+	contents, err := rcs.Contents()
+	require.NoError(t, err)
+
+	require.Equal(t, "line 1\nline 2 changed\nline 3\n", string(contents["1.3"]))
+	require.Equal(t, "line 1\nline 2\nline 3\n", string(contents["1.2"]))
+	require.Equal(t, "line 1\nline 2\n", string(contents["1.1"]))
+	require.Equal(t, "line 1\nline 2\nline 3\nbranch line\n", string(contents["1.2.2.1"]))
+	require.Equal(t, "LINE 1\nline 2\nline 3\nbranch line\n", string(contents["1.2.2.2"]))
+}
+
+func TestRCSFileContents_NoHead(t *testing.T) {
+	rcs := &RCSFile{Deltas: map[string]*Delta{}}
+
+	contents, err := rcs.Contents()
+	require.NoError(t, err)
+	require.Empty(t, contents)
+}
+
+func TestRCSFileContents_MissingHeadDelta(t *testing.T) {
+	rcs := &RCSFile{Head: "1.1", Deltas: map[string]*Delta{}}
+
+	_, err := rcs.Contents()
+	require.Error(t, err)
+}
+
+func TestRCSFileContents_InvalidDiff(t *testing.T) {
+	rcs := &RCSFile{
+		Head: "1.2",
+		Deltas: map[string]*Delta{
+			"1.2": {Revision: "1.2", Next: "1.1", Text: "content\n"},
+			"1.1": {Revision: "1.1", Text: "garbage\n"},
+		},
+	}
+
+	_, err := rcs.Contents()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "1.1")
+}
+
+func joinLines(lines [][]byte) []byte {
+	var out []byte
+	for _, l := range lines {
+		out = append(out, l...)
+	}
+	return out
+}
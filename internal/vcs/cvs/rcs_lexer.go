@@ -22,12 +22,14 @@ const (
 
 // Token represents a lexical token
 type Token struct {
-	Type  TokenType
-	Value string
-	Line  int
+	Type   TokenType
+	Value  string
+	Line   int
+	Spaced bool // Whether whitespace preceded the token
 }
 
-// RCSLexer tokenizes RCS file format
+// RCSLexer tokenizes RCS file format. It works on bytes rather than runes
+// so that strings holding binary file contents are kept byte for byte.
 type RCSLexer struct {
 	reader *bufio.Reader
 	line   int
@@ -43,9 +45,14 @@ func NewRCSLexer(r io.Reader) *RCSLexer {
 
 // NextToken returns the next token from the input
 func (l *RCSLexer) NextToken() Token {
-	l.skipWhitespace()
+	spaced := l.skipWhitespace()
+	token := l.nextToken()
+	token.Spaced = spaced
+	return token
+}
 
-	char, _, err := l.reader.ReadRune()
+func (l *RCSLexer) nextToken() Token {
+	char, err := l.reader.ReadByte()
 	if err != nil {
 		return Token{Type: TokenEOF, Line: l.line}
 	}
@@ -59,14 +66,14 @@ func (l *RCSLexer) NextToken() Token {
 		return l.readString()
 	default:
 		if isDigit(char) || (char == '.' && isDigit(l.peekChar())) {
-			if err := l.reader.UnreadRune(); err != nil {
-				log.Printf("Warning: failed to unread rune before reading number: %v", err)
+			if err := l.reader.UnreadByte(); err != nil {
+				log.Printf("Warning: failed to unread byte before reading number: %v", err)
 			}
 			return l.readNumber()
 		}
 		if isAlpha(char) || char == '_' {
-			if err := l.reader.UnreadRune(); err != nil {
-				log.Printf("Warning: failed to unread rune before reading identifier: %v", err)
+			if err := l.reader.UnreadByte(); err != nil {
+				log.Printf("Warning: failed to unread byte before reading identifier: %v", err)
 			}
 			return l.readIdent()
 		}
@@ -75,47 +82,50 @@ func (l *RCSLexer) NextToken() Token {
 	}
 }
 
-func (l *RCSLexer) peekChar() rune {
-	char, _, err := l.reader.ReadRune()
+func (l *RCSLexer) peekChar() byte {
+	char, err := l.reader.ReadByte()
 	if err != nil {
 		return 0
 	}
-	if err := l.reader.UnreadRune(); err != nil {
-		log.Printf("Warning: failed to unread rune in peekChar: %v", err)
+	if err := l.reader.UnreadByte(); err != nil {
+		log.Printf("Warning: failed to unread byte in peekChar: %v", err)
 	}
 	return char
 }
 
-func (l *RCSLexer) skipWhitespace() {
+// skipWhitespace consumes whitespace and reports whether any was found
+func (l *RCSLexer) skipWhitespace() bool {
+	skipped := false
 	for {
-		char, _, err := l.reader.ReadRune()
+		char, err := l.reader.ReadByte()
 		if err != nil {
-			return
+			return skipped
 		}
 		if char == '\n' {
 			l.line++
 		}
 		if !isWhitespace(char) && char != '\n' {
-			if err := l.reader.UnreadRune(); err != nil {
-				log.Printf("Warning: failed to unread rune in skipWhitespace: %v", err)
+			if err := l.reader.UnreadByte(); err != nil {
+				log.Printf("Warning: failed to unread byte in skipWhitespace: %v", err)
 			}
-			return
+			return skipped
 		}
+		skipped = true
 	}
 }
 
 func (l *RCSLexer) readString() Token {
-	var result []rune
+	var result []byte
 
 	for {
-		char, _, err := l.reader.ReadRune()
+		char, err := l.reader.ReadByte()
 		if err != nil {
 			break
 		}
 
 		if char == '@' {
 			// Check for escaped @@
-			next, _, err := l.reader.ReadRune()
+			next, err := l.reader.ReadByte()
 			if err != nil {
 				break
 			}
@@ -124,8 +134,8 @@ func (l *RCSLexer) readString() Token {
 				result = append(result, '@')
 			} else {
 				// End of string - unread the extra character
-				if err := l.reader.UnreadRune(); err != nil {
-					log.Printf("Warning: failed to unread rune in readString: %v", err)
+				if err := l.reader.UnreadByte(); err != nil {
+					log.Printf("Warning: failed to unread byte in readString: %v", err)
 				}
 				break
 			}
@@ -141,18 +151,18 @@ func (l *RCSLexer) readString() Token {
 }
 
 func (l *RCSLexer) readNumber() Token {
-	var result []rune
+	var result []byte
 
 	for {
-		char, _, err := l.reader.ReadRune()
+		char, err := l.reader.ReadByte()
 		if err != nil {
 			break
 		}
 		if isDigit(char) || char == '.' {
 			result = append(result, char)
 		} else {
-			if err := l.reader.UnreadRune(); err != nil {
-				log.Printf("Warning: failed to unread rune in readNumber: %v", err)
+			if err := l.reader.UnreadByte(); err != nil {
+				log.Printf("Warning: failed to unread byte in readNumber: %v", err)
 			}
 			break
 		}
@@ -162,18 +172,18 @@ func (l *RCSLexer) readNumber() Token {
 }
 
 func (l *RCSLexer) readIdent() Token {
-	var result []rune
+	var result []byte
 
 	for {
-		char, _, err := l.reader.ReadRune()
+		char, err := l.reader.ReadByte()
 		if err != nil {
 			break
 		}
 		if isAlpha(char) || isDigit(char) || char == '_' || char == '-' {
 			result = append(result, char)
 		} else {
-			if err := l.reader.UnreadRune(); err != nil {
-				log.Printf("Warning: failed to unread rune in readIdent: %v", err)
+			if err := l.reader.UnreadByte(); err != nil {
+				log.Printf("Warning: failed to unread byte in readIdent: %v", err)
 			}
 			break
 		}
@@ -182,14 +192,14 @@ func (l *RCSLexer) readIdent() Token {
 	return Token{Type: TokenIdent, Value: string(result), Line: l.line}
 }
 
-func isWhitespace(c rune) bool {
+func isWhitespace(c byte) bool {
 	return c == ' ' || c == '\t' || c == '\r'
 }
 
-func isAlpha(c rune) bool {
+func isAlpha(c byte) bool {
 	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
 }
 
-func isDigit(c rune) bool {
+func isDigit(c byte) bool {
 	return c >= '0' && c <= '9'
 }
@@ -1,6 +1,7 @@
 package cvs
 
 import (
+	"bytes"
 	"strings"
 	"testing"
 )
@@ -592,3 +593,39 @@ func TestLexerMixedContent(t *testing.T) {
 		}
 	}
 }
+
+func TestLexerTokenSpaced(t *testing.T) {
+	lexer := NewRCSLexer(strings.NewReader("commitid 4B8F;"))
+
+	want := []struct {
+		value  string
+		spaced bool
+	}{
+		{"commitid", false},
+		{"4", true},
+		{"B8F", false},
+		{";", false},
+	}
+	for _, w := range want {
+		token := lexer.NextToken()
+		if token.Value != w.value || token.Spaced != w.spaced {
+			t.Errorf("token = (%q, %v), want (%q, %v)", token.Value, token.Spaced, w.value, w.spaced)
+		}
+	}
+}
+
+func TestLexerStringWithBinary(t *testing.T) {
+	// Invalid UTF-8, NUL and CR bytes must survive unchanged
+	data := []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x00, 0xff, 0xfe, '@', '@', 0xc3}
+	input := append(append([]byte{'@'}, data...), '@', ';')
+	lexer := NewRCSLexer(bytes.NewReader(input))
+
+	token := lexer.NextToken()
+	expected := []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x00, 0xff, 0xfe, '@', 0xc3}
+	if token.Type != TokenString || token.Value != string(expected) {
+		t.Errorf("token = %v %q, want string %q", token.Type, token.Value, expected)
+	}
+	if token := lexer.NextToken(); token.Type != TokenSemicolon {
+		t.Errorf("token type = %v, want TokenSemicolon", token.Type)
+	}
+}
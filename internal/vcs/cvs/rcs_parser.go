@@ -45,9 +45,10 @@ func parseRCSDate(s string) time.Time {
 // Parse executes the main parsing logic
 func (p *RCSParser) Parse() (*RCSFile, error) {
 	rcs := &RCSFile{
-		Deltas:  make(map[string]*Delta),
-		Symbols: make(map[string]string),
-		Locks:   make(map[string]string),
+		Deltas:     make(map[string]*Delta),
+		Symbols:    make(map[string]string),
+		Locks:      make(map[string]string),
+		Newphrases: make(map[string]string),
 	}
 
 	// Parse header
@@ -140,10 +141,22 @@ func (p *RCSParser) parseHeader(rcs *RCSFile) {
 			}
 			p.skipSemicolon()
 
-		default:
-			// Unknown field - could be start of deltas or desc
-			// Don't consume the token, let outer loop handle it
+		case "expand":
+			p.advance()
+			if p.token.Type == TokenString {
+				rcs.Expand = p.token.Value
+				p.advance()
+			}
+			p.skipSemicolon()
+
+		case "desc":
+			// Start of the description, let outer loop handle it
 			return
+
+		default:
+			// Newphrase, e.g. fields added by later RCS or CVS versions
+			name, value := p.parseNewphrase()
+			rcs.Newphrases[name] = value
 		}
 
 		// Check if we've hit a revision number (start of deltas)
@@ -160,6 +173,26 @@ func (p *RCSParser) skipSemicolon() {
 	}
 }
 
+// parseNewphrase parses an unknown "id word* ;" field and returns its name
+// and value. Words not separated by whitespace are joined, since values like
+// commitids mix digits and letters which the lexer splits into tokens.
+func (p *RCSParser) parseNewphrase() (string, string) {
+	name := p.token.Value
+	p.advance()
+
+	var value strings.Builder
+	for p.token.Type != TokenEOF && p.token.Type != TokenSemicolon {
+		if p.token.Spaced && value.Len() > 0 {
+			value.WriteByte(' ')
+		}
+		value.WriteString(p.token.Value)
+		p.advance()
+	}
+	p.skipSemicolon()
+
+	return name, value.String()
+}
+
 // parseDeltas parses delta nodes (revision metadata)
 func (p *RCSParser) parseDeltas(rcs *RCSFile) {
 	for p.token.Type != TokenEOF {
@@ -231,13 +264,15 @@ func (p *RCSParser) parseDeltas(rcs *RCSFile) {
 					p.skipSemicolon()
 
 				default:
-					// Unknown field - skip it and its value
-					p.advance()
-					// Skip to semicolon
-					for p.token.Type != TokenEOF && p.token.Type != TokenSemicolon {
-						p.advance()
+					// Newphrase, e.g. the commitid written by CVS 1.12+
+					name, value := p.parseNewphrase()
+					if delta.Newphrases == nil {
+						delta.Newphrases = make(map[string]string)
+					}
+					delta.Newphrases[name] = value
+					if name == "commitid" {
+						delta.CommitID = value
 					}
-					p.skipSemicolon()
 				}
 			} else {
 				p.advance()
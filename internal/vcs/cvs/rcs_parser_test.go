@@ -527,6 +527,70 @@ desc @@;`
 	}
 }
 
+func TestParserHeaderNewphrase(t *testing.T) {
+	input := "head 1.5; integrity @ok@; branch 1.5.1;"
+	parser := NewRCSParser(strings.NewReader(input))
+
+	rcs, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if got := rcs.Newphrases["integrity"]; got != "ok" {
+		t.Errorf("Newphrases[integrity] = %q, want %q", got, "ok")
+	}
+	if rcs.Branch != "1.5.1" {
+		t.Errorf("Branch = %q, want %q", rcs.Branch, "1.5.1")
+	}
+}
+
+func TestParserDeltaCommitID(t *testing.T) {
+	input := `head 1.2;
+1.2
+date 2024.1.15.12.30.0; author test; state Exp;
+branches;
+next 1.1;
+commitid 4B8F2A1C0D3E5F67;
+1.1
+date 2024.1.14.12.30.0; author test; state Exp;
+branches;
+next ;
+owner alice group; 
+desc @@;`
+
+	parser := NewRCSParser(strings.NewReader(input))
+
+	rcs, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	delta := rcs.Deltas["1.2"]
+	if delta == nil {
+		t.Fatal("Delta 1.2 not found")
+	}
+	if delta.CommitID != "4B8F2A1C0D3E5F67" {
+		t.Errorf("CommitID = %q, want %q", delta.CommitID, "4B8F2A1C0D3E5F67")
+	}
+	if delta.Newphrases["commitid"] != "4B8F2A1C0D3E5F67" {
+		t.Errorf("Newphrases[commitid] = %q, want %q", delta.Newphrases["commitid"], "4B8F2A1C0D3E5F67")
+	}
+	if delta.Next != "1.1" {
+		t.Errorf("Next = %q, want %q", delta.Next, "1.1")
+	}
+
+	older := rcs.Deltas["1.1"]
+	if older == nil {
+		t.Fatal("Delta 1.1 not found")
+	}
+	if older.CommitID != "" {
+		t.Errorf("CommitID = %q, want empty", older.CommitID)
+	}
+	if older.Newphrases["owner"] != "alice group" {
+		t.Errorf("Newphrases[owner] = %q, want %q", older.Newphrases["owner"], "alice group")
+	}
+}
+
 func TestParserDeltaWithoutDate(t *testing.T) {
 	input := `head 1.5;
 1.5
@@ -729,3 +793,22 @@ func TestParserAdvance(t *testing.T) {
 		t.Errorf("After advance, token = %v %q, want Number '1.5'", parser.token.Type, parser.token.Value)
 	}
 }
+
+func TestParserExpand(t *testing.T) {
+	parser := NewRCSParser(strings.NewReader("head 1.1; access; symbols; locks; strict;\ncomment @# @;\nexpand @b@;\n"))
+	rcs, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if rcs.Expand != "b" || !rcs.IsBinary() {
+		t.Errorf("Expand = %q, IsBinary = %v, want b, true", rcs.Expand, rcs.IsBinary())
+	}
+	if _, ok := rcs.Newphrases["expand"]; ok {
+		t.Error("expand should not be recorded as a newphrase")
+	}
+
+	parser = NewRCSParser(strings.NewReader("head 1.1; expand @kv@;"))
+	if rcs, _ := parser.Parse(); rcs.IsBinary() {
+		t.Error("kv files are not binary")
+	}
+}
@@ -7,6 +7,8 @@ import (
 
 // RCSFile represents a parsed RCS file
 type RCSFile struct {
+	Path        string // Working file path relative to the repository root
+	RCSPath     string // Slash-separated path of the RCS file relative to the repository root
 	Head        string
 	Branch      string
 	Access      []string
@@ -14,21 +16,25 @@ type RCSFile struct {
 	Locks       map[string]string
 	StrictLocks bool
 	Comment     string
+	Expand      string // Keyword expansion mode, e.g. "b" for binary files; empty means "kv"
 	Description string
 	Deltas      map[string]*Delta
-	DeltaOrder  []string // Order of deltas as they appear
+	DeltaOrder  []string          // Order of deltas as they appear
+	Newphrases  map[string]string // Unrecognized header fields
 }
 
 // Delta represents a single revision in an RCS file
 type Delta struct {
-	Revision string
-	Date     time.Time
-	Author   string
-	State    string
-	Branches []string
-	Next     string
-	Log      string
-	Text     string
+	Revision   string
+	Date       time.Time
+	Author     string
+	State      string
+	Branches   []string
+	Next       string
+	CommitID   string            // CVS 1.12+ commitid, shared by all files of a commit
+	Newphrases map[string]string // Unrecognized delta fields, including commitid
+	Log        string
+	Text       string
 }
 
 // Commit represents a commit extracted from RCS deltas
@@ -38,6 +44,15 @@ type Commit struct {
 	Date     time.Time
 	Message  string
 	Branch   string // Empty for trunk
+	State    string // "dead" when the revision removes the file
+	CommitID string // Empty for repositories written before CVS 1.12
+}
+
+// IsBinary reports whether the file was added with -kb, which makes CVS
+// check it out byte for byte, without keyword expansion or line-ending
+// conversion
+func (r *RCSFile) IsBinary() bool {
+	return r.Expand == "b"
 }
 
 // GetCommits returns commits in reverse chronological order
@@ -65,19 +80,18 @@ func (r *RCSFile) GetCommits() []*Commit {
 			Date:     delta.Date,
 			Message:  delta.Log,
 			Branch:   branch,
+			State:    delta.State,
+			CommitID: delta.CommitID,
 		})
 
 		// Add branches from this commit
 		for _, branchRev := range delta.Branches {
-			// Find branch symbol
-			branchName := ""
-			for sym, symRev := range r.Symbols {
-				if symRev == branchRev || isBranchPrefix(symRev, branchRev) {
-					branchName = sym
-					break
-				}
+			name := r.branchName(branchRev)
+			if name == "" {
+				// Keep commits of branches without a symbol off the trunk
+				name = "unlabeled-" + branchNumber(branchRev)
 			}
-			addCommit(branchRev, branchName)
+			addCommit(branchRev, name)
 		}
 
 		// Add next (previous revision)
@@ -94,25 +108,111 @@ func (r *RCSFile) GetCommits() []*Commit {
 func (r *RCSFile) GetBranches() []string {
 	var branches []string
 	for sym, rev := range r.Symbols {
-		// Branch numbers have odd number of components (e.g., 1.2.0.2)
-		if isBranchNumber(rev) {
+		if isSymbolBranch(rev) {
 			branches = append(branches, sym)
 		}
 	}
 	return branches
 }
 
-// GetTags returns the list of tag names (symbols pointing to trunk revisions)
+// GetTags returns the list of tag names (symbols pointing to revisions)
 func (r *RCSFile) GetTags() map[string]string {
 	tags := make(map[string]string)
 	for sym, rev := range r.Symbols {
-		if !isBranchNumber(rev) {
+		if !isSymbolBranch(rev) {
 			tags[sym] = rev
 		}
 	}
 	return tags
 }
 
+// branchName returns the symbolic name of the branch a branch revision
+// lives on, or an empty string if the branch has no name
+func (r *RCSFile) branchName(rev string) string {
+	branch := branchNumber(rev)
+	magic := ""
+	if idx := strings.LastIndex(branch, "."); idx >= 0 {
+		// CVS records branch 1.2.2 as the magic number 1.2.0.2
+		magic = branch[:idx] + ".0" + branch[idx:]
+	}
+
+	name := ""
+	for sym, symRev := range r.Symbols {
+		if symRev == magic || symRev == branch {
+			// Pick deterministically when a branch has several names
+			if name == "" || sym < name {
+				name = sym
+			}
+		}
+	}
+	return name
+}
+
+// branchPoints maps every named branch to the revision it sprouts from
+func (r *RCSFile) branchPoints() map[string]string {
+	points := make(map[string]string)
+	for sym, rev := range r.Symbols {
+		parts := strings.Split(rev, ".")
+		switch {
+		case len(parts) >= 4 && len(parts)%2 == 0 && parts[len(parts)-2] == "0":
+			// Magic branch number, e.g. 1.2.0.2 sprouts from 1.2
+			points[sym] = strings.Join(parts[:len(parts)-2], ".")
+		case len(parts) >= 3 && len(parts)%2 == 1:
+			// Plain branch number, e.g. vendor branch 1.1.1 sprouts from 1.1
+			points[sym] = strings.Join(parts[:len(parts)-1], ".")
+		}
+	}
+	return points
+}
+
+// predecessors maps every revision to the revision it was derived from.
+// Trunk revisions derive from the older revision their "next" points to,
+// the first revision on a branch derives from its branch point, and later
+// branch revisions derive from the branch revision before them.
+func (r *RCSFile) predecessors() map[string]string {
+	preds := make(map[string]string)
+	for rev, delta := range r.Deltas {
+		if delta.Next != "" {
+			if isBranchNumber(rev) {
+				preds[delta.Next] = rev
+			} else {
+				preds[rev] = delta.Next
+			}
+		}
+		for _, branchRev := range delta.Branches {
+			preds[branchRev] = rev
+		}
+	}
+	return preds
+}
+
+// branchNumber returns the branch a revision lives on (e.g. 1.2.2 for 1.2.2.1)
+func branchNumber(rev string) string {
+	idx := strings.LastIndex(rev, ".")
+	if idx < 0 {
+		return ""
+	}
+	return rev[:idx]
+}
+
+// isDead reports whether a revision state marks the file as removed
+func isDead(state string) bool {
+	return state == "dead"
+}
+
+// isSymbolBranch reports whether a symbol names a branch rather than a
+// tag. Branches are recorded as magic numbers such as 1.2.0.2, or as plain
+// branch numbers with an odd number of components such as the vendor
+// branch 1.1.1. Symbols of revisions are tags, whether the revision is on
+// the trunk or on a branch like 1.2.2.1.
+func isSymbolBranch(rev string) bool {
+	parts := strings.Split(rev, ".")
+	if len(parts) >= 4 && len(parts)%2 == 0 && parts[len(parts)-2] == "0" {
+		return true
+	}
+	return len(parts) >= 3 && len(parts)%2 == 1
+}
+
 func isBranchNumber(rev string) bool {
 	// Magic branch numbers have ".0." in them (e.g., 1.2.0.2)
 	// Regular branch commits have 4+ components without .0. (e.g., 1.2.2.1)
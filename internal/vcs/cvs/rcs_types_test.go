@@ -1,6 +1,8 @@
 package cvs
 
 import (
+	"reflect"
+	"sort"
 	"testing"
 	"time"
 )
@@ -261,16 +263,26 @@ func TestRCSFileGetBranchesWithMagicNumbers(t *testing.T) {
 func TestRCSFileGetBranchesWithBranchRevisions(t *testing.T) {
 	rcs := &RCSFile{
 		Symbols: map[string]string{
-			"DEV":     "1.2.2.1", // 4 components - branch revision
-			"FEATURE": "1.3.4.5", // 4 components - branch revision
-			"REL":     "1.5",     // 2 components - trunk tag
+			"DEV_TAG":  "1.2.2.1", // 4 components - tag of a branch revision
+			"FIX_TAG":  "1.3.4.5", // 4 components - tag of a branch revision
+			"REL":      "1.5",     // 2 components - trunk tag
+			"VENDOR":   "1.1.1",   // 3 components - vendor branch
+			"NESTED":   "1.2.2.1.0.2",
+			"IMPORTED": "1.1.1.1", // Tag of a vendor branch revision
 		},
 	}
 
 	branches := rcs.GetBranches()
+	sort.Strings(branches)
+	if want := []string{"NESTED", "VENDOR"}; !reflect.DeepEqual(branches, want) {
+		t.Errorf("GetBranches() = %v, want %v", branches, want)
+	}
 
-	if len(branches) != 2 {
-		t.Errorf("GetBranches returned %d branches, want 2", len(branches))
+	tags := rcs.GetTags()
+	for _, tag := range []string{"DEV_TAG", "FIX_TAG", "REL", "IMPORTED"} {
+		if _, ok := tags[tag]; !ok {
+			t.Errorf("Expected tag %q not found", tag)
+		}
 	}
 }
 
@@ -320,7 +332,7 @@ func TestRCSFileGetTagsMixed(t *testing.T) {
 			"REL_1_0": "1.5",     // Tag (trunk)
 			"DEV":     "1.2.0.2", // Branch (magic number)
 			"REL_2_0": "1.10",    // Tag (trunk)
-			"FEATURE": "1.3.4.1", // Branch (4 components)
+			"FEATURE": "1.3.0.4", // Branch (magic number)
 		},
 	}
 
@@ -570,3 +582,105 @@ func TestGetCommitsBranchSymbolMatching(t *testing.T) {
 		t.Errorf("Branch commit revision = %q, want %q", branchCommit.Revision, "1.2.2.1")
 	}
 }
+
+func TestRCSFileBranchName(t *testing.T) {
+	rcs := &RCSFile{
+		Symbols: map[string]string{
+			"DEV":     "1.2.0.2",
+			"VENDOR":  "1.1.1",
+			"RELEASE": "1.3",
+		},
+	}
+
+	if got := rcs.branchName("1.2.2.1"); got != "DEV" {
+		t.Errorf("branchName(1.2.2.1) = %q, want %q", got, "DEV")
+	}
+	if got := rcs.branchName("1.1.1.1"); got != "VENDOR" {
+		t.Errorf("branchName(1.1.1.1) = %q, want %q", got, "VENDOR")
+	}
+	if got := rcs.branchName("1.3.2.1"); got != "" {
+		t.Errorf("branchName(1.3.2.1) = %q, want empty", got)
+	}
+}
+
+func TestRCSFilePredecessors(t *testing.T) {
+	rcs := &RCSFile{
+		Head: "1.2",
+		Deltas: map[string]*Delta{
+			"1.2":     {Revision: "1.2", Next: "1.1", Branches: []string{"1.2.2.1"}},
+			"1.1":     {Revision: "1.1"},
+			"1.2.2.1": {Revision: "1.2.2.1", Next: "1.2.2.2"},
+			"1.2.2.2": {Revision: "1.2.2.2"},
+		},
+	}
+
+	preds := rcs.predecessors()
+	expected := map[string]string{
+		"1.2":     "1.1",
+		"1.2.2.1": "1.2",
+		"1.2.2.2": "1.2.2.1",
+	}
+	for rev, want := range expected {
+		if preds[rev] != want {
+			t.Errorf("predecessor of %s = %q, want %q", rev, preds[rev], want)
+		}
+	}
+	if preds["1.1"] != "" {
+		t.Errorf("predecessor of 1.1 = %q, want empty", preds["1.1"])
+	}
+}
+
+func TestRCSFileGetCommitsCommitID(t *testing.T) {
+	rcs := &RCSFile{
+		Head: "1.1",
+		Deltas: map[string]*Delta{
+			"1.1": {Revision: "1.1", Author: "alice", CommitID: "4B8F2A1C0D3E5F67"},
+		},
+	}
+
+	commits := rcs.GetCommits()
+	if len(commits) != 1 {
+		t.Fatalf("len(commits) = %d, want 1", len(commits))
+	}
+	if commits[0].CommitID != "4B8F2A1C0D3E5F67" {
+		t.Errorf("CommitID = %q, want %q", commits[0].CommitID, "4B8F2A1C0D3E5F67")
+	}
+}
+
+func TestRCSFileBranchPoints(t *testing.T) {
+	rcs := &RCSFile{
+		Symbols: map[string]string{
+			"DEV":     "1.2.0.2",
+			"NESTED":  "1.2.2.1.0.2",
+			"VENDOR":  "1.1.1",
+			"RELEASE": "1.3",
+		},
+	}
+
+	points := rcs.branchPoints()
+	want := map[string]string{"DEV": "1.2", "NESTED": "1.2.2.1", "VENDOR": "1.1"}
+	if len(points) != len(want) {
+		t.Fatalf("branchPoints() = %v, want %v", points, want)
+	}
+	for name, rev := range want {
+		if points[name] != rev {
+			t.Errorf("branchPoints()[%s] = %q, want %q", name, points[name], rev)
+		}
+	}
+}
+
+func TestRCSFileGetCommitsUnlabeledBranch(t *testing.T) {
+	rcs := &RCSFile{
+		Head: "1.1",
+		Deltas: map[string]*Delta{
+			"1.1":     {Revision: "1.1", Branches: []string{"1.1.2.1"}},
+			"1.1.2.1": {Revision: "1.1.2.1"},
+		},
+	}
+
+	for _, c := range rcs.GetCommits() {
+		if c.Revision == "1.1.2.1" && c.Branch != "unlabeled-1.1.2" {
+			t.Errorf("Branch = %q, want %q", c.Branch, "unlabeled-1.1.2")
+		}
+	}
+}
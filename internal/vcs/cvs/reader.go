@@ -6,7 +6,9 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/adamf123git/git-migrator/internal/vcs"
 )
@@ -25,10 +27,35 @@ type ValidationResult struct {
 	Infos    []ValidationMessage
 }
 
+// ReaderOptions configures how a Reader reconstructs history
+type ReaderOptions struct {
+	// FuzzWindow is the maximum gap between file revisions of one commit
+	FuzzWindow time.Duration
+	// Keywords configures how RCS keywords are written to file contents
+	Keywords KeywordOptions
+	// VendorBranch names the branch that vendor branch revisions, such as
+	// those of cvs import, are also written to. Without it, imports that
+	// were the default branch of a file are written to the trunk only.
+	VendorBranch string
+	// Module selects a module of CVSROOT/modules, or a directory or file of
+	// the repository, to migrate. Paths are written relative to the module
+	// root. Without it the whole repository is migrated.
+	Module string
+	// ExcludeModules lists modules, directories or files left out
+	ExcludeModules []string
+}
+
 // Reader implements VCSReader for CVS repositories
 type Reader struct {
-	path     string
-	rcsFiles []*RCSFile
+	path         string
+	options      ReaderOptions
+	rcsFiles     []*RCSFile
+	commits      []*vcs.Commit
+	branchPoints map[string]string      // Branch name -> revision of the commit it forks from
+	tags         map[string]string      // Tag name -> revision of the tagged commit
+	tagFixups    map[string]*vcs.Commit // Synthetic commits for tags by revision
+	tagDates     map[string]time.Time   // Tag name -> date of its latest file revision or rtag
+	history      *History
 	// info caches repository metadata for performance optimization.
 	// Reserved for future use to avoid repeated filesystem calls when
 	// accessing repository information such as branch counts, file counts,
@@ -40,7 +67,15 @@ type Reader struct {
 
 // NewReader creates a new CVS repository reader
 func NewReader(path string) *Reader {
-	return &Reader{path: path}
+	return NewReaderWithOptions(path, ReaderOptions{})
+}
+
+// NewReaderWithOptions creates a CVS repository reader with custom options
+func NewReaderWithOptions(path string, options ReaderOptions) *Reader {
+	if options.FuzzWindow <= 0 {
+		options.FuzzWindow = DefaultFuzzWindow
+	}
+	return &Reader{path: path, options: options}
 }
 
 // Validate checks if the repository is valid and accessible
@@ -57,36 +92,197 @@ func (r *Reader) Validate() error {
 
 // GetCommits returns an iterator over all commits
 func (r *Reader) GetCommits() (vcs.CommitIterator, error) {
-	if err := r.loadRCSFiles(); err != nil {
+	if err := r.loadCommits(); err != nil {
 		return nil, err
 	}
+	return &cvsCommitIterator{commits: r.commits}, nil
+}
+
+// loadCommits reconstructs every file revision and groups the revisions
+// into commits, resolving branch points and tags along the way. The
+// commits are kept, so the repository is only read once.
+func (r *Reader) loadCommits() error {
+	if r.commits != nil {
+		return nil
+	}
+	if err := r.loadRCSFiles(); err != nil {
+		return err
+	}
 
-	// Collect all commits from all RCS files
-	var allCommits []*vcs.Commit
-	seen := make(map[string]bool) // Track commits by revision+author+date
+	root, err := filepath.Abs(r.path)
+	if err != nil {
+		root = r.path
+	}
 
+	// Collect every revision of every RCS file, noting which file revisions
+	// branches sprout from
+	var revisions []*fileRevision
+	pointsAt := make(map[string][]string)
+	tagged := make(map[string][]tagRevision)
 	for _, rcs := range r.rcsFiles {
-		commits := rcs.GetCommits()
-		for _, c := range commits {
-			// Create a unique key for deduplication
-			key := fmt.Sprintf("%s|%s|%d", c.Revision, c.Author, c.Date.Unix())
-			if !seen[key] {
-				seen[key] = true
-				allCommits = append(allCommits, &vcs.Commit{
-					Revision: c.Revision,
-					Author:   c.Author,
-					Date:     c.Date,
-					Message:  c.Message,
-					Branch:   c.Branch,
-				})
+		// Contents are the bytes stored in the RCS file, which binary
+		// (-kb) files must keep unchanged
+		contents, err := rcs.Contents()
+		if err != nil {
+			return fmt.Errorf("failed to reconstruct contents of %s: %w", rcs.Path, err)
+		}
+		preds := rcs.predecessors()
+		keywords := r.options.Keywords.modeOf(rcs.Path)
+
+		// Vendor revisions that were the default branch belong on the
+		// trunk, replacing the "Initial revision" of an import
+		vendor := rcs.defaultBranchRevisions()
+		onTrunk := make(map[string]bool, len(vendor))
+		for _, rev := range vendor {
+			onTrunk[rev] = true
+		}
+		initial := rcs.initialImport(vendor, contents)
+		if len(vendor) > 0 {
+			if next := rcs.trunkSuccessor(branchNumber(branchNumber(vendor[0]))); next != "" {
+				preds[next] = vendor[len(vendor)-1]
+			}
+		}
+		replaced := func(rev string) string {
+			if rev != "" && rev == initial {
+				return vendor[0]
+			}
+			return rev
+		}
+		for rev, pred := range preds {
+			if pred != "" && pred == initial {
+				preds[rev] = replaced(pred)
+			}
+		}
+		if initial != "" {
+			delete(preds, vendor[0])
+		}
+
+		for branch, rev := range rcs.branchPoints() {
+			if r.options.VendorBranch != "" && isVendorBranch(rcs.Symbols[branch]) {
+				continue
+			}
+			key := rcs.Path + ":" + replaced(rev)
+			pointsAt[key] = append(pointsAt[key], branch)
+		}
+		for tag, rev := range rcs.GetTags() {
+			tagged[tag] = append(tagged[tag], tagRevision{path: rcs.Path, revision: replaced(rev)})
+		}
+
+		for _, c := range rcs.GetCommits() {
+			if c.Revision == initial {
+				continue
+			}
+			action, ok := revisionAction(rcs, c.State, preds[c.Revision])
+			if !ok {
+				continue
+			}
+			content, ok := contents[c.Revision]
+			if ok {
+				content = rcs.applyKeywords(content, c.Revision, keywords, root)
+			}
+			branch := c.Branch
+			switch {
+			case onTrunk[c.Revision]:
+				branch = ""
+			case r.options.VendorBranch != "" && isVendorBranch(branchNumber(c.Revision)):
+				branch = r.options.VendorBranch
+			}
+			rev := &fileRevision{
+				path:        rcs.Path,
+				revision:    c.Revision,
+				predecessor: preds[c.Revision],
+				author:      c.Author,
+				date:        c.Date,
+				message:     c.Message,
+				branch:      branch,
+				commitID:    c.CommitID,
+				action:      action,
+				content:     content,
+				hasContent:  ok,
+			}
+			revisions = append(revisions, rev)
+
+			// Keep imports on the vendor branch as well, after the trunk
+			if onTrunk[c.Revision] && r.options.VendorBranch != "" {
+				vendorCopy := *rev
+				vendorCopy.branch = r.options.VendorBranch
+				vendorCopy.predecessor = c.Revision
+				vendorCopy.vendorCopy = true
+				revisions = append(revisions, &vendorCopy)
 			}
 		}
 	}
 
-	// Sort commits by date (oldest first for proper application)
-	sortCommitsByDate(allCommits)
+	// Group file revisions into changesets and order them for application
+	changesets := orderChangesets(groupChangesets(revisions, r.options.FuzzWindow))
+	allCommits := make([]*vcs.Commit, len(changesets))
+
+	// A branch forks from the latest commit before its first own commit
+	// that contains the revision it sprouts from in any file
+	r.branchPoints = make(map[string]string)
+	started := make(map[string]bool)
+	for i, cs := range changesets {
+		allCommits[i] = cs.toCommit()
+		if cs.branch != "" && !started[cs.branch] {
+			started[cs.branch] = true
+			allCommits[i].Parent = r.branchPoints[cs.branch]
+		}
+		for _, rev := range cs.revisions {
+			for _, branch := range pointsAt[rev.path+":"+rev.revision] {
+				r.branchPoints[branch] = cs.id
+			}
+		}
+	}
+	r.tags, r.tagFixups = resolveTags(changesets, tagged, r.branchPoints)
+	r.tagDates = tagDates(revisions, tagged)
+
+	// A tag is at least as old as its revisions; the rtag that applied it,
+	// when CVSROOT/history recorded one, dates it more closely
+	if history, err := r.GetHistory(); err != nil {
+		log.Printf("Warning: failed to read CVS history: %v", err)
+	} else {
+		for name, applied := range history.TagDates() {
+			if date, ok := r.tagDates[name]; ok && applied.After(date) {
+				r.tagDates[name] = applied
+			}
+		}
+	}
 
-	return &cvsCommitIterator{commits: allCommits}, nil
+	r.commits = allCommits
+	return nil
+}
+
+// revisionAction determines how a file revision changes the working tree.
+// A dead revision deletes a file that existed before it, while a live
+// revision adds the file when there is no live revision before it, as when
+// a removed file is resurrected. Dead revisions of files that did not exist,
+// such as the placeholder 1.1 of a file first added on a branch, have no
+// effect and are reported as not ok.
+func revisionAction(rcs *RCSFile, state, pred string) (vcs.Action, bool) {
+	existed := false
+	if delta := rcs.Deltas[pred]; pred != "" && delta != nil {
+		existed = !isDead(delta.State)
+	}
+
+	switch {
+	case isDead(state) && existed:
+		return vcs.ActionDelete, true
+	case isDead(state):
+		return vcs.ActionDelete, false
+	case existed:
+		return vcs.ActionModify, true
+	default:
+		return vcs.ActionAdd, true
+	}
+}
+
+// GetBranchPoints returns a map of branch names to the revision identifier
+// of the commit each branch was forked from
+func (r *Reader) GetBranchPoints() (map[string]string, error) {
+	if err := r.loadCommits(); err != nil {
+		return nil, err
+	}
+	return r.branchPoints, nil
 }
 
 // GetBranches returns a list of branch names
@@ -98,6 +294,11 @@ func (r *Reader) GetBranches() ([]string, error) {
 	branchSet := make(map[string]bool)
 	for _, rcs := range r.rcsFiles {
 		for _, branch := range rcs.GetBranches() {
+			// Vendor branches are written to the configured vendor branch
+			if r.options.VendorBranch != "" && isVendorBranch(rcs.Symbols[branch]) {
+				branchSet[r.options.VendorBranch] = true
+				continue
+			}
 			branchSet[branch] = true
 		}
 	}
@@ -109,19 +310,44 @@ func (r *Reader) GetBranches() ([]string, error) {
 	return branches, nil
 }
 
-// GetTags returns a map of tag names to revision identifiers
+// GetTags returns a map of tag names to the revision identifier of the
+// commit, or tag fixup commit, that contains every tagged file revision
 func (r *Reader) GetTags() (map[string]string, error) {
-	if err := r.loadRCSFiles(); err != nil {
+	if err := r.loadCommits(); err != nil {
 		return nil, err
 	}
+	return r.tags, nil
+}
 
-	allTags := make(map[string]string)
-	for _, rcs := range r.rcsFiles {
-		for name, rev := range rcs.GetTags() {
-			allTags[name] = rev
+// GetTagFixups returns the synthetic commits needed by tags whose file
+// revisions never coexisted in a single commit, keyed by revision identifier
+func (r *Reader) GetTagFixups() (map[string]*vcs.Commit, error) {
+	if err := r.loadCommits(); err != nil {
+		return nil, err
+	}
+	return r.tagFixups, nil
+}
+
+// GetTagDates returns the date of every tag: when CVSROOT/history recorded
+// the rtag that applied it, or else the date of its latest file revision
+func (r *Reader) GetTagDates() (map[string]time.Time, error) {
+	if err := r.loadCommits(); err != nil {
+		return nil, err
+	}
+	return r.tagDates, nil
+}
+
+// GetHistory returns the records of CVSROOT/history and the tags of
+// CVSROOT/val-tags
+func (r *Reader) GetHistory() (*History, error) {
+	if r.history == nil {
+		history, err := ReadHistory(filepath.Join(r.path, "CVSROOT"))
+		if err != nil {
+			return nil, err
 		}
+		r.history = history
 	}
-	return allTags, nil
+	return r.history, nil
 }
 
 // Close releases any resources
@@ -135,21 +361,34 @@ func (r *Reader) loadRCSFiles() error {
 		return nil // Already loaded
 	}
 
-	// Find all ,v files (RCS files)
-	err := filepath.Walk(r.path, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil // Skip errors
-		}
-		if info.IsDir() {
-			// Skip CVSROOT directory
-			if filepath.Base(path) == "CVSROOT" {
-				return filepath.SkipDir
+	selection, err := selectModules(r.path, r.options.Module, r.options.ExcludeModules)
+	if err != nil {
+		return err
+	}
+
+	// Find the ,v files (RCS files) of the selected modules
+	for _, root := range selection.roots() {
+		err := filepath.Walk(filepath.Join(r.path, filepath.FromSlash(root)), func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return nil // Skip errors
+			}
+			if info.IsDir() {
+				// Skip CVSROOT directory
+				if filepath.Base(path) == "CVSROOT" {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			// Check if it's an RCS file (ends with ,v)
+			if !strings.HasSuffix(path, ",v") {
+				return nil
+			}
+			working, ok := selection.workingPath(r.workingPath(path))
+			if !ok {
+				return nil // Not part of the module
 			}
-			return nil
-		}
 
-		// Check if it's an RCS file (ends with ,v)
-		if strings.HasSuffix(path, ",v") {
 			file, err := os.Open(path)
 			if err != nil {
 				return nil // Skip files we can't read
@@ -165,14 +404,36 @@ func (r *Reader) loadRCSFiles() error {
 			if err != nil {
 				return nil // Skip files we can't parse
 			}
+			rcs.Path = working
+			if rel, err := filepath.Rel(r.path, path); err == nil {
+				rcs.RCSPath = filepath.ToSlash(rel)
+			}
 
 			r.rcsFiles = append(r.rcsFiles, rcs)
+			return nil
+		})
+		if err != nil {
+			return err
 		}
+	}
 
-		return nil
-	})
+	return nil
+}
 
-	return err
+// workingPath converts the path of an RCS file into the slash-separated
+// path of the working file it describes, relative to the repository root.
+// CVS moves files removed from the trunk into an Attic subdirectory, which
+// is not part of the working file path.
+func (r *Reader) workingPath(rcsPath string) string {
+	rel, err := filepath.Rel(r.path, rcsPath)
+	if err != nil {
+		rel = filepath.Base(rcsPath)
+	}
+	dir, file := filepath.Split(rel)
+	if filepath.Base(dir) == "Attic" {
+		rel = filepath.Join(filepath.Dir(filepath.Clean(dir)), file)
+	}
+	return filepath.ToSlash(strings.TrimSuffix(rel, ",v"))
 }
 
 // cvsCommitIterator implements CommitIterator for CVS
@@ -199,13 +460,9 @@ func (i *cvsCommitIterator) Err() error {
 
 // sortCommitsByDate sorts commits chronologically (oldest first)
 func sortCommitsByDate(commits []*vcs.Commit) {
-	for i := 0; i < len(commits)-1; i++ {
-		for j := i + 1; j < len(commits); j++ {
-			if commits[i].Date.After(commits[j].Date) {
-				commits[i], commits[j] = commits[j], commits[i]
-			}
-		}
-	}
+	sort.SliceStable(commits, func(i, j int) bool {
+		return commits[i].Date.Before(commits[j].Date)
+	})
 }
 
 // Validator validates CVS repositories
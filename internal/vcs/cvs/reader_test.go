@@ -3,6 +3,7 @@ package cvs
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -188,7 +189,10 @@ text
 log
 @Initial revision@
 text
-@content@
+@d1 1
+a1 1
+content
+@
 `
 	rcsFile := filepath.Join(dir, "file.txt,v")
 	require.NoError(t, os.WriteFile(rcsFile, []byte(rcsContent), 0644))
@@ -201,6 +205,25 @@ text
 	require.Contains(t, tags, "RELEASE_1_1")
 }
 
+func TestGetCommits_ReadsRepositoryOnce(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "CVSROOT"), 0755))
+	rcsFile := filepath.Join(dir, "a.txt,v")
+	writeRCSFile(t, rcsFile, "alice", "2024.01.01.10.00.00", "Import", "a\n")
+
+	r := NewReader(dir)
+	_, err := r.GetTags()
+	require.NoError(t, err)
+
+	// Commits built for the tags are reused rather than read again
+	require.NoError(t, os.Remove(rcsFile))
+	it, err := r.GetCommits()
+	require.NoError(t, err)
+	require.True(t, it.Next())
+	require.Equal(t, "a\n", string(it.Commit().Files[0].Content))
+	require.False(t, it.Next())
+}
+
 func TestValidate_NotDirectory(t *testing.T) {
 	dir := t.TempDir()
 	// Create a file instead of directory
@@ -212,3 +235,501 @@ func TestValidate_NotDirectory(t *testing.T) {
 	require.False(t, res.Valid)
 	require.Greater(t, len(res.Errors), 0)
 }
+
+func TestGetCommits_ReconstructsFileContents(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "CVSROOT"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "src"), 0755))
+
+	rcsContent := `head	1.2;
+access;
+symbols;
+locks; strict;
+1.2
+date	2023.12.01.00.00.00;	author user;	state Exp;
+branches;
+next	1.1;
+1.1
+date	2023.01.01.00.00.00;	author user;	state Exp;
+branches;
+next	;
+desc
+@@
+1.2
+log
+@Second revision@
+text
+@hello
+world
+@
+1.1
+log
+@Initial revision@
+text
+@d2 1
+@
+`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "src", "hello.txt,v"), []byte(rcsContent), 0644))
+
+	r := NewReader(dir)
+	it, err := r.GetCommits()
+	require.NoError(t, err)
+
+	var commits []*vcs.Commit
+	for it.Next() {
+		commits = append(commits, it.Commit())
+	}
+	require.Len(t, commits, 2)
+
+	require.Len(t, commits[0].Files, 1)
+	require.Equal(t, "src/hello.txt", commits[0].Files[0].Path)
+	require.Equal(t, vcs.ActionAdd, commits[0].Files[0].Action)
+	require.Equal(t, "hello\n", string(commits[0].Files[0].Content))
+
+	require.Len(t, commits[1].Files, 1)
+	require.Equal(t, vcs.ActionModify, commits[1].Files[0].Action)
+	require.Equal(t, "hello\nworld\n", string(commits[1].Files[0].Content))
+}
+
+func TestGetCommits_InvalidDeltaText(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "CVSROOT"), 0755))
+	writeRCSFile(t, filepath.Join(dir, "a.txt,v"), "alice", "2024.01.01.10.00.00", "Import", "a\n")
+
+	content := "head 1.2;\naccess;\nsymbols;\nlocks; strict;\n" +
+		"1.2\ndate 2024.01.02.10.00.00; author alice; state Exp;\nbranches;\nnext 1.1;\n" +
+		"1.1\ndate 2024.01.01.10.00.00; author alice; state Exp;\nbranches;\nnext ;\n" +
+		"desc\n@@\n" +
+		"1.2\nlog\n@Change@\ntext\n@c\n@\n" +
+		"1.1\nlog\n@Import@\ntext\n@garbage\n@\n"
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "Attic"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "Attic", "c.txt,v"), []byte(content), 0644))
+
+	// A file whose history cannot be rebuilt fails the read rather than
+	// going missing from the commits
+	_, err := NewReader(dir).GetCommits()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "c.txt")
+	require.Contains(t, err.Error(), "revision 1.1")
+}
+
+func writeRCSFile(t *testing.T, path, author, date, log, text string) {
+	t.Helper()
+	content := "head\t1.1;\naccess;\nsymbols;\nlocks; strict;\n" +
+		"1.1\ndate\t" + date + ";\tauthor " + author + ";\tstate Exp;\nbranches;\nnext\t;\n" +
+		"desc\n@@\n1.1\nlog\n@" + log + "@\ntext\n@" + text + "@\n"
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func TestGetCommits_GroupsMultiFileCommits(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "CVSROOT"), 0755))
+
+	writeRCSFile(t, filepath.Join(dir, "a.txt,v"), "alice", "2024.01.01.10.00.00", "Import", "a\n")
+	writeRCSFile(t, filepath.Join(dir, "lib", "b.txt,v"), "alice", "2024.01.01.10.00.03", "Import", "b\n")
+	writeRCSFile(t, filepath.Join(dir, "c.txt,v"), "bob", "2024.01.01.10.00.00", "Unrelated", "c\n")
+
+	r := NewReaderWithOptions(dir, ReaderOptions{FuzzWindow: time.Minute})
+	it, err := r.GetCommits()
+	require.NoError(t, err)
+
+	var commits []*vcs.Commit
+	for it.Next() {
+		commits = append(commits, it.Commit())
+	}
+	require.Len(t, commits, 2)
+
+	byAuthor := make(map[string]*vcs.Commit)
+	for _, c := range commits {
+		byAuthor[c.Author] = c
+	}
+	require.Len(t, byAuthor["alice"].Files, 2)
+	require.Equal(t, "a.txt", byAuthor["alice"].Files[0].Path)
+	require.Equal(t, "lib/b.txt", byAuthor["alice"].Files[1].Path)
+	require.Len(t, byAuthor["bob"].Files, 1)
+}
+
+func TestNewReader_DefaultFuzzWindow(t *testing.T) {
+	r := NewReader("/repo")
+	require.Equal(t, DefaultFuzzWindow, r.options.FuzzWindow)
+
+	r = NewReaderWithOptions("/repo", ReaderOptions{FuzzWindow: time.Second})
+	require.Equal(t, time.Second, r.options.FuzzWindow)
+}
+
+func TestGetCommits_GroupsByCommitID(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "CVSROOT"), 0755))
+
+	// A slow commit over a network: same commitid, an hour apart
+	for name, date := range map[string]string{"a.txt": "2024.01.01.10.00.00", "b.txt": "2024.01.01.11.00.00"} {
+		content := "head\t1.1;\naccess;\nsymbols;\nlocks; strict;\n" +
+			"1.1\ndate\t" + date + ";\tauthor alice;\tstate Exp;\nbranches;\nnext\t;\ncommitid\t100659E2C4A1B2C3D4E;\n" +
+			"desc\n@@\n1.1\nlog\n@Import@\ntext\n@" + name + "\n@\n"
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name+",v"), []byte(content), 0644))
+	}
+
+	r := NewReader(dir)
+	it, err := r.GetCommits()
+	require.NoError(t, err)
+
+	var commits []*vcs.Commit
+	for it.Next() {
+		commits = append(commits, it.Commit())
+	}
+	require.Len(t, commits, 1)
+	require.Len(t, commits[0].Files, 2)
+}
+
+func TestGetCommits_AtticAndDeadRevisions(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "CVSROOT"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "src", "Attic"), 0755))
+
+	files := map[string]string{
+		// Added, then removed from the trunk
+		"src/Attic/gone.txt,v": "head 1.2;\naccess;\nsymbols;\nlocks; strict;\n" +
+			"1.2\ndate 2024.01.02.10.00.00; author alice; state dead;\nbranches;\nnext 1.1;\n" +
+			"1.1\ndate 2024.01.01.10.00.00; author alice; state Exp;\nbranches;\nnext ;\n" +
+			"desc\n@@\n" +
+			"1.2\nlog\n@Remove gone@\ntext\n@@\n" +
+			"1.1\nlog\n@Add gone@\ntext\n@a0 1\ngone\n@\n",
+		// Removed and later resurrected
+		"src/revived.txt,v": "head 1.3;\naccess;\nsymbols;\nlocks; strict;\n" +
+			"1.3\ndate 2024.01.03.10.00.00; author bob; state Exp;\nbranches;\nnext 1.2;\n" +
+			"1.2\ndate 2024.01.02.11.00.00; author bob; state dead;\nbranches;\nnext 1.1;\n" +
+			"1.1\ndate 2024.01.01.11.00.00; author bob; state Exp;\nbranches;\nnext ;\n" +
+			"desc\n@@\n" +
+			"1.3\nlog\n@Revive@\ntext\n@back\n@\n" +
+			"1.2\nlog\n@Kill@\ntext\n@d1 1\n@\n" +
+			"1.1\nlog\n@Start@\ntext\n@a0 1\nold\n@\n",
+		// First added on a branch, so the trunk 1.1 is a dead placeholder
+		"src/Attic/feature.txt,v": "head 1.1;\naccess;\nsymbols DEV:1.1.0.2;\nlocks; strict;\n" +
+			"1.1\ndate 2024.01.04.10.00.00; author carol; state dead;\nbranches 1.1.2.1;\nnext ;\n" +
+			"1.1.2.1\ndate 2024.01.04.10.00.00; author carol; state Exp;\nbranches;\nnext ;\n" +
+			"desc\n@@\n" +
+			"1.1\nlog\n@file feature.txt was initially added on branch DEV.\n@\ntext\n@@\n" +
+			"1.1.2.1\nlog\n@Add feature@\ntext\n@a0 1\nfeature\n@\n",
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	it, err := NewReader(dir).GetCommits()
+	require.NoError(t, err)
+
+	byMessage := make(map[string]*vcs.Commit)
+	for it.Next() {
+		byMessage[it.Commit().Message] = it.Commit()
+	}
+	require.Len(t, byMessage, 6)
+
+	expect := []struct {
+		message string
+		path    string
+		action  vcs.Action
+		content string
+	}{
+		{"Add gone", "src/gone.txt", vcs.ActionAdd, "gone\n"},
+		{"Remove gone", "src/gone.txt", vcs.ActionDelete, ""},
+		{"Start", "src/revived.txt", vcs.ActionAdd, "old\n"},
+		{"Kill", "src/revived.txt", vcs.ActionDelete, ""},
+		{"Revive", "src/revived.txt", vcs.ActionAdd, "back\n"},
+		{"Add feature", "src/feature.txt", vcs.ActionAdd, "feature\n"},
+	}
+	for _, e := range expect {
+		c := byMessage[e.message]
+		require.NotNil(t, c, e.message)
+		require.Len(t, c.Files, 1, e.message)
+		require.Equal(t, e.path, c.Files[0].Path, e.message)
+		require.Equal(t, e.action, c.Files[0].Action, e.message)
+		require.Equal(t, e.content, string(c.Files[0].Content), e.message)
+	}
+	require.Equal(t, "DEV", byMessage["Add feature"].Branch)
+}
+
+func TestReader_WorkingPath(t *testing.T) {
+	r := NewReader("/repo")
+	require.Equal(t, "a.txt", r.workingPath("/repo/a.txt,v"))
+	require.Equal(t, "src/a.txt", r.workingPath("/repo/src/a.txt,v"))
+	require.Equal(t, "src/a.txt", r.workingPath("/repo/src/Attic/a.txt,v"))
+	require.Equal(t, "a.txt", r.workingPath("/repo/Attic/a.txt,v"))
+}
+
+func TestGetCommits_BranchParents(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "CVSROOT"), 0755))
+
+	// DEV sprouts from 1.1 and has a commit, REL sprouts from 1.2 and has none
+	content := "head 1.2;\naccess;\nsymbols REL:1.2.0.4 DEV:1.1.0.2;\nlocks; strict;\n" +
+		"1.2\ndate 2024.01.03.10.00.00; author alice; state Exp;\nbranches;\nnext 1.1;\n" +
+		"1.1\ndate 2024.01.01.10.00.00; author alice; state Exp;\nbranches 1.1.2.1;\nnext ;\n" +
+		"1.1.2.1\ndate 2024.01.02.10.00.00; author bob; state Exp;\nbranches;\nnext ;\n" +
+		"desc\n@@\n" +
+		"1.2\nlog\n@Second@\ntext\n@two\n@\n" +
+		"1.1\nlog\n@First@\ntext\n@d1 1\na1 1\none\n@\n" +
+		"1.1.2.1\nlog\n@On DEV@\ntext\n@d1 1\na1 1\ndev\n@\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt,v"), []byte(content), 0644))
+
+	r := NewReader(dir)
+	it, err := r.GetCommits()
+	require.NoError(t, err)
+
+	byMessage := make(map[string]*vcs.Commit)
+	for it.Next() {
+		byMessage[it.Commit().Message] = it.Commit()
+	}
+	require.Len(t, byMessage, 3)
+	require.Equal(t, "DEV", byMessage["On DEV"].Branch)
+	require.Equal(t, byMessage["First"].Revision, byMessage["On DEV"].Parent)
+	require.Empty(t, byMessage["Second"].Parent)
+
+	points, err := r.GetBranchPoints()
+	require.NoError(t, err)
+	require.Equal(t, byMessage["First"].Revision, points["DEV"])
+	require.Equal(t, byMessage["Second"].Revision, points["REL"])
+}
+
+func TestGetCommits_BinaryFile(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "CVSROOT"), 0755))
+
+	// A -kb file whose revisions hold bytes that are not UTF-8, CRLF line
+	// endings, an @ and a keyword that must not be expanded
+	v2 := []byte("\x89PNG\r\n\x00\xff$Id$\n\xfe@\xc3\n")
+	v1 := []byte("\x89PNG\r\n\x00\xff$Id$\n")
+	escape := func(b []byte) string { return strings.ReplaceAll(string(b), "@", "@@") }
+	rcsContent := "head\t1.2;\naccess;\nsymbols;\nlocks; strict;\ncomment\t@# @;\nexpand\t@b@;\n\n" +
+		"1.2\ndate\t2023.12.01.00.00.00;\tauthor user;\tstate Exp;\nbranches;\nnext\t1.1;\n\n" +
+		"1.1\ndate\t2023.01.01.00.00.00;\tauthor user;\tstate Exp;\nbranches;\nnext\t;\n\n" +
+		"desc\n@@\n\n1.2\nlog\n@Update image@\ntext\n@" + escape(v2) + "@\n\n" +
+		"1.1\nlog\n@Add image@\ntext\n@d3 1\n@\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "logo.png,v"), []byte(rcsContent), 0644))
+
+	it, err := NewReader(dir).GetCommits()
+	require.NoError(t, err)
+	var commits []*vcs.Commit
+	for it.Next() {
+		commits = append(commits, it.Commit())
+	}
+	require.Len(t, commits, 2)
+	require.Equal(t, v1, commits[0].Files[0].Content)
+	require.Equal(t, v2, commits[1].Files[0].Content)
+}
+
+func TestGetCommits_Keywords(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "CVSROOT"), 0755))
+	writeRCSFile(t, filepath.Join(dir, "src", "main.c,v"), "user", "2023.01.01.00.00.00", "Initial",
+		"/* $Id: main.c,v 1.0 2022/01/01 00:00:00 old Exp $ */\n")
+	writeRCSFile(t, filepath.Join(dir, "doc", "notes.txt,v"), "user", "2023.01.01.00.00.00", "Initial",
+		"$Revision: 1.0 $\n")
+
+	r := NewReaderWithOptions(dir, ReaderOptions{Keywords: KeywordOptions{
+		Mode:  KeywordsCollapse,
+		Rules: []KeywordRule{{Pattern: "*.c", Mode: KeywordsExpand}},
+	}})
+	it, err := r.GetCommits()
+	require.NoError(t, err)
+	require.True(t, it.Next())
+
+	contents := make(map[string]string)
+	for _, fc := range it.Commit().Files {
+		contents[fc.Path] = string(fc.Content)
+	}
+	require.Equal(t, "/* $Id: main.c,v 1.1 2023/01/01 00:00:00 user Exp $ */\n", contents["src/main.c"])
+	require.Equal(t, "$Revision$\n", contents["doc/notes.txt"])
+}
+
+// writeImportedFile writes the RCS file of a file brought in by two cvs
+// imports; with local set, a trunk commit between them ends the default
+// branch
+func writeImportedFile(t *testing.T, path string, local bool) {
+	t.Helper()
+	header := "head 1.1;\nbranch 1.1.1;\n"
+	trunk := "1.1\ndate 2024.01.01.10.00.00; author alice; state Exp;\nbranches 1.1.1.1;\nnext ;\n"
+	trunkText := ""
+	if local {
+		header = "head 1.2;\n"
+		trunk = "1.2\ndate 2024.01.02.10.00.00; author bob; state Exp;\nbranches;\nnext 1.1;\n" + trunk
+		trunkText = "1.2\nlog\n@Local change@\ntext\n@v1\nlocal\n@\n"
+	}
+	content := header + "access;\nsymbols REL_2:1.1.1.2 REL_1:1.1.1.1 VENDOR:1.1.1;\nlocks; strict;\n" + trunk +
+		"1.1.1.1\ndate 2024.01.01.10.00.00; author alice; state Exp;\nbranches;\nnext 1.1.1.2;\n" +
+		"1.1.1.2\ndate 2024.01.03.10.00.00; author alice; state Exp;\nbranches;\nnext ;\n" +
+		"desc\n@@\n" + trunkText
+	if local {
+		content += "1.1\nlog\n@Initial revision\n@\ntext\n@d2 1\n@\n"
+	} else {
+		content += "1.1\nlog\n@Initial revision\n@\ntext\n@v1\n@\n"
+	}
+	content += "1.1.1.1\nlog\n@Import 1@\ntext\n@@\n" +
+		"1.1.1.2\nlog\n@Import 2@\ntext\n@d1 1\na1 1\nv2\n@\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func TestGetCommits_VendorBranch(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "CVSROOT"), 0755))
+	writeImportedFile(t, filepath.Join(dir, "a.c,v"), false)
+
+	r := NewReader(dir)
+	it, err := r.GetCommits()
+	require.NoError(t, err)
+	var commits []*vcs.Commit
+	for it.Next() {
+		commits = append(commits, it.Commit())
+	}
+
+	// One commit per import, both on the trunk
+	require.Len(t, commits, 2)
+	require.Equal(t, "Import 1", commits[0].Message)
+	require.Equal(t, "", commits[0].Branch)
+	require.Equal(t, vcs.ActionAdd, commits[0].Files[0].Action)
+	require.Equal(t, "v1\n", string(commits[0].Files[0].Content))
+	require.Equal(t, "Import 2", commits[1].Message)
+	require.Equal(t, "", commits[1].Branch)
+	require.Equal(t, "v2\n", string(commits[1].Files[0].Content))
+
+	tags, err := r.GetTags()
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"REL_1": commits[0].Revision, "REL_2": commits[1].Revision}, tags)
+	branches, err := r.GetBranches()
+	require.NoError(t, err)
+	require.Equal(t, []string{"VENDOR"}, branches)
+}
+
+func TestGetCommits_VendorBranchAfterLocalChange(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "CVSROOT"), 0755))
+	writeImportedFile(t, filepath.Join(dir, "a.c,v"), true)
+
+	r := NewReaderWithOptions(dir, ReaderOptions{VendorBranch: "vendor"})
+	it, err := r.GetCommits()
+	require.NoError(t, err)
+	byKey := make(map[string]*vcs.Commit)
+	for it.Next() {
+		c := it.Commit()
+		byKey[c.Branch+"|"+c.Message] = c
+	}
+
+	// The first import was the trunk until the local change; the second
+	// only updated the vendor branch. Both imports are on the vendor branch.
+	require.Len(t, byKey, 4)
+	require.Equal(t, "v1\n", string(byKey["|Import 1"].Files[0].Content))
+	require.Equal(t, vcs.ActionModify, byKey["|Local change"].Files[0].Action)
+	require.Contains(t, byKey, "vendor|Import 1")
+	require.Equal(t, "v2\n", string(byKey["vendor|Import 2"].Files[0].Content))
+	require.Empty(t, byKey["vendor|Import 1"].Files[0].Revision)
+
+	branches, err := r.GetBranches()
+	require.NoError(t, err)
+	require.Equal(t, []string{"vendor"}, branches)
+	tags, err := r.GetTags()
+	require.NoError(t, err)
+	require.Equal(t, byKey["|Import 1"].Revision, tags["REL_1"])
+	require.Equal(t, byKey["vendor|Import 2"].Revision, tags["REL_2"])
+}
+
+func TestGetCommits_Module(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "CVSROOT"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "CVSROOT", "modules"),
+		[]byte("app project/app\nwebsite &app &docs\ndocs -d manual project/doc\n"), 0644))
+
+	writeRCSFile(t, filepath.Join(dir, "project", "app", "main.c,v"), "alice", "2024.01.01.10.00.00", "Import", "main\n")
+	writeRCSFile(t, filepath.Join(dir, "project", "app", "test", "main_test.c,v"), "alice", "2024.01.01.10.00.00", "Import", "test\n")
+	writeRCSFile(t, filepath.Join(dir, "project", "doc", "index.html,v"), "alice", "2024.01.01.10.00.00", "Import", "$Id$\n")
+	writeRCSFile(t, filepath.Join(dir, "other", "readme,v"), "alice", "2024.01.01.10.00.00", "Import", "other\n")
+
+	paths := func(options ReaderOptions) []string {
+		t.Helper()
+		it, err := NewReaderWithOptions(dir, options).GetCommits()
+		require.NoError(t, err)
+		var files []string
+		for it.Next() {
+			for _, f := range it.Commit().Files {
+				files = append(files, f.Path)
+			}
+		}
+		return files
+	}
+
+	require.ElementsMatch(t, []string{"main.c", "test/main_test.c"}, paths(ReaderOptions{Module: "app"}))
+	require.ElementsMatch(t, []string{"app/main.c", "manual/index.html"},
+		paths(ReaderOptions{Module: "website", ExcludeModules: []string{"project/app/test"}}))
+	require.ElementsMatch(t, []string{"project/app/main.c", "project/doc/index.html", "project/app/test/main_test.c"},
+		paths(ReaderOptions{ExcludeModules: []string{"other"}}))
+
+	// Keywords still name the RCS file in the repository
+	r := NewReaderWithOptions(dir, ReaderOptions{Module: "docs", Keywords: KeywordOptions{Mode: KeywordsExpand}})
+	it, err := r.GetCommits()
+	require.NoError(t, err)
+	require.True(t, it.Next())
+	require.Equal(t, "index.html", it.Commit().Files[0].Path)
+	require.Contains(t, string(it.Commit().Files[0].Content), "$Id: index.html,v 1.1 ")
+
+	_, err = NewReaderWithOptions(dir, ReaderOptions{Module: "nonexistent"}).GetCommits()
+	require.Error(t, err)
+}
+
+func TestGetTagDates_History(t *testing.T) {
+	dir := t.TempDir()
+	cvsroot := filepath.Join(dir, "CVSROOT")
+	require.NoError(t, os.MkdirAll(cvsroot, 0755))
+
+	rcsContent := "head\t1.1;\naccess;\nsymbols\n\tREL_1_0:1.1\n\tREL_1_1:1.1\n\tREL_0_9:1.1;\nlocks; strict;\n" +
+		"1.1\ndate\t2023.06.01.12.00.00;\tauthor alice;\tstate Exp;\nbranches;\nnext\t;\n" +
+		"desc\n@@\n1.1\nlog\n@Initial revision@\ntext\n@content@\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file.txt,v"), []byte(rcsContent), 0644))
+
+	// REL_1_0 was applied later than its revision; the REL_0_9 record
+	// predates the revision and cannot be right
+	require.NoError(t, os.WriteFile(filepath.Join(cvsroot, "history"), []byte(
+		"T65920080|bob|<remote>/*0|A|REL_1_0|project\n"+
+			"T64788800|bob|<remote>/*0|A|REL_0_9|project\n"), 0644))
+
+	dates, err := NewReader(dir).GetTagDates()
+	require.NoError(t, err)
+	revision := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	require.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), dates["REL_1_0"])
+	require.Equal(t, revision, dates["REL_1_1"])
+	require.Equal(t, revision, dates["REL_0_9"])
+}
+
+func TestGetTags_TagOnBranchRevision(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "CVSROOT"), 0755))
+
+	content := "head 1.1;\naccess;\nsymbols DEV_TAG:1.1.2.1 DEV:1.1.0.2;\nlocks; strict;\n" +
+		"1.1\ndate 2024.01.01.10.00.00; author alice; state Exp;\nbranches 1.1.2.1;\nnext ;\n" +
+		"1.1.2.1\ndate 2024.01.02.10.00.00; author bob; state Exp;\nbranches;\nnext ;\n" +
+		"desc\n@@\n" +
+		"1.1\nlog\n@First@\ntext\n@one\n@\n" +
+		"1.1.2.1\nlog\n@On DEV@\ntext\n@d1 1\na1 1\ndev\n@\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt,v"), []byte(content), 0644))
+
+	r := NewReader(dir)
+	branches, err := r.GetBranches()
+	require.NoError(t, err)
+	require.Equal(t, []string{"DEV"}, branches)
+
+	tags, err := r.GetTags()
+	require.NoError(t, err)
+	require.Contains(t, tags, "DEV_TAG")
+
+	// The tag resolves to the commit on DEV
+	it, err := r.GetCommits()
+	require.NoError(t, err)
+	var dev *vcs.Commit
+	for it.Next() {
+		if it.Commit().Message == "On DEV" {
+			dev = it.Commit()
+		}
+	}
+	require.NotNil(t, dev)
+	tags, err = r.GetTags()
+	require.NoError(t, err)
+	require.Equal(t, dev.Revision, tags["DEV_TAG"])
+}
@@ -0,0 +1,241 @@
+package cvs
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+)
+
+// tagRevision is the revision of one file that a tag points at
+type tagRevision struct {
+	path     string
+	revision string
+}
+
+// resolveTags finds, for every tag, the changeset at which each tagged file
+// is at its tagged revision. CVS tags files individually, so such a
+// changeset does not always exist; those tags get a synthetic fixup commit
+// that sets the mismatched files to their tagged content and removes files
+// the tag does not include. points maps branches to the changeset they fork
+// from. It returns the revision identifier of the commit for each tag and
+// the fixup commits by revision identifier.
+func resolveTags(changesets []*changeset, tags map[string][]tagRevision, points map[string]string) (map[string]string, map[string]*vcs.Commit) {
+	// Locate every file revision and its successors in the ordered changesets
+	index := make(map[string]int)
+	revisions := make(map[string]*fileRevision)
+	successors := make(map[string][]*fileRevision)
+	for i, cs := range changesets {
+		for _, rev := range cs.revisions {
+			if rev.vendorCopy {
+				continue
+			}
+			key := rev.path + ":" + rev.revision
+			index[key] = i
+			revisions[key] = rev
+			if rev.predecessor != "" {
+				pred := rev.path + ":" + rev.predecessor
+				successors[pred] = append(successors[pred], rev)
+			}
+		}
+	}
+
+	names := make([]string, 0, len(tags))
+	for name := range tags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	resolved := make(map[string]string)
+	fixups := make(map[string]*vcs.Commit)
+	for _, name := range names {
+		var tagged []*fileRevision
+		for _, tr := range tags[name] {
+			if rev, ok := revisions[tr.path+":"+tr.revision]; ok {
+				tagged = append(tagged, rev)
+			}
+		}
+		if len(tagged) == 0 {
+			log.Printf("Warning: tag %s does not refer to any migrated file revision", name)
+			continue
+		}
+		sort.Slice(tagged, func(i, j int) bool { return tagged[i].path < tagged[j].path })
+
+		branch := tagBranch(tagged)
+
+		// Base the tag on the latest changeset of its branch that
+		// contains a tagged revision
+		base := -1
+		for _, rev := range tagged {
+			i := index[rev.path+":"+rev.revision]
+			if changesets[i].branch == branch && i > base {
+				base = i
+			}
+		}
+		if base < 0 {
+			log.Printf("Warning: tag %s has no commit on branch %q", name, branch)
+			continue
+		}
+
+		// A tagged revision is current at the base changeset if it was
+		// committed no later and not yet replaced on the tag's branch
+		var stale []*fileRevision
+		for _, rev := range tagged {
+			key := rev.path + ":" + rev.revision
+			current := index[key] <= base
+			for _, next := range successors[key] {
+				if next.branch == branch && index[next.path+":"+next.revision] <= base {
+					current = false
+				}
+			}
+			if !current {
+				stale = append(stale, rev)
+			}
+		}
+
+		// Files at the base changeset that the tag does not include
+		included := make(map[string]bool)
+		for _, tr := range tags[name] {
+			included[tr.path] = true
+		}
+		var untagged []string
+		for _, path := range presentFiles(changesets, points, branch, base) {
+			if !included[path] {
+				untagged = append(untagged, path)
+			}
+		}
+
+		if len(stale) == 0 && len(untagged) == 0 {
+			resolved[name] = changesets[base].id
+			continue
+		}
+
+		fixup := tagFixup(name, changesets[base], tagged, stale, untagged)
+		fixups[fixup.Revision] = fixup
+		resolved[name] = fixup.Revision
+	}
+
+	return resolved, fixups
+}
+
+// tagDates returns, for every tag, the date of the latest file revision it
+// refers to
+func tagDates(revisions []*fileRevision, tags map[string][]tagRevision) map[string]time.Time {
+	dates := make(map[string]time.Time, len(revisions))
+	for _, rev := range revisions {
+		dates[rev.path+":"+rev.revision] = rev.date
+	}
+
+	latest := make(map[string]time.Time)
+	for name, tagged := range tags {
+		for _, tr := range tagged {
+			if date, ok := dates[tr.path+":"+tr.revision]; ok && date.After(latest[name]) {
+				latest[name] = date
+			}
+		}
+	}
+	return latest
+}
+
+// tagBranch returns the branch most tagged revisions live on, preferring
+// branches over the trunk since untouched files keep trunk revisions
+func tagBranch(tagged []*fileRevision) string {
+	counts := make(map[string]int)
+	for _, rev := range tagged {
+		if rev.branch != "" {
+			counts[rev.branch]++
+		}
+	}
+
+	branch := ""
+	for name, n := range counts {
+		if branch == "" || n > counts[branch] || (n == counts[branch] && name < branch) {
+			branch = name
+		}
+	}
+	return branch
+}
+
+// presentFiles returns, sorted, the files that exist at changeset upto of
+// branch: those whose latest revision in the history of the branch is not
+// a deletion. A branch's history includes that of the branch it forks
+// from, up to its branch point.
+func presentFiles(changesets []*changeset, points map[string]string, branch string, upto int) []string {
+	position := make(map[string]int, len(changesets))
+	for i, cs := range changesets {
+		position[cs.id] = i
+	}
+
+	// The last changeset of each branch in the history
+	until := map[string]int{branch: upto}
+	for b := branch; b != ""; {
+		i, ok := position[points[b]]
+		if !ok {
+			break
+		}
+		b = changesets[i].branch
+		if _, seen := until[b]; seen {
+			break
+		}
+		until[b] = i
+	}
+
+	alive := make(map[string]bool)
+	for i := 0; i <= upto; i++ {
+		cs := changesets[i]
+		if last, ok := until[cs.branch]; !ok || i > last {
+			continue
+		}
+		for _, rev := range cs.revisions {
+			alive[rev.path] = rev.action != vcs.ActionDelete
+		}
+	}
+
+	var files []string
+	for path, ok := range alive {
+		if ok {
+			files = append(files, path)
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// tagFixup creates the synthetic commit that brings the stale files of a
+// tag to their tagged revisions on top of the base changeset, and removes
+// the untagged files
+func tagFixup(name string, base *changeset, tagged, stale []*fileRevision, untagged []string) *vcs.Commit {
+	hash := sha1.Sum([]byte("tag:" + name))
+
+	var date time.Time
+	for _, rev := range tagged {
+		if rev.date.After(date) {
+			date = rev.date
+		}
+	}
+
+	commit := &vcs.Commit{
+		Revision: hex.EncodeToString(hash[:])[:16],
+		Author:   "git-migrator",
+		Date:     date,
+		Message:  fmt.Sprintf("This commit was manufactured by git-migrator to create tag '%s'.", name),
+		Branch:   base.branch,
+		Parent:   base.id,
+	}
+	for _, rev := range stale {
+		switch {
+		case rev.action == vcs.ActionDelete:
+			commit.Files = append(commit.Files, vcs.FileChange{Path: rev.path, Action: vcs.ActionDelete})
+		case rev.hasContent:
+			commit.Files = append(commit.Files, vcs.FileChange{Path: rev.path, Action: vcs.ActionModify, Content: rev.content})
+		}
+	}
+	for _, path := range untagged {
+		commit.Files = append(commit.Files, vcs.FileChange{Path: path, Action: vcs.ActionDelete})
+	}
+	return commit
+}
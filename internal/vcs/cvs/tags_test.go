@@ -0,0 +1,149 @@
+package cvs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	"github.com/stretchr/testify/require"
+)
+
+// tagHistory builds two files where a.c changes again after b.c changes:
+//
+//	one:   a.c 1.1, b.c 1.1
+//	two:   b.c 1.2
+//	three: a.c 1.2
+func tagHistory() []*changeset {
+	a1 := rev("a.c", "1.1", "alice", "one", 0)
+	b1 := rev("b.c", "1.1", "alice", "one", 0)
+	b2 := rev("b.c", "1.2", "alice", "two", time.Hour)
+	b2.predecessor = "1.1"
+	a2 := rev("a.c", "1.2", "alice", "three", 2*time.Hour)
+	a2.predecessor = "1.1"
+	for _, r := range []*fileRevision{a1, b1, b2, a2} {
+		r.content = []byte(r.path + " " + r.revision)
+		r.hasContent = true
+	}
+	return orderChangesets(groupChangesets([]*fileRevision{a1, b1, b2, a2}, DefaultFuzzWindow))
+}
+
+func TestResolveTags_ExistingCommit(t *testing.T) {
+	changesets := tagHistory()
+	tags := map[string][]tagRevision{
+		"FIRST":  {{"a.c", "1.1"}, {"b.c", "1.1"}},
+		"SECOND": {{"a.c", "1.1"}, {"b.c", "1.2"}},
+		"LATEST": {{"a.c", "1.2"}, {"b.c", "1.2"}},
+	}
+
+	resolved, fixups := resolveTags(changesets, tags, nil)
+	require.Empty(t, fixups)
+	require.Equal(t, changesets[0].id, resolved["FIRST"])
+	require.Equal(t, changesets[1].id, resolved["SECOND"])
+	require.Equal(t, changesets[2].id, resolved["LATEST"])
+}
+
+func TestResolveTags_MixedTagNeedsFixup(t *testing.T) {
+	changesets := tagHistory()
+	// b.c 1.1 was replaced before a.c 1.2 was committed
+	tags := map[string][]tagRevision{
+		"MIXED": {{"a.c", "1.2"}, {"b.c", "1.1"}},
+	}
+
+	resolved, fixups := resolveTags(changesets, tags, nil)
+	require.Len(t, fixups, 1)
+
+	fixup := fixups[resolved["MIXED"]]
+	require.NotNil(t, fixup)
+	require.Equal(t, changesets[2].id, fixup.Parent)
+	require.Contains(t, fixup.Message, "'MIXED'")
+	require.Equal(t, []vcs.FileChange{
+		{Path: "b.c", Action: vcs.ActionModify, Content: []byte("b.c 1.1")},
+	}, fixup.Files)
+}
+
+func TestResolveTags_BranchTag(t *testing.T) {
+	a1 := rev("a.c", "1.1", "alice", "one", 0)
+	b1 := rev("b.c", "1.1", "alice", "one", 0)
+	branch := rev("a.c", "1.1.2.1", "bob", "on branch", time.Hour)
+	branch.predecessor = "1.1"
+	branch.branch = "DEV"
+	trunk := rev("b.c", "1.2", "alice", "trunk", 2*time.Hour)
+	trunk.predecessor = "1.1"
+	changesets := orderChangesets(groupChangesets([]*fileRevision{a1, b1, branch, trunk}, DefaultFuzzWindow))
+
+	// b.c is untouched on DEV, so its trunk 1.1 is still current there
+	resolved, fixups := resolveTags(changesets, map[string][]tagRevision{
+		"DEV_TAG": {{"a.c", "1.1.2.1"}, {"b.c", "1.1"}},
+	}, map[string]string{"DEV": changesets[0].id})
+	require.Empty(t, fixups)
+	require.Equal(t, changesets[1].id, resolved["DEV_TAG"])
+	require.Equal(t, "DEV", changesets[1].branch)
+}
+
+func TestResolveTags_UntaggedFiles(t *testing.T) {
+	a1 := rev("a.c", "1.1", "alice", "one", 0)
+	b1 := rev("b.c", "1.1", "alice", "one", 0)
+	old := rev("old.c", "1.1", "alice", "one", 0)
+	branch := rev("a.c", "1.1.2.1", "bob", "on branch", time.Hour)
+	branch.predecessor = "1.1"
+	branch.branch = "DEV"
+	added := rev("new.c", "1.1", "alice", "added", 2*time.Hour)
+	a2 := rev("a.c", "1.2", "alice", "trunk", 3*time.Hour)
+	a2.predecessor = "1.1"
+	gone := rev("b.c", "1.2", "alice", "removed", 4*time.Hour)
+	gone.predecessor = "1.1"
+	gone.action = vcs.ActionDelete
+	changesets := orderChangesets(groupChangesets([]*fileRevision{a1, b1, old, branch, added, a2, gone}, DefaultFuzzWindow))
+	points := map[string]string{"DEV": changesets[0].id}
+
+	resolved, fixups := resolveTags(changesets, map[string][]tagRevision{
+		// old.c was never tagged and new.c was added after the tag
+		"REL": {{"a.c", "1.2"}, {"b.c", "1.1"}},
+		// new.c on the trunk is not part of DEV, old.c is
+		"DEV_TAG": {{"a.c", "1.1.2.1"}, {"b.c", "1.1"}},
+		// Every file of its base is tagged
+		"ALL": {{"a.c", "1.1"}, {"b.c", "1.1"}, {"old.c", "1.1"}},
+	}, points)
+
+	require.Len(t, fixups, 2)
+	require.Equal(t, changesets[0].id, resolved["ALL"])
+
+	rel := fixups[resolved["REL"]]
+	require.NotNil(t, rel)
+	require.Equal(t, []vcs.FileChange{
+		{Path: "new.c", Action: vcs.ActionDelete},
+		{Path: "old.c", Action: vcs.ActionDelete},
+	}, rel.Files)
+
+	dev := fixups[resolved["DEV_TAG"]]
+	require.NotNil(t, dev)
+	require.Equal(t, []vcs.FileChange{
+		{Path: "old.c", Action: vcs.ActionDelete},
+	}, dev.Files)
+}
+
+func TestResolveTags_UnknownRevisions(t *testing.T) {
+	resolved, fixups := resolveTags(tagHistory(), map[string][]tagRevision{
+		"GHOST": {{"missing.c", "1.1"}},
+	}, nil)
+	require.Empty(t, resolved)
+	require.Empty(t, fixups)
+}
+
+func TestTagDates(t *testing.T) {
+	var revisions []*fileRevision
+	for _, cs := range tagHistory() {
+		revisions = append(revisions, cs.revisions...)
+	}
+	tags := map[string][]tagRevision{
+		"FIRST":   {{"a.c", "1.1"}, {"b.c", "1.1"}},
+		"MIXED":   {{"a.c", "1.2"}, {"b.c", "1.1"}},
+		"UNKNOWN": {{"c.c", "1.1"}},
+	}
+
+	dates := tagDates(revisions, tags)
+	require.Equal(t, changesetBase, dates["FIRST"])
+	require.Equal(t, changesetBase.Add(2*time.Hour), dates["MIXED"])
+	_, ok := dates["UNKNOWN"]
+	require.False(t, ok)
+}
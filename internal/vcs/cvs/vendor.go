@@ -0,0 +1,104 @@
+package cvs
+
+import (
+	"bytes"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// isVendorBranch reports whether a branch number is a vendor branch, such
+// as the 1.1.1 that cvs import creates. Branches made with cvs tag -b get
+// even numbers and are recorded with magic numbers like 1.2.0.2 instead.
+func isVendorBranch(branch string) bool {
+	parts := strings.Split(branch, ".")
+	if len(parts) < 3 || len(parts)%2 == 0 || strings.Contains(branch, ".0.") {
+		return false
+	}
+	n, err := strconv.Atoi(parts[len(parts)-1])
+	return err == nil && n%2 == 1
+}
+
+// defaultBranchRevisions returns, oldest first, the vendor branch
+// revisions that were the latest trunk revision when they were checked in.
+//
+// A file imported with cvs import has the vendor branch as its default
+// branch, named by the branch header, until a revision is committed on the
+// trunk. Until then checkouts get the latest vendor revision, and later
+// imports update it. Committing 1.2 clears the header, so for files that
+// have a 1.2 the vendor revisions before it count as trunk revisions too.
+func (r *RCSFile) defaultBranchRevisions() []string {
+	branch := r.Branch
+	if branch == "" || !isVendorBranch(branch) {
+		branch = ""
+		if r.trunkSuccessor("1.1") != "" {
+			branch = "1.1.1"
+		}
+	}
+	if branch == "" {
+		return nil
+	}
+
+	var until *Delta
+	if next := r.trunkSuccessor(branchNumber(branch)); next != "" {
+		until = r.Deltas[next]
+	}
+
+	var revs []string
+	for rev, delta := range r.Deltas {
+		if branchNumber(rev) != branch {
+			continue
+		}
+		if until != nil && !delta.Date.Before(until.Date) {
+			continue
+		}
+		revs = append(revs, rev)
+	}
+	sort.Slice(revs, func(i, j int) bool { return revisionLess(revs[i], revs[j]) })
+	return revs
+}
+
+// trunkSuccessor returns the trunk revision committed after rev, or an
+// empty string if there is none
+func (r *RCSFile) trunkSuccessor(rev string) string {
+	for candidate, delta := range r.Deltas {
+		if delta.Next == rev && isTrunkRevision(candidate) && candidate != rev {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// initialImport returns the trunk revision that cvs import creates next to
+// the first vendor revision, or an empty string if the file has none.
+// Both hold the same text, so migrating the "Initial revision" as well as
+// the import would produce two commits for one import.
+func (r *RCSFile) initialImport(vendor []string, contents map[string][]byte) string {
+	if len(vendor) == 0 || !strings.HasSuffix(vendor[0], ".1") {
+		return ""
+	}
+	point := branchNumber(branchNumber(vendor[0]))
+	delta := r.Deltas[point]
+	if delta == nil || isDead(delta.State) || strings.TrimSpace(delta.Log) != "Initial revision" {
+		return ""
+	}
+	base, ok := contents[point]
+	imported, ok2 := contents[vendor[0]]
+	if !ok || !ok2 || !bytes.Equal(base, imported) {
+		return ""
+	}
+	return point
+}
+
+// revisionLess orders revision numbers of one branch numerically
+func revisionLess(a, b string) bool {
+	pa, pb := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		na, _ := strconv.Atoi(pa[i])
+		nb, _ := strconv.Atoi(pb[i])
+		if na != nb {
+			return na < nb
+		}
+	}
+	return len(pa) < len(pb)
+}
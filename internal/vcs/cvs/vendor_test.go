@@ -0,0 +1,80 @@
+package cvs
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsVendorBranch(t *testing.T) {
+	tests := map[string]bool{
+		"1.1.1":       true,
+		"1.1.3":       true,
+		"1.2.2":       false,
+		"1.2.0.2":     false,
+		"1.1.1.1":     false,
+		"1.1":         false,
+		"1.2.2.1.0.2": false,
+	}
+	for branch, expected := range tests {
+		if got := isVendorBranch(branch); got != expected {
+			t.Errorf("isVendorBranch(%q) = %v, want %v", branch, got, expected)
+		}
+	}
+}
+
+func TestDefaultBranchRevisions(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2004, 1, d, 0, 0, 0, 0, time.UTC) }
+	vendor := func(branch string, trunk ...*Delta) *RCSFile {
+		rcs := &RCSFile{
+			Branch: branch,
+			Deltas: map[string]*Delta{
+				"1.1":      {Revision: "1.1", Date: day(1), Branches: []string{"1.1.1.1"}},
+				"1.1.1.1":  {Revision: "1.1.1.1", Date: day(1), Next: "1.1.1.2"},
+				"1.1.1.2":  {Revision: "1.1.1.2", Date: day(3), Next: "1.1.1.10"},
+				"1.1.1.10": {Revision: "1.1.1.10", Date: day(9)},
+			},
+		}
+		for _, delta := range trunk {
+			rcs.Deltas[delta.Revision] = delta
+		}
+		return rcs
+	}
+
+	tests := []struct {
+		name     string
+		rcs      *RCSFile
+		expected []string
+	}{
+		{"default branch", vendor("1.1.1"), []string{"1.1.1.1", "1.1.1.2", "1.1.1.10"}},
+		{"until 1.2", vendor("", &Delta{Revision: "1.2", Date: day(5), Next: "1.1"}), []string{"1.1.1.1", "1.1.1.2"}},
+		{"no default branch", vendor(""), nil},
+	}
+	for _, tt := range tests {
+		got := tt.rcs.defaultBranchRevisions()
+		if strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+			t.Errorf("%s: defaultBranchRevisions() = %v, want %v", tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestInitialImport(t *testing.T) {
+	rcs := &RCSFile{
+		Deltas: map[string]*Delta{
+			"1.1":     {Revision: "1.1", State: "Exp", Log: "Initial revision\n"},
+			"1.1.1.1": {Revision: "1.1.1.1", State: "Exp", Log: "Import\n"},
+		},
+	}
+	contents := map[string][]byte{"1.1": []byte("a\n"), "1.1.1.1": []byte("a\n")}
+	if got := rcs.initialImport([]string{"1.1.1.1"}, contents); got != "1.1" {
+		t.Errorf("initialImport = %q, want 1.1", got)
+	}
+
+	contents["1.1.1.1"] = []byte("b\n")
+	if got := rcs.initialImport([]string{"1.1.1.1"}, contents); got != "" {
+		t.Errorf("initialImport with different contents = %q, want empty", got)
+	}
+	if got := rcs.initialImport(nil, contents); got != "" {
+		t.Errorf("initialImport without vendor revisions = %q, want empty", got)
+	}
+}
@@ -0,0 +1,355 @@
+// Package fastimport writes migrations as git fast-import streams.
+package fastimport
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+)
+
+// Stdout is the path that makes Init write the stream to standard output
+const Stdout = "-"
+
+// nullRevision deletes a ref when used as the source of a reset
+const nullRevision = "0000000000000000000000000000000000000000"
+
+// Writer implements VCSWriter by emitting a git fast-import stream. Commits
+// are identified by marks such as ":12", which are what LastCommitHash
+// returns and what CreateBranch and CreateTag accept.
+type Writer struct {
+	out        *bufio.Writer
+	file       io.Closer
+	nextMark   int
+	blobs      map[[sha1.Size]byte]string // Content hash -> blob mark
+	branches   map[string]string          // Branch name -> mark of its tip
+	authors    map[string]vcs.Signature   // Commit mark -> author
+	branch     string                     // Branch ApplyCommit writes to
+	lastCommit string
+}
+
+// NewWriter creates a new fast-import stream writer
+func NewWriter() *Writer {
+	return &Writer{
+		blobs:    make(map[[sha1.Size]byte]string),
+		branches: make(map[string]string),
+		authors:  make(map[string]vcs.Signature),
+		branch:   "master",
+	}
+}
+
+// Init creates the stream file at the given path, or writes to standard
+// output when the path is Stdout
+func (w *Writer) Init(path string) error {
+	if path == Stdout {
+		w.start(os.Stdout)
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create stream file: %w", err)
+	}
+	w.start(file)
+	w.file = file
+	return nil
+}
+
+// start begins the stream. The done feature makes git fast-import reject
+// streams that end before Close.
+func (w *Writer) start(out io.Writer) {
+	w.out = bufio.NewWriter(out)
+	fmt.Fprintf(w.out, "feature done\n")
+}
+
+// ApplyCommit writes a commit on the current branch
+func (w *Writer) ApplyCommit(commit *vcs.Commit) error {
+	return w.ApplyCommitWithParents(commit, w.branch)
+}
+
+// ApplyCommitToBranch writes a commit on the named branch. A branch that
+// has no commits in the stream yet is forked from parent, which accepts
+// anything CreateBranch does; when parent is empty or unknown the branch
+// starts without history.
+func (w *Writer) ApplyCommitToBranch(commit *vcs.Commit, branch, parent string) error {
+	if _, ok := w.branches[branch]; ok || parent == "" {
+		return w.ApplyCommitWithParents(commit, branch)
+	}
+	return w.ApplyCommitWithParents(commit, branch, parent)
+}
+
+// ApplyCommitWithParents writes a commit on the named branch with explicit
+// parents: the first replaces the branch tip and any others become merge
+// parents. Without parents the commit continues the branch.
+func (w *Writer) ApplyCommitWithParents(commit *vcs.Commit, branch string, parents ...string) error {
+	if w.out == nil {
+		return fmt.Errorf("stream not initialized")
+	}
+
+	// Blobs go before the commit that refers to them
+	blobMarks := make([]string, len(commit.Files))
+	for i, fc := range commit.Files {
+		if fc.Action != vcs.ActionDelete {
+			blobMarks[i] = w.writeBlob(fc.Content)
+		}
+	}
+
+	var from []string
+	for _, parent := range parents {
+		ref, err := w.resolve(parent)
+		if err != nil {
+			return err
+		}
+		from = append(from, ref)
+	}
+
+	mark := w.newMark()
+	author := vcs.Signature{Name: commit.Author, Email: commit.Email, When: commit.Date}
+	signature := formatSignature(author)
+	fmt.Fprintf(w.out, "commit refs/heads/%s\n", branch)
+	fmt.Fprintf(w.out, "mark %s\n", mark)
+	fmt.Fprintf(w.out, "author %s\n", signature)
+	fmt.Fprintf(w.out, "committer %s\n", signature)
+	w.writeData([]byte(commit.Message))
+	for i, ref := range from {
+		if i == 0 {
+			fmt.Fprintf(w.out, "from %s\n", ref)
+		} else {
+			fmt.Fprintf(w.out, "merge %s\n", ref)
+		}
+	}
+	for i, fc := range commit.Files {
+		if fc.Action == vcs.ActionDelete {
+			fmt.Fprintf(w.out, "D %s\n", quotePath(fc.Path))
+		} else {
+			fmt.Fprintf(w.out, "M %s %s %s\n", fileMode(fc.Mode), blobMarks[i], quotePath(fc.Path))
+		}
+	}
+	fmt.Fprintf(w.out, "\n")
+
+	w.branches[branch] = mark
+	w.authors[mark] = author
+	w.branch = branch
+	w.lastCommit = mark
+	return nil
+}
+
+// fileMode returns the mode of a file change in a filemodify command
+func fileMode(mode vcs.FileMode) string {
+	switch mode {
+	case vcs.ModeExecutable:
+		return "100755"
+	case vcs.ModeSymlink:
+		return "120000"
+	default:
+		return "100644"
+	}
+}
+
+// CheckoutBranch makes ApplyCommit continue an existing branch
+func (w *Writer) CheckoutBranch(name string) error {
+	tip, ok := w.branches[name]
+	if !ok {
+		return fmt.Errorf("branch %s does not exist", name)
+	}
+	w.branch = name
+	w.lastCommit = tip
+	return nil
+}
+
+// CreateBranch creates a new branch
+func (w *Writer) CreateBranch(name, revision string) error {
+	if w.out == nil {
+		return fmt.Errorf("stream not initialized")
+	}
+
+	ref, err := w.resolve(revision)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w.out, "reset refs/heads/%s\nfrom %s\n\n", name, ref)
+	w.branches[name] = ref
+	return nil
+}
+
+// DeleteBranch removes a branch reference
+func (w *Writer) DeleteBranch(name string) error {
+	if w.out == nil {
+		return fmt.Errorf("stream not initialized")
+	}
+
+	fmt.Fprintf(w.out, "reset refs/heads/%s\nfrom %s\n\n", name, nullRevision)
+	delete(w.branches, name)
+	return nil
+}
+
+// BranchExists reports whether the stream has created the named branch
+func (w *Writer) BranchExists(name string) bool {
+	_, ok := w.branches[name]
+	return ok
+}
+
+// CreateTag creates a new tag. An empty message creates a lightweight tag,
+// any other message an annotated tag by the author of the tagged commit.
+func (w *Writer) CreateTag(name, revision, message string) error {
+	if message != "" {
+		return w.CreateAnnotatedTag(name, revision, message, vcs.Signature{})
+	}
+	if w.out == nil {
+		return fmt.Errorf("stream not initialized")
+	}
+
+	ref, err := w.resolve(revision)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w.out, "reset refs/tags/%s\nfrom %s\n\n", name, ref)
+	return nil
+}
+
+// CreateAnnotatedTag creates an annotated tag. The tagger defaults to the
+// author of the tagged commit and the tag date to the commit date.
+func (w *Writer) CreateAnnotatedTag(name, revision, message string, tagger vcs.Signature) error {
+	if w.out == nil {
+		return fmt.Errorf("stream not initialized")
+	}
+
+	ref, err := w.resolve(revision)
+	if err != nil {
+		return err
+	}
+
+	// Commits from outside the stream have no known author
+	signature, ok := w.authors[ref]
+	if !ok {
+		signature = vcs.Signature{Name: "git-migrator", When: time.Now()}
+	}
+	if tagger.Name != "" {
+		signature.Name, signature.Email = tagger.Name, tagger.Email
+	}
+	if !tagger.When.IsZero() {
+		signature.When = tagger.When
+	}
+
+	fmt.Fprintf(w.out, "tag %s\nfrom %s\ntagger %s\n", name, ref, formatSignature(signature))
+	w.writeData([]byte(message))
+	return nil
+}
+
+// LastCommitHash returns the mark of the most recently written commit, or
+// an empty string if no commit has been written yet
+func (w *Writer) LastCommitHash() string {
+	return w.lastCommit
+}
+
+// Close ends the stream and closes the stream file
+func (w *Writer) Close() error {
+	if w.out == nil {
+		return nil
+	}
+
+	fmt.Fprintf(w.out, "done\n")
+	err := w.out.Flush()
+	w.out = nil
+	if w.file != nil {
+		if closeErr := w.file.Close(); err == nil {
+			err = closeErr
+		}
+		w.file = nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write stream: %w", err)
+	}
+	return nil
+}
+
+// resolve converts "HEAD" or a branch name into the mark of its commit;
+// marks and other commit-ish values are passed to git fast-import as is
+func (w *Writer) resolve(revision string) (string, error) {
+	if revision == "HEAD" {
+		if w.lastCommit == "" {
+			return "", fmt.Errorf("no commits written yet")
+		}
+		return w.lastCommit, nil
+	}
+	if tip, ok := w.branches[revision]; ok {
+		return tip, nil
+	}
+	if revision == "" || strings.ContainsAny(revision, " \n") {
+		return "", fmt.Errorf("invalid revision %q", revision)
+	}
+	return revision, nil
+}
+
+// writeBlob writes file content once and returns its mark
+func (w *Writer) writeBlob(content []byte) string {
+	sum := sha1.Sum(content)
+	if mark, ok := w.blobs[sum]; ok {
+		return mark
+	}
+
+	mark := w.newMark()
+	fmt.Fprintf(w.out, "blob\nmark %s\n", mark)
+	w.writeData(content)
+	w.blobs[sum] = mark
+	return mark
+}
+
+// writeData writes a length-prefixed data block
+func (w *Writer) writeData(data []byte) {
+	fmt.Fprintf(w.out, "data %d\n", len(data))
+	_, _ = w.out.Write(data)
+	_ = w.out.WriteByte('\n')
+}
+
+func (w *Writer) newMark() string {
+	w.nextMark++
+	return fmt.Sprintf(":%d", w.nextMark)
+}
+
+// formatSignature formats an identity and time the way fast-import expects
+func formatSignature(signature vcs.Signature) string {
+	clean := strings.NewReplacer("<", "", ">", "", "\n", " ")
+	name := strings.TrimSpace(clean.Replace(signature.Name))
+	email := strings.TrimSpace(clean.Replace(signature.Email))
+	when := signature.When
+	if name == "" {
+		return fmt.Sprintf("<%s> %d %s", email, when.Unix(), when.Format("-0700"))
+	}
+	return fmt.Sprintf("%s <%s> %d %s", name, email, when.Unix(), when.Format("-0700"))
+}
+
+// quotePath quotes a path in C style when fast-import requires it: when
+// it starts with a double quote or contains a newline
+func quotePath(path string) string {
+	if !strings.HasPrefix(path, `"`) && !strings.Contains(path, "\n") {
+		return path
+	}
+
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c == '\n':
+			b.WriteString(`\n`)
+		case c < 0x20 || c == 0x7f:
+			fmt.Fprintf(&b, `\%03o`, c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,217 @@
+package fastimport
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+	"github.com/stretchr/testify/require"
+)
+
+// writeStream runs fn against a writer and returns the resulting stream
+func writeStream(t *testing.T, fn func(w *Writer)) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "out", "stream.fi")
+	w := NewWriter()
+	require.NoError(t, w.Init(path))
+	fn(w)
+	require.NoError(t, w.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func testCommit(revision, message string, files ...vcs.FileChange) *vcs.Commit {
+	return &vcs.Commit{
+		Revision: revision,
+		Author:   "Jane Doe",
+		Email:    "jane@example.com",
+		Date:     time.Date(2020, 1, 2, 3, 4, 5, 0, time.FixedZone("", -7*3600)),
+		Message:  message,
+		Files:    files,
+	}
+}
+
+func TestWriter_Commits(t *testing.T) {
+	stream := writeStream(t, func(w *Writer) {
+		require.NoError(t, w.ApplyCommit(testCommit("1", "Initial",
+			vcs.FileChange{Path: "a.txt", Action: vcs.ActionAdd, Content: []byte("hello\n")},
+			vcs.FileChange{Path: "b.txt", Action: vcs.ActionAdd, Content: []byte("hello\n")},
+		)))
+		require.Equal(t, ":2", w.LastCommitHash())
+
+		require.NoError(t, w.ApplyCommit(testCommit("2", "Remove b",
+			vcs.FileChange{Path: "b.txt", Action: vcs.ActionDelete},
+		)))
+		require.Equal(t, ":3", w.LastCommitHash())
+	})
+
+	expected := "feature done\n" +
+		"blob\nmark :1\ndata 6\nhello\n\n" +
+		"commit refs/heads/master\nmark :2\n" +
+		"author Jane Doe <jane@example.com> 1577959445 -0700\n" +
+		"committer Jane Doe <jane@example.com> 1577959445 -0700\n" +
+		"data 7\nInitial\n" +
+		"M 100644 :1 a.txt\nM 100644 :1 b.txt\n\n" +
+		"commit refs/heads/master\nmark :3\n" +
+		"author Jane Doe <jane@example.com> 1577959445 -0700\n" +
+		"committer Jane Doe <jane@example.com> 1577959445 -0700\n" +
+		"data 8\nRemove b\n" +
+		"D b.txt\n\n" +
+		"done\n"
+	require.Equal(t, expected, stream)
+}
+
+func TestWriter_FileModes(t *testing.T) {
+	stream := writeStream(t, func(w *Writer) {
+		require.NoError(t, w.ApplyCommit(testCommit("1", "Modes",
+			vcs.FileChange{Path: "run.sh", Action: vcs.ActionAdd, Content: []byte("#!/bin/sh\n"), Mode: vcs.ModeExecutable},
+			vcs.FileChange{Path: "current", Action: vcs.ActionAdd, Content: []byte("run.sh"), Mode: vcs.ModeSymlink},
+		)))
+	})
+
+	require.Contains(t, stream, "M 100755 :1 run.sh\nM 120000 :2 current\n\n")
+}
+
+func TestWriter_Branches(t *testing.T) {
+	stream := writeStream(t, func(w *Writer) {
+		require.NoError(t, w.ApplyCommit(testCommit("1.1", "Trunk")))
+		require.NoError(t, w.ApplyCommitToBranch(testCommit("1.1.2.1", "Branch"), "dev", ":1"))
+		require.NoError(t, w.ApplyCommitToBranch(testCommit("1.1.2.2", "More"), "dev", ":1"))
+		require.NoError(t, w.ApplyCommitWithParents(testCommit("1.2", "Merge"), "master", "master", "dev"))
+
+		require.True(t, w.BranchExists("dev"))
+		require.NoError(t, w.CheckoutBranch("master"))
+		require.Equal(t, ":4", w.LastCommitHash())
+		require.Error(t, w.CheckoutBranch("missing"))
+
+		require.NoError(t, w.CreateBranch("release", "HEAD"))
+		require.NoError(t, w.DeleteBranch("dev"))
+		require.False(t, w.BranchExists("dev"))
+	})
+
+	require.Contains(t, stream, "commit refs/heads/dev\nmark :2\n")
+	require.Contains(t, stream, "data 6\nBranch\nfrom :1\n\n")
+	require.Contains(t, stream, "data 4\nMore\n\n")
+	require.Contains(t, stream, "data 5\nMerge\nfrom :1\nmerge :3\n\n")
+	require.Contains(t, stream, "reset refs/heads/release\nfrom :4\n\n")
+	require.Contains(t, stream, "reset refs/heads/dev\nfrom "+nullRevision+"\n\n")
+}
+
+func TestWriter_Tags(t *testing.T) {
+	stream := writeStream(t, func(w *Writer) {
+		require.NoError(t, w.ApplyCommit(testCommit("1", "Initial")))
+		require.NoError(t, w.CreateTag("v1.0", ":1", ""))
+		require.NoError(t, w.CreateTag("v1.1", "master", "Release 1.1"))
+		require.Error(t, w.CreateTag("bad", "", ""))
+	})
+
+	require.Contains(t, stream, "reset refs/tags/v1.0\nfrom :1\n\n")
+	require.Contains(t, stream, "tag v1.1\nfrom :1\n"+
+		"tagger Jane Doe <jane@example.com> 1577959445 -0700\n"+
+		"data 11\nRelease 1.1\n")
+}
+
+func TestWriter_AnnotatedTagger(t *testing.T) {
+	stream := writeStream(t, func(w *Writer) {
+		require.NoError(t, w.ApplyCommit(testCommit("1", "Initial")))
+		require.NoError(t, w.CreateAnnotatedTag("v1", ":1", "Tagged", vcs.Signature{
+			Name: "Release Bot", Email: "release@example.com",
+		}))
+		require.NoError(t, w.CreateAnnotatedTag("v2", ":1", "Dated", vcs.Signature{
+			When: time.Unix(1000, 0).UTC(),
+		}))
+	})
+
+	require.Contains(t, stream, "tag v1\nfrom :1\ntagger Release Bot <release@example.com> 1577959445 -0700\n")
+	require.Contains(t, stream, "tag v2\nfrom :1\ntagger Jane Doe <jane@example.com> 1000 +0000\n")
+}
+
+func TestWriter_NotInitialized(t *testing.T) {
+	w := NewWriter()
+	if err := w.ApplyCommit(testCommit("1", "Initial")); err == nil {
+		t.Error("expected error for uninitialized writer")
+	}
+	if err := w.CreateBranch("dev", "HEAD"); err == nil {
+		t.Error("expected error for uninitialized writer")
+	}
+	if err := w.CreateTag("v1", "HEAD", ""); err == nil {
+		t.Error("expected error for uninitialized writer")
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("Close of uninitialized writer failed: %v", err)
+	}
+}
+
+func TestFormatSignature(t *testing.T) {
+	when := time.Unix(1000, 0).In(time.FixedZone("", 5*3600+30*60))
+	tests := []struct {
+		name, email, expected string
+	}{
+		{"Jane Doe", "jane@example.com", "Jane Doe <jane@example.com> 1000 +0530"},
+		{"<Jane>", "<jane@example.com>", "Jane <jane@example.com> 1000 +0530"},
+		{"", "jane", "<jane> 1000 +0530"},
+	}
+	for _, tt := range tests {
+		if got := formatSignature(vcs.Signature{Name: tt.name, Email: tt.email, When: when}); got != tt.expected {
+			t.Errorf("formatSignature(%q, %q) = %q, want %q", tt.name, tt.email, got, tt.expected)
+		}
+	}
+}
+
+func TestQuotePath(t *testing.T) {
+	tests := map[string]string{
+		"dir/file.txt":  "dir/file.txt",
+		"with space.c":  "with space.c",
+		`back\slash`:    `back\slash`,
+		`"quoted".txt`:  `"\"quoted\".txt"`,
+		"line\nbreak":   `"line\nbreak"`,
+		"tab\tand\nnew": `"tab\011and\nnew"`,
+	}
+	for path, expected := range tests {
+		if got := quotePath(path); got != expected {
+			t.Errorf("quotePath(%q) = %q, want %q", path, got, expected)
+		}
+	}
+}
+
+func TestWriter_GitFastImport(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	stream := writeStream(t, func(w *Writer) {
+		require.NoError(t, w.ApplyCommit(testCommit("1", "Initial",
+			vcs.FileChange{Path: "src/main.c", Action: vcs.ActionAdd, Content: []byte("int main;\n")},
+		)))
+		require.NoError(t, w.ApplyCommitToBranch(testCommit("2", "Fixup",
+			vcs.FileChange{Path: "src/main.c", Action: vcs.ActionDelete},
+		), "TAG.FIXUP", ":2"))
+		require.NoError(t, w.CreateTag("v1", w.LastCommitHash(), "Release"))
+		require.NoError(t, w.CheckoutBranch("master"))
+		require.NoError(t, w.DeleteBranch("TAG.FIXUP"))
+	})
+
+	repo := t.TempDir()
+	git := func(stdin string, args ...string) string {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		cmd.Stdin = strings.NewReader(stdin)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+		return string(out)
+	}
+	git("", "init", "--quiet")
+	git(stream, "fast-import", "--quiet")
+
+	refs := git("", "for-each-ref", "--format=%(refname) %(objecttype)")
+	require.Equal(t, "refs/heads/master commit\nrefs/tags/v1 tag\n", refs)
+	require.Equal(t, "int main;\n", git("", "show", "master:src/main.c"))
+	require.Equal(t, "Jane Doe\n", git("", "log", "-1", "--format=%an", "v1"))
+}
@@ -0,0 +1,178 @@
+package git
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+// treeBuilder edits a Git tree in memory. Subtrees are read from the object
+// database only when a change reaches into them, and only the trees along
+// changed paths are written back, so the cost of a commit depends on the
+// files it changes rather than on the size of the tree.
+type treeBuilder struct {
+	storer storer.EncodedObjectStorer
+	root   *treeNode
+}
+
+// treeNode is a directory of the tree being built
+type treeNode struct {
+	hash    plumbing.Hash         // Stored tree, valid unless dirty
+	entries map[string]*treeEntry // Nil until loaded from the stored tree
+	dirty   bool
+}
+
+// treeEntry is a file or directory within a treeNode
+type treeEntry struct {
+	mode filemode.FileMode
+	hash plumbing.Hash // Blob or stored tree
+	dir  *treeNode     // Loaded directory, nil for files and unloaded trees
+}
+
+// newTreeBuilder starts editing the stored tree with the given hash, or an
+// empty tree when the hash is zero
+func newTreeBuilder(s storer.EncodedObjectStorer, root plumbing.Hash) *treeBuilder {
+	node := &treeNode{hash: root}
+	if root.IsZero() {
+		node.entries = make(map[string]*treeEntry)
+		node.dirty = true
+	}
+	return &treeBuilder{storer: s, root: node}
+}
+
+// setFile points a path at a blob, creating parent directories and
+// replacing any file or directory already there
+func (b *treeBuilder) setFile(path string, mode filemode.FileMode, blob plumbing.Hash) error {
+	names := strings.Split(path, "/")
+	node := b.root
+	for _, name := range names[:len(names)-1] {
+		if err := b.load(node); err != nil {
+			return err
+		}
+		node.dirty = true
+
+		entry, ok := node.entries[name]
+		if !ok || entry.mode != filemode.Dir {
+			entry = &treeEntry{mode: filemode.Dir, dir: &treeNode{entries: make(map[string]*treeEntry), dirty: true}}
+			node.entries[name] = entry
+		}
+		if entry.dir == nil {
+			entry.dir = &treeNode{hash: entry.hash}
+		}
+		node = entry.dir
+	}
+
+	if err := b.load(node); err != nil {
+		return err
+	}
+	node.dirty = true
+	node.entries[names[len(names)-1]] = &treeEntry{mode: mode, hash: blob}
+	return nil
+}
+
+// removeFile removes a path and any directories left empty by its removal.
+// It reports whether the path existed.
+func (b *treeBuilder) removeFile(path string) (bool, error) {
+	return b.remove(b.root, strings.Split(path, "/"))
+}
+
+func (b *treeBuilder) remove(node *treeNode, names []string) (bool, error) {
+	if err := b.load(node); err != nil {
+		return false, err
+	}
+	entry, ok := node.entries[names[0]]
+	if !ok {
+		return false, nil
+	}
+
+	if len(names) > 1 {
+		if entry.mode != filemode.Dir {
+			return false, nil
+		}
+		if entry.dir == nil {
+			entry.dir = &treeNode{hash: entry.hash}
+		}
+		removed, err := b.remove(entry.dir, names[1:])
+		if !removed || err != nil {
+			return removed, err
+		}
+		node.dirty = true
+		if len(entry.dir.entries) > 0 {
+			return true, nil
+		}
+	}
+
+	// Git does not store empty directories
+	delete(node.entries, names[0])
+	node.dirty = true
+	return true, nil
+}
+
+// write stores the changed trees and returns the hash of the root tree
+func (b *treeBuilder) write() (plumbing.Hash, error) {
+	return b.writeNode(b.root)
+}
+
+func (b *treeBuilder) writeNode(node *treeNode) (plumbing.Hash, error) {
+	if !node.dirty {
+		return node.hash, nil
+	}
+
+	tree := &object.Tree{}
+	for name, entry := range node.entries {
+		if entry.dir != nil {
+			hash, err := b.writeNode(entry.dir)
+			if err != nil {
+				return plumbing.ZeroHash, err
+			}
+			entry.hash = hash
+		}
+		tree.Entries = append(tree.Entries, object.TreeEntry{Name: name, Mode: entry.mode, Hash: entry.hash})
+	}
+
+	// Git orders entries as if directory names ended with a slash
+	sortKey := func(e object.TreeEntry) string {
+		if e.Mode == filemode.Dir {
+			return e.Name + "/"
+		}
+		return e.Name
+	}
+	sort.Slice(tree.Entries, func(i, j int) bool {
+		return sortKey(tree.Entries[i]) < sortKey(tree.Entries[j])
+	})
+
+	obj := b.storer.NewEncodedObject()
+	if err := tree.Encode(obj); err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to encode tree: %w", err)
+	}
+	hash, err := b.storer.SetEncodedObject(obj)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to store tree: %w", err)
+	}
+
+	node.hash = hash
+	node.dirty = false
+	return hash, nil
+}
+
+// load reads the entries of a directory from its stored tree
+func (b *treeBuilder) load(node *treeNode) error {
+	if node.entries != nil {
+		return nil
+	}
+
+	tree, err := object.GetTree(b.storer, node.hash)
+	if err != nil {
+		return fmt.Errorf("failed to read tree %s: %w", node.hash, err)
+	}
+	node.entries = make(map[string]*treeEntry, len(tree.Entries))
+	for _, e := range tree.Entries {
+		node.entries[e.Name] = &treeEntry{mode: e.Mode, hash: e.Hash}
+	}
+	return nil
+}
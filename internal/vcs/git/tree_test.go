@@ -0,0 +1,116 @@
+package git
+
+import (
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+// treeFiles lists the files of a stored tree with their content
+func treeFiles(t *testing.T, s *memory.Storage, hash plumbing.Hash) map[string]string {
+	t.Helper()
+
+	tree, err := object.GetTree(s, hash)
+	require.NoError(t, err)
+	files := make(map[string]string)
+	require.NoError(t, tree.Files().ForEach(func(f *object.File) error {
+		content, err := f.Contents()
+		files[f.Name] = content
+		return err
+	}))
+	return files
+}
+
+func storeTestBlob(t *testing.T, s *memory.Storage, content string) plumbing.Hash {
+	t.Helper()
+
+	obj := s.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+	writer, err := obj.Writer()
+	require.NoError(t, err)
+	_, err = writer.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	hash, err := s.SetEncodedObject(obj)
+	require.NoError(t, err)
+	return hash
+}
+
+func TestTreeBuilder(t *testing.T) {
+	s := memory.NewStorage()
+	b := newTreeBuilder(s, plumbing.ZeroHash)
+	require.NoError(t, b.setFile("README", filemode.Regular, storeTestBlob(t, s, "readme")))
+	require.NoError(t, b.setFile("src/main.c", filemode.Regular, storeTestBlob(t, s, "main")))
+	require.NoError(t, b.setFile("src/lib/util.c", filemode.Regular, storeTestBlob(t, s, "util")))
+	require.NoError(t, b.setFile("doc/guide.txt", filemode.Regular, storeTestBlob(t, s, "guide")))
+
+	first, err := b.write()
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"README":         "readme",
+		"src/main.c":     "main",
+		"src/lib/util.c": "util",
+		"doc/guide.txt":  "guide",
+	}, treeFiles(t, s, first))
+
+	// A new builder loads only what it changes and leaves other trees alone
+	b = newTreeBuilder(s, first)
+	removed, err := b.removeFile("src/lib/util.c")
+	require.NoError(t, err)
+	require.True(t, removed)
+	removed, err = b.removeFile("src/missing.c")
+	require.NoError(t, err)
+	require.False(t, removed)
+	require.NoError(t, b.setFile("src/main.c", filemode.Regular, storeTestBlob(t, s, "main v2")))
+
+	second, err := b.write()
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"README":        "readme",
+		"src/main.c":    "main v2",
+		"doc/guide.txt": "guide",
+	}, treeFiles(t, s, second))
+	require.Nil(t, b.root.entries["doc"].dir, "unchanged subtree should not be loaded")
+
+	// Removing the last file of a directory removes the directory
+	removed, err = b.removeFile("doc/guide.txt")
+	require.NoError(t, err)
+	require.True(t, removed)
+	_, ok := b.root.entries["doc"]
+	require.False(t, ok)
+}
+
+func TestTreeBuilderEntryOrder(t *testing.T) {
+	s := memory.NewStorage()
+	blob := storeTestBlob(t, s, "x")
+	b := newTreeBuilder(s, plumbing.ZeroHash)
+	for _, path := range []string{"a/file", "a-b", "a.c", "b"} {
+		require.NoError(t, b.setFile(path, filemode.Regular, blob))
+	}
+
+	hash, err := b.write()
+	require.NoError(t, err)
+	tree, err := object.GetTree(s, hash)
+	require.NoError(t, err)
+
+	var names []string
+	for _, e := range tree.Entries {
+		names = append(names, e.Name)
+	}
+	require.Equal(t, []string{"a-b", "a.c", "a", "b"}, names)
+}
+
+func TestTreeBuilderReplacesFileWithDirectory(t *testing.T) {
+	s := memory.NewStorage()
+	b := newTreeBuilder(s, plumbing.ZeroHash)
+	require.NoError(t, b.setFile("lib", filemode.Regular, storeTestBlob(t, s, "file")))
+	require.NoError(t, b.setFile("lib/util.c", filemode.Regular, storeTestBlob(t, s, "util")))
+
+	hash, err := b.write()
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"lib/util.c": "util"}, treeFiles(t, s, hash))
+}
@@ -10,21 +10,49 @@ import (
 	"github.com/adamf123git/git-migrator/internal/vcs"
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/go-git/go-git/v5/plumbing/storer"
 )
 
+// WriterOptions configures how a Writer creates commits
+type WriterOptions struct {
+	// TreeBuilder writes blobs, trees and commits straight to the object
+	// database instead of staging files through the worktree. Only the trees
+	// along changed paths are rewritten, which is much faster for large
+	// repositories. The worktree and index are only brought up to date with
+	// HEAD when the writer is closed.
+	TreeBuilder bool
+
+	// Bare creates a repository without a worktree. Commits are written
+	// with the tree builder. Open detects bare repositories by itself.
+	Bare bool
+
+	// InitialBranch is the branch HEAD refers to in a new repository.
+	// Defaults to master.
+	InitialBranch string
+}
+
 // Writer implements VCSWriter for Git repositories
 type Writer struct {
 	path       string
+	options    WriterOptions
 	repo       *git.Repository
 	worktree   *git.Worktree
 	lastCommit plumbing.Hash
+	tree       *treeBuilder  // Tree of treeCommit, kept between commits
+	treeCommit plumbing.Hash // Commit the tree builder holds the tree of
 }
 
 // NewWriter creates a new Git repository writer
 func NewWriter() *Writer {
-	return &Writer{}
+	return NewWriterWithOptions(WriterOptions{})
+}
+
+// NewWriterWithOptions creates a Git repository writer with custom options
+func NewWriterWithOptions(options WriterOptions) *Writer {
+	return &Writer{options: options}
 }
 
 // Init creates a new repository at the given path
@@ -35,7 +63,14 @@ func (w *Writer) Init(path string) error {
 	}
 
 	// Initialize repository
-	repo, err := git.PlainInit(path, false)
+	initOptions := git.InitOptions{}
+	if w.options.InitialBranch != "" {
+		initOptions.DefaultBranch = plumbing.NewBranchReferenceName(w.options.InitialBranch)
+	}
+	repo, err := git.PlainInitWithOptions(path, &git.PlainInitOptions{
+		InitOptions: initOptions,
+		Bare:        w.options.Bare,
+	})
 	if err != nil {
 		return fmt.Errorf("failed to init repository: %w", err)
 	}
@@ -46,6 +81,9 @@ func (w *Writer) Init(path string) error {
 	// Ensure .git/objects directory structure exists to prevent race conditions
 	// when creating loose objects during concurrent operations
 	objectsDir := filepath.Join(path, ".git", "objects")
+	if w.options.Bare {
+		objectsDir = filepath.Join(path, "objects")
+	}
 	subdirs := []string{
 		filepath.Join(objectsDir, "info"),
 		filepath.Join(objectsDir, "pack"),
@@ -55,6 +93,9 @@ func (w *Writer) Init(path string) error {
 			return fmt.Errorf("failed to create objects directory %s: %w", dir, err)
 		}
 	}
+	if w.options.Bare {
+		return nil
+	}
 
 	// Get worktree
 	worktree, err := repo.Worktree()
@@ -151,9 +192,12 @@ func (w *Writer) IsRepo(path string) bool {
 
 // ApplyCommit applies a commit to the repository
 func (w *Writer) ApplyCommit(commit *vcs.Commit) error {
-	if w.repo == nil || w.worktree == nil {
+	if !w.initialized() {
 		return fmt.Errorf("repository not initialized")
 	}
+	if w.bypassWorktree() {
+		return w.buildCommit(commit)
+	}
 
 	// Process file changes
 	for _, fc := range commit.Files {
@@ -168,7 +212,7 @@ func (w *Writer) ApplyCommit(commit *vcs.Commit) error {
 			}
 
 			// Write file
-			if err := os.WriteFile(fullPath, fc.Content, 0644); err != nil {
+			if err := writeFile(fullPath, fc); err != nil {
 				return fmt.Errorf("failed to write file: %w", err)
 			}
 
@@ -214,37 +258,367 @@ func (w *Writer) ApplyCommit(commit *vcs.Commit) error {
 	return nil
 }
 
-// CreateBranch creates a new branch
-func (w *Writer) CreateBranch(name, revision string) error {
+// writeFile writes a file change to the worktree with its mode. A file of
+// another kind at the path is replaced rather than written through.
+func writeFile(path string, fc vcs.FileChange) error {
+	if info, err := os.Lstat(path); err == nil && (info.Mode()&os.ModeSymlink != 0 || fc.Mode == vcs.ModeSymlink) {
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+
+	switch fc.Mode {
+	case vcs.ModeSymlink:
+		return os.Symlink(string(fc.Content), path)
+	case vcs.ModeExecutable:
+		if err := os.WriteFile(path, fc.Content, 0755); err != nil {
+			return err
+		}
+		return os.Chmod(path, 0755)
+	default:
+		if err := os.WriteFile(path, fc.Content, 0644); err != nil {
+			return err
+		}
+		return os.Chmod(path, 0644)
+	}
+}
+
+// fileMode returns the Git file mode of a file change
+func fileMode(mode vcs.FileMode) filemode.FileMode {
+	switch mode {
+	case vcs.ModeExecutable:
+		return filemode.Executable
+	case vcs.ModeSymlink:
+		return filemode.Symlink
+	default:
+		return filemode.Regular
+	}
+}
+
+// initialized reports whether the writer has a repository it can commit to
+func (w *Writer) initialized() bool {
+	return w.repo != nil && (w.worktree != nil || w.bypassWorktree())
+}
+
+// bypassWorktree reports whether commits are written with the tree builder
+func (w *Writer) bypassWorktree() bool {
+	return w.options.TreeBuilder || w.options.Bare
+}
+
+// buildCommit creates a commit on HEAD without touching the worktree, by
+// applying the file changes to the tree of the HEAD commit
+func (w *Writer) buildCommit(commit *vcs.Commit) error {
+	var parents []plumbing.Hash
+	parent := plumbing.ZeroHash
+	head, err := w.repo.Head()
+	switch {
+	case err == nil:
+		parent = head.Hash()
+		parents = append(parents, parent)
+	case err != plumbing.ErrReferenceNotFound:
+		return fmt.Errorf("failed to get HEAD: %w", err)
+	}
+
+	if w.tree == nil || w.treeCommit != parent {
+		root := plumbing.ZeroHash
+		if !parent.IsZero() {
+			parentCommit, err := w.repo.CommitObject(parent)
+			if err != nil {
+				return fmt.Errorf("failed to get commit: %w", err)
+			}
+			root = parentCommit.TreeHash
+		}
+		w.tree = newTreeBuilder(w.repo.Storer, root)
+	}
+
+	for _, fc := range commit.Files {
+		switch fc.Action {
+		case vcs.ActionAdd, vcs.ActionModify:
+			blob, err := w.storeBlob(fc.Content)
+			if err != nil {
+				return err
+			}
+			if err := w.tree.setFile(fc.Path, fileMode(fc.Mode), blob); err != nil {
+				w.tree = nil
+				return err
+			}
+
+		case vcs.ActionDelete:
+			// Deleting a path the tree does not have leaves it unchanged
+			if _, err := w.tree.removeFile(fc.Path); err != nil {
+				w.tree = nil
+				return err
+			}
+		}
+	}
+
+	treeHash, err := w.tree.write()
+	if err != nil {
+		w.tree = nil
+		return err
+	}
+
+	signature := object.Signature{Name: commit.Author, Email: commit.Email, When: commit.Date}
+	obj := w.repo.Storer.NewEncodedObject()
+	err = (&object.Commit{
+		Author:       signature,
+		Committer:    signature,
+		Message:      commit.Message,
+		TreeHash:     treeHash,
+		ParentHashes: parents,
+	}).Encode(obj)
+	if err != nil {
+		return fmt.Errorf("failed to encode commit: %w", err)
+	}
+	hash, err := w.repo.Storer.SetEncodedObject(obj)
+	if err != nil {
+		return fmt.Errorf("failed to create commit: %w", err)
+	}
+
+	// Advance the branch HEAD refers to, or HEAD itself when detached
+	refName := plumbing.HEAD
+	if ref, err := w.repo.Storer.Reference(plumbing.HEAD); err == nil && ref.Type() == plumbing.SymbolicReference {
+		refName = ref.Target()
+	}
+	if err := w.repo.Storer.SetReference(plumbing.NewHashReference(refName, hash)); err != nil {
+		return fmt.Errorf("failed to update %s: %w", refName, err)
+	}
+
+	w.lastCommit = hash
+	w.treeCommit = hash
+	return nil
+}
+
+// storeBlob writes file content to the object database unless it is
+// already there and returns its hash
+func (w *Writer) storeBlob(content []byte) (plumbing.Hash, error) {
+	hash := plumbing.ComputeHash(plumbing.BlobObject, content)
+	if w.repo.Storer.HasEncodedObject(hash) == nil {
+		return hash, nil
+	}
+
+	obj := w.repo.Storer.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+	obj.SetSize(int64(len(content)))
+	writer, err := obj.Writer()
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to write blob: %w", err)
+	}
+	if _, err := writer.Write(content); err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to write blob: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to write blob: %w", err)
+	}
+	if _, err := w.repo.Storer.SetEncodedObject(obj); err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to store blob: %w", err)
+	}
+	return hash, nil
+}
+
+// ApplyCommitToBranch applies a commit on top of the named branch instead of
+// the current HEAD. A branch that does not exist yet is forked from parent,
+// which accepts anything CreateBranch does; when parent is empty or does not
+// name an existing commit the branch starts without history.
+func (w *Writer) ApplyCommitToBranch(commit *vcs.Commit, branch, parent string) error {
+	if !w.initialized() {
+		return fmt.Errorf("repository not initialized")
+	}
+
+	if err := w.switchBranch(branch, parent); err != nil {
+		return fmt.Errorf("failed to switch to branch %s: %w", branch, err)
+	}
+
+	return w.ApplyCommit(commit)
+}
+
+// CheckoutBranch makes an existing branch the current HEAD and worktree
+func (w *Writer) CheckoutBranch(name string) error {
+	if !w.initialized() {
+		return fmt.Errorf("repository not initialized")
+	}
+
+	refName := plumbing.NewBranchReferenceName(name)
+	ref, err := w.repo.Reference(refName, true)
+	if err != nil {
+		return err
+	}
+	if err := w.checkout(refName, ref.Hash()); err != nil {
+		return err
+	}
+
+	// HEAD now refers to the branch tip rather than the last applied commit
+	w.lastCommit = ref.Hash()
+	return nil
+}
+
+// DeleteBranch removes a branch reference
+func (w *Writer) DeleteBranch(name string) error {
 	if w.repo == nil {
 		return fmt.Errorf("repository not initialized")
 	}
 
-	// Resolve revision to hash
-	var hash plumbing.Hash
-	if revision == "HEAD" {
-		if w.lastCommit.IsZero() {
-			// Get HEAD reference
-			head, err := w.repo.Head()
-			if err != nil {
-				return fmt.Errorf("failed to get HEAD: %w", err)
+	return w.repo.Storer.RemoveReference(plumbing.NewBranchReferenceName(name))
+}
+
+// BranchExists reports whether a branch with the given name has commits
+func (w *Writer) BranchExists(name string) bool {
+	if w.repo == nil {
+		return false
+	}
+
+	_, err := w.repo.Reference(plumbing.NewBranchReferenceName(name), true)
+	return err == nil
+}
+
+// LastCommitHash returns the hash of the most recently applied commit, or an
+// empty string if no commit has been applied yet
+func (w *Writer) LastCommitHash() string {
+	if w.lastCommit.IsZero() {
+		return ""
+	}
+	return w.lastCommit.String()
+}
+
+// switchBranch points HEAD and the worktree at the named branch, creating
+// it from parent first if needed
+func (w *Writer) switchBranch(branch, parent string) error {
+	refName := plumbing.NewBranchReferenceName(branch)
+
+	// Nothing to do if HEAD already refers to the branch, even an unborn one
+	head, err := w.repo.Storer.Reference(plumbing.HEAD)
+	if err == nil && head.Type() == plumbing.SymbolicReference && head.Target() == refName {
+		return nil
+	}
+
+	if ref, err := w.repo.Reference(refName, true); err == nil {
+		return w.checkout(refName, ref.Hash())
+	}
+
+	if parent != "" {
+		if hash, err := w.resolveHash(parent); err == nil {
+			if _, err := w.repo.CommitObject(hash); err == nil {
+				return w.checkout(refName, hash)
 			}
-			hash = head.Hash()
-		} else {
-			hash = w.lastCommit
 		}
-	} else {
-		// Parse revision
-		h, err := w.repo.ResolveRevision(plumbing.Revision(revision))
+	}
+
+	return w.orphanBranch(refName)
+}
+
+// checkout points the branch and HEAD at a commit and makes the index and
+// worktree match it. Unlike a forced worktree checkout only tracked files
+// are touched, so untracked files such as a migration state database in the
+// target directory survive switching branches.
+func (w *Writer) checkout(refName plumbing.ReferenceName, hash plumbing.Hash) error {
+	if w.bypassWorktree() {
+		if err := w.repo.Storer.SetReference(plumbing.NewHashReference(refName, hash)); err != nil {
+			return err
+		}
+		return w.repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, refName))
+	}
+
+	if err := w.repo.Storer.SetReference(plumbing.NewHashReference(refName, hash)); err != nil {
+		return err
+	}
+	if err := w.repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, refName)); err != nil {
+		return err
+	}
+	return w.resetWorktree(hash)
+}
+
+// resetWorktree makes the index and worktree match a commit, touching only
+// the files tracked now or in the commit
+func (w *Writer) resetWorktree(hash plumbing.Hash) error {
+	commit, err := w.repo.CommitObject(hash)
+	if err != nil {
+		return fmt.Errorf("failed to get commit: %w", err)
+	}
+
+	// Files tracked now or in the target commit
+	seen := make(map[string]bool)
+	var files []string
+	idx, err := w.repo.Storer.Index()
+	if err != nil {
+		return fmt.Errorf("failed to read index: %w", err)
+	}
+	for _, entry := range idx.Entries {
+		seen[entry.Name] = true
+		files = append(files, entry.Name)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return fmt.Errorf("failed to get tree: %w", err)
+	}
+	err = tree.Files().ForEach(func(f *object.File) error {
+		if !seen[f.Name] {
+			files = append(files, f.Name)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list files: %w", err)
+	}
+	if len(files) == 0 {
+		return nil
+	}
+
+	return w.worktree.Reset(&git.ResetOptions{Commit: hash, Mode: git.HardReset, Files: files})
+}
+
+// orphanBranch makes HEAD refer to a branch without history and empties the
+// index and worktree so that its first commit only contains its own files
+func (w *Writer) orphanBranch(refName plumbing.ReferenceName) error {
+	if w.bypassWorktree() {
+		return w.repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, refName))
+	}
+
+	idx, err := w.repo.Storer.Index()
+	if err != nil {
+		return fmt.Errorf("failed to read index: %w", err)
+	}
+	for _, entry := range idx.Entries {
+		path := filepath.Join(w.path, filepath.FromSlash(entry.Name))
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove file: %w", err)
+		}
+	}
+	if err := w.repo.Storer.SetIndex(&index.Index{Version: 2}); err != nil {
+		return fmt.Errorf("failed to reset index: %w", err)
+	}
+
+	return w.repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, refName))
+}
+
+// resolveHash resolves "HEAD", a reference name or a commit hash to a hash
+func (w *Writer) resolveHash(revision string) (plumbing.Hash, error) {
+	if revision == "HEAD" {
+		if !w.lastCommit.IsZero() {
+			return w.lastCommit, nil
+		}
+		head, err := w.repo.Head()
 		if err != nil {
-			// Try as raw hash
-			hash = plumbing.NewHash(revision)
-			if hash.IsZero() {
-				return fmt.Errorf("failed to resolve revision: %w", err)
-			}
-		} else {
-			hash = *h
+			return plumbing.ZeroHash, fmt.Errorf("failed to get HEAD: %w", err)
 		}
+		return head.Hash(), nil
+	}
+
+	h, err := w.repo.ResolveRevision(plumbing.Revision(revision))
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to resolve revision %s: %w", revision, err)
+	}
+	return *h, nil
+}
+
+// CreateBranch creates a new branch
+func (w *Writer) CreateBranch(name, revision string) error {
+	if w.repo == nil {
+		return fmt.Errorf("repository not initialized")
+	}
+
+	hash, err := w.resolveHash(revision)
+	if err != nil {
+		return err
 	}
 
 	// Create branch reference
@@ -252,52 +626,55 @@ func (w *Writer) CreateBranch(name, revision string) error {
 	return w.repo.Storer.SetReference(ref)
 }
 
-// CreateTag creates a new tag
+// CreateTag creates a new tag. An empty message creates a lightweight tag,
+// any other message an annotated tag by the author of the tagged commit.
 func (w *Writer) CreateTag(name, revision, message string) error {
+	if message != "" {
+		return w.CreateAnnotatedTag(name, revision, message, vcs.Signature{})
+	}
 	if w.repo == nil {
 		return fmt.Errorf("repository not initialized")
 	}
 
-	// Resolve revision to hash
-	var hash plumbing.Hash
-	if revision == "HEAD" {
-		if w.lastCommit.IsZero() {
-			head, err := w.repo.Head()
-			if err != nil {
-				return fmt.Errorf("failed to get HEAD: %w", err)
-			}
-			hash = head.Hash()
-		} else {
-			hash = w.lastCommit
-		}
-	} else {
-		h, err := w.repo.ResolveRevision(plumbing.Revision(revision))
-		if err != nil {
-			hash = plumbing.NewHash(revision)
-			if hash.IsZero() {
-				return fmt.Errorf("failed to resolve revision: %w", err)
-			}
-		} else {
-			hash = *h
-		}
+	hash, err := w.resolveHash(revision)
+	if err != nil {
+		return err
 	}
 
-	if message == "" {
-		// Lightweight tag
-		ref := plumbing.NewHashReference(plumbing.ReferenceName("refs/tags/"+name), hash)
-		return w.repo.Storer.SetReference(ref)
+	// Lightweight tag
+	ref := plumbing.NewHashReference(plumbing.ReferenceName("refs/tags/"+name), hash)
+	return w.repo.Storer.SetReference(ref)
+}
+
+// CreateAnnotatedTag creates an annotated tag. The tagger defaults to the
+// author of the tagged commit and the tag date to the commit date.
+func (w *Writer) CreateAnnotatedTag(name, revision, message string, tagger vcs.Signature) error {
+	if w.repo == nil {
+		return fmt.Errorf("repository not initialized")
+	}
+
+	hash, err := w.resolveHash(revision)
+	if err != nil {
+		return err
 	}
 
-	// Annotated tag - get commit for tagger info
+	// Get commit for default tagger info
 	commit, err := w.repo.CommitObject(hash)
 	if err != nil {
 		return fmt.Errorf("failed to get commit: %w", err)
 	}
+	signature := commit.Author
+	if tagger.Name != "" {
+		signature.Name, signature.Email = tagger.Name, tagger.Email
+	}
+	if !tagger.When.IsZero() {
+		signature.When = tagger.When
+	}
 
 	// Create tag object using object storage
 	tag := &object.Tag{
 		Name:       name,
-		Tagger:     commit.Author,
+		Tagger:     signature,
 		Message:    message,
 		TargetType: plumbing.CommitObject,
 		Target:     hash,
@@ -429,8 +806,23 @@ func (w *Writer) GetCommitHashes() ([]string, error) {
 	return hashes, err
 }
 
-// Close releases any resources
+// Close releases any resources. A worktree left behind by the tree builder
+// is checked out at HEAD.
 func (w *Writer) Close() error {
+	if w.worktree == nil || !w.options.TreeBuilder {
+		return nil
+	}
+
+	head, err := w.repo.Head()
+	if err == plumbing.ErrReferenceNotFound {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	if err := w.resetWorktree(head.Hash()); err != nil {
+		return fmt.Errorf("failed to check out HEAD: %w", err)
+	}
 	return nil
 }
 
@@ -445,6 +837,10 @@ func (w *Writer) Open(path string) error {
 	w.repo = repo
 
 	worktree, err := repo.Worktree()
+	if err == git.ErrIsBareRepository {
+		w.options.Bare = true
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("failed to get worktree: %w", err)
 	}
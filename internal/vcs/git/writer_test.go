@@ -7,6 +7,10 @@ import (
 	"time"
 
 	"github.com/adamf123git/git-migrator/internal/vcs"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewWriter(t *testing.T) {
@@ -948,6 +952,12 @@ func TestWriterCreateTagInvalidRevision(t *testing.T) {
 	if err == nil {
 		t.Error("CreateTag should fail with invalid revision")
 	}
+
+	// Hashes of commits that do not exist are not valid revisions either
+	err = w.CreateTag("missing-tag", "0123456789abcdef000000000000000000000000", "")
+	if err == nil {
+		t.Error("CreateTag should fail with a hash of a missing commit")
+	}
 }
 
 func TestWriterCreateAnnotatedTagWithRevision(t *testing.T) {
@@ -1727,3 +1737,407 @@ func TestWriterCreateAnnotatedTag_WithLastCommit(t *testing.T) {
 		t.Error("Tag 'v1.0.0' not found")
 	}
 }
+
+func TestWriterApplyCommitToBranch(t *testing.T) {
+	repoPath := filepath.Join(t.TempDir(), "branch-commit-repo")
+
+	w := NewWriter()
+	if err := w.Init(repoPath); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	defer w.Close()
+
+	commitFile := func(message, content string) *vcs.Commit {
+		return &vcs.Commit{
+			Author:  "Test",
+			Email:   "test@example.com",
+			Date:    time.Now(),
+			Message: message,
+			Files:   []vcs.FileChange{{Path: "file.txt", Action: vcs.ActionAdd, Content: []byte(content)}},
+		}
+	}
+
+	if err := w.ApplyCommitToBranch(commitFile("trunk 1", "one"), "master", ""); err != nil {
+		t.Fatalf("ApplyCommitToBranch failed: %v", err)
+	}
+	forkPoint := w.LastCommitHash()
+	if err := w.ApplyCommitToBranch(commitFile("trunk 2", "two"), "master", ""); err != nil {
+		t.Fatalf("ApplyCommitToBranch failed: %v", err)
+	}
+	trunkTip := w.LastCommitHash()
+
+	// Untracked files must survive switching branches
+	untracked := filepath.Join(repoPath, "state.db")
+	if err := os.WriteFile(untracked, []byte("state"), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	// Fork from the first trunk commit rather than the trunk tip
+	branchCommit := commitFile("branch 1", "branched")
+	branchCommit.Files = append(branchCommit.Files, vcs.FileChange{Path: "release.txt", Action: vcs.ActionAdd, Content: []byte("r")})
+	if err := w.ApplyCommitToBranch(branchCommit, "release", forkPoint); err != nil {
+		t.Fatalf("ApplyCommitToBranch failed: %v", err)
+	}
+	branchTip, err := w.repo.CommitObject(w.lastCommit)
+	if err != nil {
+		t.Fatalf("CommitObject failed: %v", err)
+	}
+	if len(branchTip.ParentHashes) != 1 || branchTip.ParentHashes[0].String() != forkPoint {
+		t.Errorf("branch parents = %v, want [%s]", branchTip.ParentHashes, forkPoint)
+	}
+
+	// Trunk continues from its own tip after the branch commit
+	if err := w.ApplyCommitToBranch(commitFile("trunk 3", "three"), "master", ""); err != nil {
+		t.Fatalf("ApplyCommitToBranch failed: %v", err)
+	}
+	trunkCommit, err := w.repo.CommitObject(w.lastCommit)
+	if err != nil {
+		t.Fatalf("CommitObject failed: %v", err)
+	}
+	if trunkCommit.ParentHashes[0].String() != trunkTip {
+		t.Errorf("trunk parent = %s, want %s", trunkCommit.ParentHashes[0], trunkTip)
+	}
+	if _, err := trunkCommit.File("release.txt"); err == nil {
+		t.Error("trunk commit should not contain release.txt")
+	}
+	if _, err := os.Stat(filepath.Join(repoPath, "release.txt")); !os.IsNotExist(err) {
+		t.Error("release.txt should be removed from the worktree on the trunk")
+	}
+	if _, err := os.Stat(untracked); err != nil {
+		t.Errorf("untracked file was removed: %v", err)
+	}
+
+	if !w.BranchExists("release") {
+		t.Error("BranchExists(release) = false, want true")
+	}
+	if w.BranchExists("missing") {
+		t.Error("BranchExists(missing) = true, want false")
+	}
+
+	if err := w.CheckoutBranch("release"); err != nil {
+		t.Fatalf("CheckoutBranch failed: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(repoPath, "file.txt"))
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if string(content) != "branched" {
+		t.Errorf("file.txt = %q, want %q", content, "branched")
+	}
+}
+
+func TestWriterApplyCommitToBranch_Orphan(t *testing.T) {
+	repoPath := filepath.Join(t.TempDir(), "orphan-repo")
+
+	w := NewWriter()
+	if err := w.Init(repoPath); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	defer w.Close()
+
+	trunk := &vcs.Commit{
+		Author: "Test", Email: "test@example.com", Date: time.Now(), Message: "trunk",
+		Files: []vcs.FileChange{{Path: "trunk.txt", Action: vcs.ActionAdd, Content: []byte("trunk")}},
+	}
+	if err := w.ApplyCommitToBranch(trunk, "master", ""); err != nil {
+		t.Fatalf("ApplyCommitToBranch failed: %v", err)
+	}
+
+	orphan := &vcs.Commit{
+		Author: "Test", Email: "test@example.com", Date: time.Now(), Message: "orphan",
+		Files: []vcs.FileChange{{Path: "other.txt", Action: vcs.ActionAdd, Content: []byte("other")}},
+	}
+	if err := w.ApplyCommitToBranch(orphan, "other", "no-such-revision"); err != nil {
+		t.Fatalf("ApplyCommitToBranch failed: %v", err)
+	}
+
+	commit, err := w.repo.CommitObject(w.lastCommit)
+	if err != nil {
+		t.Fatalf("CommitObject failed: %v", err)
+	}
+	if len(commit.ParentHashes) != 0 {
+		t.Errorf("orphan commit has parents %v", commit.ParentHashes)
+	}
+	if _, err := commit.File("trunk.txt"); err == nil {
+		t.Error("orphan commit should not contain trunk.txt")
+	}
+}
+
+func TestWriterApplyCommitToBranchNoRepo(t *testing.T) {
+	w := NewWriter()
+	if err := w.ApplyCommitToBranch(&vcs.Commit{}, "master", ""); err == nil {
+		t.Error("ApplyCommitToBranch should fail without repository")
+	}
+	if err := w.CheckoutBranch("master"); err == nil {
+		t.Error("CheckoutBranch should fail without repository")
+	}
+	if w.BranchExists("master") {
+		t.Error("BranchExists should be false without repository")
+	}
+	if w.LastCommitHash() != "" {
+		t.Error("LastCommitHash should be empty without commits")
+	}
+}
+
+func TestWriterDeleteBranch(t *testing.T) {
+	w := NewWriter()
+	if err := w.DeleteBranch("feature"); err == nil {
+		t.Error("DeleteBranch should fail without repository")
+	}
+
+	if err := w.Init(filepath.Join(t.TempDir(), "delete-branch-repo")); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	commit := &vcs.Commit{
+		Author: "Test", Email: "test@example.com", Date: time.Now(), Message: "initial",
+		Files: []vcs.FileChange{{Path: "file.txt", Action: vcs.ActionAdd, Content: []byte("x")}},
+	}
+	if err := w.ApplyCommit(commit); err != nil {
+		t.Fatalf("ApplyCommit failed: %v", err)
+	}
+	if err := w.CreateBranch("feature", "HEAD"); err != nil {
+		t.Fatalf("CreateBranch failed: %v", err)
+	}
+
+	if err := w.DeleteBranch("feature"); err != nil {
+		t.Fatalf("DeleteBranch failed: %v", err)
+	}
+	if w.BranchExists("feature") {
+		t.Error("feature branch should be deleted")
+	}
+}
+
+// treeBuilderHistory applies the same history with a trunk, a branch and
+// deletions to a writer and returns the hashes of the resulting trees
+func treeBuilderHistory(t *testing.T, w *Writer) []string {
+	t.Helper()
+
+	date := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	commit := func(message string, files ...vcs.FileChange) *vcs.Commit {
+		date = date.Add(time.Hour)
+		return &vcs.Commit{Author: "Test", Email: "test@example.com", Date: date, Message: message, Files: files}
+	}
+
+	var trees []string
+	record := func() {
+		c, err := w.repo.CommitObject(w.lastCommit)
+		if err != nil {
+			t.Fatalf("CommitObject failed: %v", err)
+		}
+		trees = append(trees, c.TreeHash.String())
+	}
+
+	if err := w.ApplyCommit(commit("initial",
+		vcs.FileChange{Path: "README", Action: vcs.ActionAdd, Content: []byte("readme")},
+		vcs.FileChange{Path: "src/main.c", Action: vcs.ActionAdd, Content: []byte("main")},
+		vcs.FileChange{Path: "src/lib/util.c", Action: vcs.ActionAdd, Content: []byte("util")},
+	)); err != nil {
+		t.Fatalf("ApplyCommit failed: %v", err)
+	}
+	record()
+	trunk := w.LastCommitHash()
+
+	if err := w.ApplyCommit(commit("remove util",
+		vcs.FileChange{Path: "src/lib/util.c", Action: vcs.ActionDelete},
+		vcs.FileChange{Path: "src/main.c", Action: vcs.ActionModify, Content: []byte("main v2")},
+	)); err != nil {
+		t.Fatalf("ApplyCommit failed: %v", err)
+	}
+	record()
+
+	if err := w.ApplyCommitToBranch(commit("branch",
+		vcs.FileChange{Path: "src/branch.c", Action: vcs.ActionAdd, Content: []byte("branch")},
+	), "dev", trunk); err != nil {
+		t.Fatalf("ApplyCommitToBranch failed: %v", err)
+	}
+	record()
+
+	if err := w.ApplyCommitToBranch(commit("trunk again",
+		vcs.FileChange{Path: "README", Action: vcs.ActionDelete},
+	), "master", ""); err != nil {
+		t.Fatalf("ApplyCommitToBranch failed: %v", err)
+	}
+	record()
+
+	return trees
+}
+
+func TestWriterTreeBuilder(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	worktreeWriter := NewWriter()
+	if err := worktreeWriter.Init(filepath.Join(tmpDir, "worktree")); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	expected := treeBuilderHistory(t, worktreeWriter)
+
+	repoPath := filepath.Join(tmpDir, "objects")
+	w := NewWriterWithOptions(WriterOptions{TreeBuilder: true})
+	if err := w.Init(repoPath); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	require.Equal(t, expected, treeBuilderHistory(t, w))
+
+	// Commits are chained on their branches
+	dev, err := w.repo.Reference(plumbing.NewBranchReferenceName("dev"), true)
+	require.NoError(t, err)
+	devCommit, err := w.repo.CommitObject(dev.Hash())
+	require.NoError(t, err)
+	require.Len(t, devCommit.ParentHashes, 1)
+	first, err := w.repo.CommitObject(devCommit.ParentHashes[0])
+	require.NoError(t, err)
+	require.Equal(t, "initial", first.Message)
+
+	head, err := w.repo.Head()
+	require.NoError(t, err)
+	require.Equal(t, plumbing.NewBranchReferenceName("master"), head.Name())
+	require.Equal(t, w.LastCommitHash(), head.Hash().String())
+
+	// Nothing is written to the worktree
+	if _, err := os.Stat(filepath.Join(repoPath, "src")); !os.IsNotExist(err) {
+		t.Errorf("worktree should be empty, got %v", err)
+	}
+
+	// Until the writer is closed, which checks out HEAD and keeps untracked files
+	untracked := filepath.Join(repoPath, "state.db")
+	require.NoError(t, os.WriteFile(untracked, []byte("state"), 0644))
+	require.NoError(t, w.Close())
+	status, err := w.worktree.Status()
+	require.NoError(t, err)
+	require.Len(t, status, 1)
+	require.Equal(t, gogit.Untracked, status.File("state.db").Worktree)
+	_, err = os.Stat(filepath.Join(repoPath, "src"))
+	require.NoError(t, err)
+}
+
+func TestWriterFileModes(t *testing.T) {
+	for _, options := range []WriterOptions{{}, {TreeBuilder: true}} {
+		w := NewWriterWithOptions(options)
+		repoPath := filepath.Join(t.TempDir(), "repo")
+		if err := w.Init(repoPath); err != nil {
+			t.Fatalf("Init failed: %v", err)
+		}
+
+		commit := func(files ...vcs.FileChange) {
+			require.NoError(t, w.ApplyCommit(&vcs.Commit{
+				Author: "Test", Email: "test@example.com", Date: time.Now(), Message: "modes", Files: files,
+			}))
+		}
+		modes := func() map[string]filemode.FileMode {
+			c, err := w.repo.CommitObject(plumbing.NewHash(w.LastCommitHash()))
+			require.NoError(t, err)
+			tree, err := c.Tree()
+			require.NoError(t, err)
+			modes := make(map[string]filemode.FileMode)
+			for _, entry := range tree.Entries {
+				modes[entry.Name] = entry.Mode
+			}
+			return modes
+		}
+
+		commit(
+			vcs.FileChange{Path: "run.sh", Action: vcs.ActionAdd, Content: []byte("#!/bin/sh\n"), Mode: vcs.ModeExecutable},
+			vcs.FileChange{Path: "current", Action: vcs.ActionAdd, Content: []byte("run.sh"), Mode: vcs.ModeSymlink},
+			vcs.FileChange{Path: "README", Action: vcs.ActionAdd, Content: []byte("readme")},
+		)
+		require.Equal(t, map[string]filemode.FileMode{
+			"run.sh":  filemode.Executable,
+			"current": filemode.Symlink,
+			"README":  filemode.Regular,
+		}, modes(), "TreeBuilder: %v", options.TreeBuilder)
+
+		// Modes change both ways, and a link is replaced rather than written through
+		commit(
+			vcs.FileChange{Path: "run.sh", Action: vcs.ActionModify, Content: []byte("#!/bin/sh\n")},
+			vcs.FileChange{Path: "current", Action: vcs.ActionModify, Content: []byte("plain")},
+			vcs.FileChange{Path: "README", Action: vcs.ActionModify, Content: []byte("readme"), Mode: vcs.ModeExecutable},
+		)
+		require.Equal(t, map[string]filemode.FileMode{
+			"run.sh":  filemode.Regular,
+			"current": filemode.Regular,
+			"README":  filemode.Executable,
+		}, modes(), "TreeBuilder: %v", options.TreeBuilder)
+		if !options.TreeBuilder {
+			content, err := os.ReadFile(filepath.Join(repoPath, "run.sh"))
+			require.NoError(t, err)
+			require.Equal(t, "#!/bin/sh\n", string(content))
+		}
+	}
+}
+
+func TestWriterBare(t *testing.T) {
+	repoPath := filepath.Join(t.TempDir(), "repo.git")
+	commit := func(message, path, content string) *vcs.Commit {
+		return &vcs.Commit{
+			Author: "Test", Email: "test@example.com", Date: time.Now(), Message: message,
+			Files: []vcs.FileChange{{Path: path, Action: vcs.ActionAdd, Content: []byte(content)}},
+		}
+	}
+
+	w := NewWriterWithOptions(WriterOptions{Bare: true})
+	if err := w.Init(repoPath); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(repoPath, ".git")); !os.IsNotExist(err) {
+		t.Error(".git directory should not exist in a bare repository")
+	}
+	if _, err := os.Stat(filepath.Join(repoPath, "objects", "pack")); err != nil {
+		t.Errorf("objects/pack should exist: %v", err)
+	}
+
+	require.NoError(t, w.ApplyCommit(commit("initial", "src/main.c", "main")))
+	first := w.LastCommitHash()
+	require.NoError(t, w.ApplyCommitToBranch(commit("branch", "src/dev.c", "dev"), "dev", first))
+	require.NoError(t, w.CheckoutBranch("master"))
+	require.NoError(t, w.CreateBranch("release", "HEAD"))
+	require.NoError(t, w.CreateTag("v1.0", first, "Release 1.0"))
+	require.NoError(t, w.Close())
+
+	// Reopening the bare repository appends to it
+	w = NewWriter()
+	if err := w.Open(repoPath); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	require.True(t, w.IsRepo(repoPath))
+	require.NoError(t, w.ApplyCommit(commit("second", "README", "readme")))
+
+	hashes, err := w.GetCommitHashes()
+	require.NoError(t, err)
+	require.Equal(t, []string{first, w.LastCommitHash()}, hashes)
+
+	branches, err := w.ListBranches()
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"master", "dev", "release"}, branches)
+
+	tags, err := w.ListTags()
+	require.NoError(t, err)
+	require.Contains(t, tags, "v1.0")
+
+	last, err := w.repo.CommitObject(plumbing.NewHash(w.LastCommitHash()))
+	require.NoError(t, err)
+	_, err = last.File("src/main.c")
+	require.NoError(t, err)
+	_, err = last.File("src/dev.c")
+	require.Error(t, err, "branch file should not be on master")
+}
+
+func TestWriterInitialBranch(t *testing.T) {
+	for _, bare := range []bool{false, true} {
+		w := NewWriterWithOptions(WriterOptions{InitialBranch: "main", Bare: bare})
+		if err := w.Init(filepath.Join(t.TempDir(), "repo")); err != nil {
+			t.Fatalf("Init failed: %v", err)
+		}
+
+		head, err := w.repo.Storer.Reference(plumbing.HEAD)
+		require.NoError(t, err)
+		require.Equal(t, plumbing.NewBranchReferenceName("main"), head.Target())
+
+		commit := &vcs.Commit{
+			Author: "Test", Email: "test@example.com", Date: time.Now(), Message: "initial",
+			Files: []vcs.FileChange{{Path: "file.txt", Action: vcs.ActionAdd, Content: []byte("x")}},
+		}
+		require.NoError(t, w.ApplyCommit(commit))
+		require.True(t, w.BranchExists("main"))
+		require.False(t, w.BranchExists("master"))
+	}
+}
@@ -0,0 +1,346 @@
+// Package svn provides Subversion dump file reading capabilities.
+package svn
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Revision is a revision record of a dump file and the nodes it changes
+type Revision struct {
+	Number int
+	Props  map[string]string // Revision properties such as svn:author and svn:log
+	Nodes  []*Node
+}
+
+// Node is a change to a single path within a revision
+type Node struct {
+	Path         string
+	Kind         string // "file", "dir", or empty for deletions
+	Action       string // "add", "change", "delete" or "replace"
+	CopyFromPath string
+	CopyFromRev  int
+	Props        map[string]string // Nil when the record carries no properties
+	DeletedProps []string          // Properties removed by a property delta
+	PropDelta    bool              // Props change the base properties instead of replacing them
+	Text         []byte
+	HasText      bool
+	TextDelta    bool   // Text is an svndiff against the base content
+	TextMD5      string // Checksum of the full text, if recorded
+}
+
+// DumpParser reads revisions from an svnadmin dump stream
+type DumpParser struct {
+	reader  *bufio.Reader
+	version int
+	uuid    string
+	pending map[string]string // Headers read ahead of the current revision
+	started bool
+}
+
+// NewDumpParser creates a new dump parser
+func NewDumpParser(r io.Reader) *DumpParser {
+	return &DumpParser{reader: bufio.NewReader(r)}
+}
+
+// Version returns the dump format version, available after ReadHeader
+func (p *DumpParser) Version() int {
+	return p.version
+}
+
+// UUID returns the repository UUID, if the dump records one
+func (p *DumpParser) UUID() string {
+	return p.uuid
+}
+
+// ReadHeader reads the format version and UUID at the start of the dump
+func (p *DumpParser) ReadHeader() error {
+	if p.started {
+		return nil
+	}
+	p.started = true
+
+	headers, err := p.readHeaders()
+	if err == io.EOF {
+		return fmt.Errorf("empty dump file")
+	}
+	if err != nil {
+		return err
+	}
+
+	version, ok := headers["SVN-fs-dump-format-version"]
+	if !ok {
+		return fmt.Errorf("not a Subversion dump file: missing format version")
+	}
+	p.version, err = strconv.Atoi(version)
+	if err != nil || p.version < 1 || p.version > 3 {
+		return fmt.Errorf("unsupported dump format version: %s", version)
+	}
+
+	for {
+		headers, err = p.readHeaders()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if uuid, ok := headers["UUID"]; ok {
+			p.uuid = uuid
+			continue
+		}
+		p.pending = headers
+		return nil
+	}
+}
+
+// Next returns the next revision with all of its nodes, or io.EOF
+func (p *DumpParser) Next() (*Revision, error) {
+	if err := p.ReadHeader(); err != nil {
+		return nil, err
+	}
+
+	headers := p.pending
+	p.pending = nil
+	if headers == nil {
+		var err error
+		if headers, err = p.readHeaders(); err != nil {
+			return nil, err
+		}
+	}
+
+	number, ok := headers["Revision-number"]
+	if !ok {
+		return nil, fmt.Errorf("expected revision record, got %v", headerNames(headers))
+	}
+	rev := &Revision{}
+	var err error
+	if rev.Number, err = strconv.Atoi(number); err != nil {
+		return nil, fmt.Errorf("invalid revision number %q", number)
+	}
+	props, _, _, err := p.readContent(headers)
+	if err != nil {
+		return nil, fmt.Errorf("revision %d: %w", rev.Number, err)
+	}
+	rev.Props = props
+	if rev.Props == nil {
+		rev.Props = make(map[string]string)
+	}
+
+	for {
+		headers, err := p.readHeaders()
+		if err == io.EOF {
+			return rev, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("revision %d: %w", rev.Number, err)
+		}
+		if _, ok := headers["Revision-number"]; ok {
+			p.pending = headers
+			return rev, nil
+		}
+
+		node, err := p.readNode(headers)
+		if err != nil {
+			return nil, fmt.Errorf("revision %d: %w", rev.Number, err)
+		}
+		rev.Nodes = append(rev.Nodes, node)
+	}
+}
+
+// readNode reads the content of a node record
+func (p *DumpParser) readNode(headers map[string]string) (*Node, error) {
+	path, ok := headers["Node-path"]
+	if !ok {
+		return nil, fmt.Errorf("expected node record, got %v", headerNames(headers))
+	}
+
+	node := &Node{
+		Path:         path,
+		Kind:         headers["Node-kind"],
+		Action:       headers["Node-action"],
+		CopyFromPath: headers["Node-copyfrom-path"],
+		PropDelta:    headers["Prop-delta"] == "true",
+		TextDelta:    headers["Text-delta"] == "true",
+		TextMD5:      headers["Text-content-md5"],
+	}
+	if node.CopyFromPath != "" {
+		rev, err := strconv.Atoi(headers["Node-copyfrom-rev"])
+		if err != nil {
+			return nil, fmt.Errorf("node %s: invalid copyfrom revision %q", path, headers["Node-copyfrom-rev"])
+		}
+		node.CopyFromRev = rev
+	}
+
+	var err error
+	node.Props, node.Text, node.DeletedProps, err = p.readContent(headers)
+	if err != nil {
+		return nil, fmt.Errorf("node %s: %w", path, err)
+	}
+	_, node.HasText = headers["Text-content-length"]
+
+	return node, nil
+}
+
+// readContent reads the property and text blocks of a record, returning
+// the properties, the text and any properties deleted by a property delta
+func (p *DumpParser) readContent(headers map[string]string) (map[string]string, []byte, []string, error) {
+	propLen, err := contentLength(headers, "Prop-content-length")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	textLen, err := contentLength(headers, "Text-content-length")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	totalLen, err := contentLength(headers, "Content-length")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var props map[string]string
+	var deleted []string
+	if _, ok := headers["Prop-content-length"]; ok {
+		block := make([]byte, propLen)
+		if _, err := io.ReadFull(p.reader, block); err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to read properties: %w", err)
+		}
+		if props, deleted, err = parseProps(block); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	var text []byte
+	if textLen > 0 {
+		text = make([]byte, textLen)
+		if _, err := io.ReadFull(p.reader, text); err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to read text: %w", err)
+		}
+	}
+
+	if rest := totalLen - propLen - textLen; rest > 0 {
+		if _, err := p.reader.Discard(rest); err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to skip content: %w", err)
+		}
+	}
+
+	return props, text, deleted, nil
+}
+
+// readHeaders reads a block of "Name: value" lines terminated by a blank
+// line, skipping blank lines before it. It returns io.EOF at end of input.
+func (p *DumpParser) readHeaders() (map[string]string, error) {
+	headers := make(map[string]string)
+	for {
+		line, err := p.reader.ReadString('\n')
+		if err == io.EOF && line == "" {
+			if len(headers) == 0 {
+				return nil, io.EOF
+			}
+			return headers, nil
+		}
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			if len(headers) == 0 {
+				continue
+			}
+			return headers, nil
+		}
+
+		name, value, ok := strings.Cut(line, ": ")
+		if !ok {
+			return nil, fmt.Errorf("malformed header line %q", line)
+		}
+		headers[name] = value
+	}
+}
+
+// parseProps parses a property block terminated by PROPS-END. Properties
+// removed in a property delta are returned separately.
+func parseProps(block []byte) (map[string]string, []string, error) {
+	props := make(map[string]string)
+	var deleted []string
+
+	r := bufio.NewReader(bytes.NewReader(block))
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, nil, fmt.Errorf("unterminated property block")
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "PROPS-END" {
+			return props, deleted, nil
+		}
+
+		kind, size, ok := strings.Cut(line, " ")
+		if !ok || (kind != "K" && kind != "D") {
+			return nil, nil, fmt.Errorf("malformed property line %q", line)
+		}
+		key, err := readPropValue(r, size)
+		if err != nil {
+			return nil, nil, err
+		}
+		if kind == "D" {
+			deleted = append(deleted, key)
+			continue
+		}
+
+		line, err = r.ReadString('\n')
+		if err != nil {
+			return nil, nil, fmt.Errorf("missing value for property %s", key)
+		}
+		kind, size, ok = strings.Cut(strings.TrimSuffix(line, "\n"), " ")
+		if !ok || kind != "V" {
+			return nil, nil, fmt.Errorf("malformed property line %q", line)
+		}
+		value, err := readPropValue(r, size)
+		if err != nil {
+			return nil, nil, err
+		}
+		props[key] = value
+	}
+}
+
+// readPropValue reads a length-prefixed property key or value and the
+// newline that follows it
+func readPropValue(r *bufio.Reader, size string) (string, error) {
+	n, err := strconv.Atoi(size)
+	if err != nil || n < 0 {
+		return "", fmt.Errorf("invalid property length %q", size)
+	}
+	buf := make([]byte, n+1)
+	if _, err := io.ReadFull(r, buf); err != nil || buf[n] != '\n' {
+		return "", fmt.Errorf("truncated property data")
+	}
+	return string(buf[:n]), nil
+}
+
+// contentLength parses a length header, treating a missing header as zero
+func contentLength(headers map[string]string, name string) (int, error) {
+	value, ok := headers[name]
+	if !ok {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s %q", name, value)
+	}
+	return n, nil
+}
+
+// headerNames lists the header names of a record for error messages
+func headerNames(headers map[string]string) []string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,173 @@
+package svn
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// dumpBuilder writes dump files for tests, computing the length headers
+type dumpBuilder struct {
+	buf bytes.Buffer
+}
+
+func newDump(version int) *dumpBuilder {
+	d := &dumpBuilder{}
+	fmt.Fprintf(&d.buf, "SVN-fs-dump-format-version: %d\n\n", version)
+	fmt.Fprintf(&d.buf, "UUID: 2b3c4d5e-0000-0000-0000-000000000000\n\n")
+	return d
+}
+
+// revision writes a revision record with the usual revision properties
+func (d *dumpBuilder) revision(number int, author, date, message string) *dumpBuilder {
+	props := encodeProps("svn:author", author, "svn:date", date, "svn:log", message)
+	fmt.Fprintf(&d.buf, "Revision-number: %d\nProp-content-length: %d\nContent-length: %d\n\n%s\n",
+		number, len(props), len(props), props)
+	return d
+}
+
+// node writes a node record. Props and text are omitted when nil.
+func (d *dumpBuilder) node(headers []string, props []string, text []byte) *dumpBuilder {
+	for _, header := range headers {
+		d.buf.WriteString(header + "\n")
+	}
+	total := 0
+	var propBlock string
+	if props != nil {
+		propBlock = encodeProps(props...)
+		fmt.Fprintf(&d.buf, "Prop-content-length: %d\n", len(propBlock))
+		total += len(propBlock)
+	}
+	if text != nil {
+		fmt.Fprintf(&d.buf, "Text-content-length: %d\n", len(text))
+		total += len(text)
+	}
+	if props != nil || text != nil {
+		fmt.Fprintf(&d.buf, "Content-length: %d\n", total)
+	}
+	d.buf.WriteString("\n")
+	d.buf.WriteString(propBlock)
+	d.buf.Write(text)
+	d.buf.WriteString("\n\n")
+	return d
+}
+
+func (d *dumpBuilder) String() string {
+	return d.buf.String()
+}
+
+// encodeProps encodes key/value pairs as a property block
+func encodeProps(kv ...string) string {
+	var b strings.Builder
+	for i := 0; i+1 < len(kv); i += 2 {
+		fmt.Fprintf(&b, "K %d\n%s\nV %d\n%s\n", len(kv[i]), kv[i], len(kv[i+1]), kv[i+1])
+	}
+	b.WriteString("PROPS-END\n")
+	return b.String()
+}
+
+func TestDumpParser_Revisions(t *testing.T) {
+	dump := newDump(2).
+		revision(0, "", "2024-01-01T00:00:00.000000Z", "").
+		revision(1, "alice", "2024-01-02T10:00:00.000000Z", "Initial import").
+		node([]string{"Node-path: trunk", "Node-kind: dir", "Node-action: add"}, []string{}, nil).
+		node([]string{"Node-path: trunk/README", "Node-kind: file", "Node-action: add"},
+			[]string{"svn:eol-style", "native"}, []byte("hello\n")).
+		revision(2, "bob", "2024-01-03T10:00:00.000000Z", "Branch").
+		node([]string{"Node-path: branches/dev", "Node-kind: dir", "Node-action: add",
+			"Node-copyfrom-rev: 1", "Node-copyfrom-path: trunk"}, nil, nil)
+
+	parser := NewDumpParser(strings.NewReader(dump.String()))
+
+	rev, err := parser.Next()
+	require.NoError(t, err)
+	require.Equal(t, 2, parser.Version())
+	require.Equal(t, "2b3c4d5e-0000-0000-0000-000000000000", parser.UUID())
+	require.Equal(t, 0, rev.Number)
+	require.Empty(t, rev.Nodes)
+
+	rev, err = parser.Next()
+	require.NoError(t, err)
+	require.Equal(t, 1, rev.Number)
+	require.Equal(t, "alice", rev.Props["svn:author"])
+	require.Equal(t, "Initial import", rev.Props["svn:log"])
+	require.Len(t, rev.Nodes, 2)
+
+	readme := rev.Nodes[1]
+	require.Equal(t, "trunk/README", readme.Path)
+	require.Equal(t, "file", readme.Kind)
+	require.Equal(t, "add", readme.Action)
+	require.True(t, readme.HasText)
+	require.Equal(t, "hello\n", string(readme.Text))
+	require.Equal(t, map[string]string{"svn:eol-style": "native"}, readme.Props)
+
+	rev, err = parser.Next()
+	require.NoError(t, err)
+	require.Equal(t, 2, rev.Number)
+	require.Len(t, rev.Nodes, 1)
+	require.Equal(t, "trunk", rev.Nodes[0].CopyFromPath)
+	require.Equal(t, 1, rev.Nodes[0].CopyFromRev)
+	require.Nil(t, rev.Nodes[0].Props)
+	require.False(t, rev.Nodes[0].HasText)
+
+	_, err = parser.Next()
+	require.Equal(t, io.EOF, err)
+}
+
+func TestDumpParser_PropDelta(t *testing.T) {
+	block := "K 13\nsvn:mergeinfo\nV 0\n\nD 14\nsvn:executable\nPROPS-END\n"
+	dump := "SVN-fs-dump-format-version: 3\n\n" +
+		"Revision-number: 1\nProp-content-length: 10\nContent-length: 10\n\nPROPS-END\n\n" +
+		"Node-path: a.sh\nNode-kind: file\nNode-action: change\nProp-delta: true\n" +
+		fmt.Sprintf("Prop-content-length: %d\nContent-length: %d\n\n%s\n", len(block), len(block), block)
+
+	parser := NewDumpParser(strings.NewReader(dump))
+	rev, err := parser.Next()
+	require.NoError(t, err)
+	require.Len(t, rev.Nodes, 1)
+
+	node := rev.Nodes[0]
+	require.True(t, node.PropDelta)
+	require.Equal(t, map[string]string{"svn:mergeinfo": ""}, node.Props)
+	require.Equal(t, []string{"svn:executable"}, node.DeletedProps)
+}
+
+func TestDumpParser_InvalidHeader(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"empty", "", "empty dump file"},
+		{"not a dump", "Hello: world\n\n", "missing format version"},
+		{"unsupported version", "SVN-fs-dump-format-version: 4\n\n", "unsupported dump format version"},
+		{"malformed", "garbage\n", "malformed header line"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewDumpParser(strings.NewReader(tt.input)).ReadHeader()
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestParseProps_Malformed(t *testing.T) {
+	tests := []string{
+		"K 3\nfoo\n",
+		"K 3\nfoo\nX 3\nbar\nPROPS-END\n",
+		"K 10\nfoo\nV 3\nbar\nPROPS-END\n",
+		"Q 3\nfoo\nPROPS-END\n",
+	}
+
+	for _, block := range tests {
+		if _, _, err := parseProps([]byte(block)); err == nil {
+			t.Errorf("parseProps(%q) succeeded, expected an error", block)
+		}
+	}
+}
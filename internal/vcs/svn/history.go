@@ -0,0 +1,335 @@
+package svn
+
+import (
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+)
+
+// Revision property names
+const (
+	propAuthor = "svn:author"
+	propDate   = "svn:date"
+	propLog    = "svn:log"
+)
+
+// history splits the revisions of a repository into the commits of the
+// trunk, branches and tags of its layout
+type history struct {
+	layout  Layout
+	lines   map[string]*lineState // Line directory -> state
+	commits []*vcs.Commit
+	trees   map[string]*entry // Commit revision -> tree of its line
+	warned  map[string]bool   // Paths outside the layout already reported
+}
+
+// lineState follows a line directory across revisions
+type lineState struct {
+	line
+	parent       string       // Commit the line was copied from
+	parentBranch string       // Branch of the parent commit
+	tree         *entry       // Tree of the latest commit of the line
+	commits      []lineCommit // Commits of the line, starting with its parent
+	committed    bool         // Whether the line has a commit of its own
+	deleted      bool
+	final        *entry    // Current tree of a tag
+	created      *Revision // Revision that created the line
+	modified     *Revision // Latest revision that made a tag differ from its parent
+}
+
+// lineCommit is the commit a line is at from an SVN revision on
+type lineCommit struct {
+	number   int
+	revision string
+}
+
+func newHistory(layout Layout) *history {
+	return &history{
+		layout: layout,
+		lines:  make(map[string]*lineState),
+		trees:  make(map[string]*entry),
+		warned: make(map[string]bool),
+	}
+}
+
+// add records the commits of a revision given the trees before and after it
+func (h *history) add(rev *Revision, before, after *entry) {
+	for _, ln := range h.affectedLines(rev, before, after) {
+		old, current := before.lookup(ln.dir), after.lookup(ln.dir)
+		if old == current {
+			continue
+		}
+
+		state := h.lines[ln.dir]
+		if current == nil || !current.dir {
+			// Deleted branches stay in Git, deleted tags do not
+			if ln.kind == lineTag {
+				delete(h.lines, ln.dir)
+			} else if state != nil {
+				state.deleted = true
+			}
+			continue
+		}
+		if state == nil {
+			state = h.newLine(ln, rev)
+		}
+		state.deleted = false
+
+		if ln.kind == lineTag {
+			state.final = current
+			if len(diffTrees(state.tree, current, "", nil)) > 0 {
+				state.modified = rev
+			}
+			continue
+		}
+
+		changes := diffTrees(state.tree, current, "", nil)
+		state.tree = current
+		if len(changes) == 0 {
+			continue
+		}
+
+		commit := revisionCommit(rev, lineRevision(ln.dir, rev.Number), ln.name, changes)
+		if !state.committed {
+			commit.Parent = state.parent
+			state.committed = true
+		}
+		h.commits = append(h.commits, commit)
+		h.trees[commit.Revision] = current
+		state.commits = append(state.commits, lineCommit{number: rev.Number, revision: commit.Revision})
+	}
+}
+
+// affectedLines returns the lines a revision may change, sorted by path
+func (h *history) affectedLines(rev *Revision, before, after *entry) []line {
+	affected := make(map[string]line)
+	for _, node := range rev.Nodes {
+		if ln, _, ok := h.layout.lineOf(node.Path); ok {
+			affected[ln.dir] = ln
+			continue
+		}
+
+		if h.layout.isContainer(node.Path) {
+			for _, tree := range []*entry{before, after} {
+				for _, ln := range h.layout.lines(tree) {
+					if isWithin(ln.dir, node.Path) {
+						affected[ln.dir] = ln
+					}
+				}
+			}
+			continue
+		}
+
+		h.warnOutside(node.Path)
+	}
+
+	lines := make([]line, 0, len(affected))
+	for _, ln := range affected {
+		lines = append(lines, ln)
+	}
+	sort.Slice(lines, func(i, j int) bool { return lines[i].dir < lines[j].dir })
+	return lines
+}
+
+// warnOutside reports a path of the project that is not part of any line,
+// once per top-level directory
+func (h *history) warnOutside(p string) {
+	root := splitPath(h.layout.Root)
+	names := splitPath(p)
+	if len(names) <= len(root) || !isWithin(p, h.layout.Root) {
+		return
+	}
+
+	top := strings.Join(names[:len(root)+1], "/")
+	if !h.warned[top] {
+		h.warned[top] = true
+		log.Printf("Warning: ignoring %s, which is outside the trunk, branches and tags", top)
+	}
+}
+
+// newLine starts following a line created in a revision, forking it from
+// the commit it was copied from
+func (h *history) newLine(ln line, rev *Revision) *lineState {
+	state := &lineState{line: ln, created: rev}
+	h.lines[ln.dir] = state
+
+	source, ok := h.copySource(ln.dir, rev)
+	if !ok {
+		return state
+	}
+	state.parent = source.revision
+	state.parentBranch = h.lines[source.dir].name
+	state.tree = h.trees[source.revision]
+	state.commits = []lineCommit{{number: rev.Number, revision: source.revision}}
+	return state
+}
+
+// copySourceCommit is the commit of a line a directory was copied from
+type copySourceCommit struct {
+	dir      string
+	revision string
+}
+
+// copySource finds the commit a line directory was copied from in a
+// revision, following copies of the directory or one of its parents
+func (h *history) copySource(dir string, rev *Revision) (copySourceCommit, bool) {
+	var copied *Node
+	for _, node := range rev.Nodes {
+		if node.CopyFromPath == "" || !isWithin(dir, node.Path) {
+			continue
+		}
+		if copied == nil || len(node.Path) > len(copied.Path) {
+			copied = node
+		}
+	}
+	if copied == nil {
+		return copySourceCommit{}, false
+	}
+
+	sourcePath := copied.CopyFromPath + strings.TrimPrefix(dir, copied.Path)
+	source, _, ok := h.layout.lineOf(sourcePath)
+	if !ok {
+		return copySourceCommit{}, false
+	}
+	state, ok := h.lines[source.dir]
+	if !ok {
+		return copySourceCommit{}, false
+	}
+
+	// The line is at its latest commit made no later than the copy
+	i := sort.Search(len(state.commits), func(i int) bool {
+		return state.commits[i].number > copied.CopyFromRev
+	})
+	if i == 0 {
+		return copySourceCommit{}, false
+	}
+	return copySourceCommit{dir: source.dir, revision: state.commits[i-1].revision}, true
+}
+
+// branches returns the names of branches that exist or have commits
+func (h *history) branches() []string {
+	branches := []string{}
+	for _, state := range h.lines {
+		if state.kind == lineBranch && (state.committed || !state.deleted) {
+			branches = append(branches, state.name)
+		}
+	}
+	sort.Strings(branches)
+	return branches
+}
+
+// branchPoints returns the commit each branch was copied from
+func (h *history) branchPoints() map[string]string {
+	points := make(map[string]string)
+	for _, state := range h.lines {
+		if state.kind == lineBranch && state.parent != "" {
+			points[state.name] = state.parent
+		}
+	}
+	return points
+}
+
+// tags resolves every tag to the commit it was copied from. Tags that were
+// changed after the copy get a synthetic commit with those changes on top
+// of the copied commit.
+func (h *history) tags() (map[string]string, map[string]*vcs.Commit) {
+	tags := make(map[string]string)
+	fixups := make(map[string]*vcs.Commit)
+
+	dirs := make([]string, 0, len(h.lines))
+	for dir := range h.lines {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	for _, dir := range dirs {
+		state := h.lines[dir]
+		if state.kind != lineTag || state.final == nil {
+			continue
+		}
+		if state.parent == "" {
+			log.Printf("Warning: tag %s was not copied from the trunk or a branch", state.name)
+			continue
+		}
+
+		changes := diffTrees(state.tree, state.final, "", nil)
+		if len(changes) == 0 || state.modified == nil {
+			tags[state.name] = state.parent
+			continue
+		}
+
+		fixup := revisionCommit(state.modified, lineRevision(state.dir, state.modified.Number), state.parentBranch, changes)
+		fixup.Parent = state.parent
+		fixups[fixup.Revision] = fixup
+		tags[state.name] = fixup.Revision
+	}
+	return tags, fixups
+}
+
+// tagDates returns the date of the latest revision that changed each tag,
+// which is the revision that copied it unless it was modified later
+func (h *history) tagDates() map[string]time.Time {
+	dates := make(map[string]time.Time)
+	for _, state := range h.lines {
+		if state.kind != lineTag || state.final == nil || state.parent == "" {
+			continue
+		}
+		rev := state.created
+		if state.modified != nil {
+			rev = state.modified
+		}
+		dates[state.name] = revisionDate(rev)
+	}
+	return dates
+}
+
+// lineRevision returns the revision identifier of a line's commit: the
+// SVN revision number pegged to the line directory
+func lineRevision(dir string, number int) string {
+	if dir == "" {
+		return strconv.Itoa(number)
+	}
+	return dir + "@" + strconv.Itoa(number)
+}
+
+// revisionCommit converts a revision and its file changes into a commit.
+// Files take the revision of the commit, so that the same path changed on
+// two lines in one SVN revision has a distinct revision on each.
+func revisionCommit(rev *Revision, revision, branch string, files []vcs.FileChange) *vcs.Commit {
+	author := rev.Props[propAuthor]
+	if author == "" {
+		author = "(no author)"
+	}
+
+	for i := range files {
+		files[i].Revision = revision
+	}
+
+	return &vcs.Commit{
+		Revision: revision,
+		Author:   author,
+		Date:     revisionDate(rev),
+		Message:  rev.Props[propLog],
+		Branch:   branch,
+		Files:    files,
+	}
+}
+
+// revisionDate returns the date of a revision, or the zero time if it has
+// none
+func revisionDate(rev *Revision) time.Time {
+	value, ok := rev.Props[propDate]
+	if !ok {
+		return time.Time{}
+	}
+	date, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		log.Printf("Warning: revision %d has invalid date %q", rev.Number, value)
+		return time.Time{}
+	}
+	return date
+}
@@ -0,0 +1,198 @@
+package svn
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Layout describes where the trunk, branches and tags of a project live in
+// a repository. The zero Layout treats the whole repository as the trunk.
+type Layout struct {
+	Root     string   // Project directory, empty for the repository root
+	Trunk    string   // Trunk directory relative to Root
+	Branches []string // Glob patterns of branch directories relative to Root
+	Tags     []string // Glob patterns of tag directories relative to Root
+}
+
+// StandardLayout is the conventional trunk, branches and tags layout
+var StandardLayout = Layout{
+	Trunk:    "trunk",
+	Branches: []string{"branches/*"},
+	Tags:     []string{"tags/*"},
+}
+
+// lineKind distinguishes the directories a layout maps to Git refs
+type lineKind int
+
+const (
+	lineTrunk lineKind = iota
+	lineBranch
+	lineTag
+)
+
+// line is a directory whose history becomes the trunk, a branch or a tag
+type line struct {
+	dir  string // Repository path of the directory
+	kind lineKind
+	name string // Git branch or tag name, empty for the trunk
+}
+
+// layoutPattern is a split trunk, branch or tag pattern
+type layoutPattern struct {
+	names []string
+	kind  lineKind
+}
+
+// Check reports malformed glob patterns
+func (l Layout) Check() error {
+	for _, pattern := range append(append([]string{}, l.Branches...), l.Tags...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid layout pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// patterns returns the trunk, branch and tag patterns in order of
+// precedence, as repository paths. The trunk is the project root itself
+// when no directories are configured.
+func (l Layout) patterns() []layoutPattern {
+	root := splitPath(l.Root)
+	join := func(p string) []string {
+		return append(append([]string{}, root...), splitPath(p)...)
+	}
+
+	var patterns []layoutPattern
+	if l.Trunk != "" || (len(l.Branches) == 0 && len(l.Tags) == 0) {
+		patterns = append(patterns, layoutPattern{names: join(l.Trunk), kind: lineTrunk})
+	}
+	for _, p := range l.Branches {
+		patterns = append(patterns, layoutPattern{names: join(p), kind: lineBranch})
+	}
+	for _, p := range l.Tags {
+		patterns = append(patterns, layoutPattern{names: join(p), kind: lineTag})
+	}
+	return patterns
+}
+
+// lineOf returns the line containing a repository path and the path
+// relative to the line directory
+func (l Layout) lineOf(p string) (line, string, bool) {
+	names := splitPath(p)
+
+	var found line
+	depth := -1
+	for _, pattern := range l.patterns() {
+		n := len(pattern.names)
+		if len(names) < n || (depth >= 0 && n >= depth) || !matchNames(pattern.names, names[:n]) {
+			continue
+		}
+		found = newLine(pattern, names[:n])
+		depth = n
+	}
+	if depth < 0 {
+		return line{}, "", false
+	}
+	return found, strings.Join(names[depth:], "/"), true
+}
+
+// isContainer reports whether a path is a parent directory of possible
+// line directories, such as the branches directory itself
+func (l Layout) isContainer(p string) bool {
+	names := splitPath(p)
+	for _, pattern := range l.patterns() {
+		if len(names) < len(pattern.names) && matchNames(pattern.names[:len(names)], names) {
+			return true
+		}
+	}
+	return false
+}
+
+// lines returns the line directories present in a tree, sorted by path.
+// Lines nested inside another line belong to the outer one.
+func (l Layout) lines(root *entry) []line {
+	var found []line
+	seen := make(map[string]bool)
+	for _, pattern := range l.patterns() {
+		expand(root, pattern.names, nil, func(names []string) {
+			ln := newLine(pattern, names)
+			if !seen[ln.dir] {
+				seen[ln.dir] = true
+				found = append(found, ln)
+			}
+		})
+	}
+	// Sorting by components keeps nested lines right after their parent
+	sort.SliceStable(found, func(i, j int) bool {
+		return strings.ReplaceAll(found[i].dir, "/", "\x00") < strings.ReplaceAll(found[j].dir, "/", "\x00")
+	})
+
+	var lines []line
+	for _, ln := range found {
+		if len(lines) > 0 && isWithin(ln.dir, lines[len(lines)-1].dir) {
+			continue
+		}
+		lines = append(lines, ln)
+	}
+	return lines
+}
+
+// expand calls fn with the path of every directory in the tree that matches
+// the pattern components
+func expand(e *entry, pattern, names []string, fn func(names []string)) {
+	if e == nil || !e.dir {
+		return
+	}
+	if len(pattern) == 0 {
+		fn(names)
+		return
+	}
+
+	children := make([]string, 0, len(e.children))
+	for name := range e.children {
+		if ok, _ := path.Match(pattern[0], name); ok {
+			children = append(children, name)
+		}
+	}
+	sort.Strings(children)
+	for _, name := range children {
+		expand(e.children[name], pattern[1:], append(append([]string{}, names...), name), fn)
+	}
+}
+
+// newLine creates the line for a directory matched by a pattern. Branch and
+// tag names are the path components from the first wildcard on, or the
+// last component of a literal pattern.
+func newLine(pattern layoutPattern, names []string) line {
+	ln := line{dir: strings.Join(names, "/"), kind: pattern.kind}
+	if pattern.kind == lineTrunk {
+		return ln
+	}
+
+	first := len(names) - 1
+	for i, p := range pattern.names {
+		if strings.ContainsAny(p, `*?[\`) {
+			first = i
+			break
+		}
+	}
+	ln.name = strings.Join(names[first:], "/")
+	return ln
+}
+
+// matchNames matches path components against pattern components
+func matchNames(pattern, names []string) bool {
+	for i, p := range pattern {
+		if ok, _ := path.Match(p, names[i]); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// isWithin reports whether a path is inside a directory
+func isWithin(p, dir string) bool {
+	return dir == "" || p == dir || strings.HasPrefix(p, dir+"/")
+}
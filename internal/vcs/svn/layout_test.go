@@ -0,0 +1,98 @@
+package svn
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLayout_LineOf(t *testing.T) {
+	layout := Layout{
+		Root:     "project",
+		Trunk:    "trunk",
+		Branches: []string{"branches/*", "branches/features/*"},
+		Tags:     []string{"tags/releases/v*"},
+	}
+
+	tests := []struct {
+		path string
+		dir  string
+		kind lineKind
+		name string
+		rel  string
+		ok   bool
+	}{
+		{"project/trunk", "project/trunk", lineTrunk, "", "", true},
+		{"project/trunk/src/main.c", "project/trunk", lineTrunk, "", "src/main.c", true},
+		{"project/branches/dev/README", "project/branches/dev", lineBranch, "dev", "README", true},
+		{"project/branches/features/login/a.c", "project/branches/features", lineBranch, "features", "login/a.c", true},
+		{"project/tags/releases/v1.0/README", "project/tags/releases/v1.0", lineTag, "v1.0", "README", true},
+		{"project/tags/releases/beta/README", "", lineTrunk, "", "", false},
+		{"project/README", "", lineTrunk, "", "", false},
+		{"other/trunk/README", "", lineTrunk, "", "", false},
+	}
+
+	for _, tt := range tests {
+		ln, rel, ok := layout.lineOf(tt.path)
+		if ok != tt.ok {
+			t.Errorf("lineOf(%q) ok = %v, want %v", tt.path, ok, tt.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if ln.dir != tt.dir || ln.kind != tt.kind || ln.name != tt.name || rel != tt.rel {
+			t.Errorf("lineOf(%q) = %+v, %q, want dir %q kind %d name %q rel %q", tt.path, ln, rel, tt.dir, tt.kind, tt.name, tt.rel)
+		}
+	}
+}
+
+func TestLayout_BranchNames(t *testing.T) {
+	layout := Layout{Branches: []string{"branches/*/*", "maintenance"}}
+
+	ln, _, ok := layout.lineOf("branches/team/feature/file.c")
+	require.True(t, ok)
+	require.Equal(t, "team/feature", ln.name)
+
+	ln, _, ok = layout.lineOf("maintenance/file.c")
+	require.True(t, ok)
+	require.Equal(t, "maintenance", ln.name)
+}
+
+func TestLayout_ZeroIsWholeRepository(t *testing.T) {
+	ln, rel, ok := Layout{}.lineOf("trunk/README")
+	require.True(t, ok)
+	require.Equal(t, "", ln.dir)
+	require.Equal(t, lineTrunk, ln.kind)
+	require.Equal(t, "trunk/README", rel)
+}
+
+func TestLayout_Lines(t *testing.T) {
+	root := newDir()
+	for _, p := range []string{"trunk/a", "branches/dev/a", "branches/dev-2/a", "branches/dev/nested/a", "tags/v1/a", "README"} {
+		root = root.with(p, &entry{content: []byte(p)})
+	}
+
+	layout := Layout{Trunk: "trunk", Branches: []string{"branches/*", "branches/*/*"}, Tags: []string{"tags/*"}}
+	var dirs []string
+	for _, ln := range layout.lines(root) {
+		dirs = append(dirs, ln.dir)
+	}
+	require.Equal(t, []string{"branches/dev", "branches/dev-2", "tags/v1", "trunk"}, dirs)
+}
+
+func TestLayout_IsContainer(t *testing.T) {
+	layout := StandardLayout
+	layout.Root = "project"
+
+	require.True(t, layout.isContainer("project"))
+	require.True(t, layout.isContainer("project/branches"))
+	require.False(t, layout.isContainer("project/branches/dev"))
+	require.False(t, layout.isContainer("project/docs"))
+	require.False(t, layout.isContainer("other"))
+}
+
+func TestLayout_Check(t *testing.T) {
+	require.NoError(t, StandardLayout.Check())
+	require.Error(t, Layout{Branches: []string{"branches/["}}.Check())
+}
@@ -0,0 +1,308 @@
+package svn
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+)
+
+// ReaderOptions configures how a Reader maps repository paths to Git refs
+type ReaderOptions struct {
+	// Layout locates the trunk, branches and tags. The zero Layout migrates
+	// the whole repository as the trunk.
+	Layout Layout
+}
+
+// Reader implements VCSReader for Subversion dump files as written by
+// svnadmin dump. The whole history is replayed in memory; unchanged files
+// and copied directories are shared between revisions.
+type Reader struct {
+	path         string
+	options      ReaderOptions
+	commits      []*vcs.Commit
+	branches     []string
+	branchPoints map[string]string      // Branch name -> revision of the commit it was copied from
+	tags         map[string]string      // Tag name -> revision of the tagged commit
+	tagFixups    map[string]*vcs.Commit // Synthetic commits for modified tags by revision
+	tagDates     map[string]time.Time   // Tag name -> date of its latest change
+	loaded       bool
+}
+
+// NewReader creates a new Subversion dump file reader
+func NewReader(path string) *Reader {
+	return NewReaderWithOptions(path, ReaderOptions{})
+}
+
+// NewReaderWithOptions creates a Subversion dump file reader with custom
+// options
+func NewReaderWithOptions(path string, options ReaderOptions) *Reader {
+	return &Reader{path: path, options: options}
+}
+
+// Validate checks that the path is a readable dump file of a supported
+// format version
+func (r *Reader) Validate() error {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("validation failed: %s is a directory, expected an svnadmin dump file", r.path)
+	}
+
+	file, err := os.Open(r.path)
+	if err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Printf("Warning: failed to close dump file %s: %v", r.path, err)
+		}
+	}()
+
+	if err := NewDumpParser(file).ReadHeader(); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+	if err := r.options.Layout.Check(); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+	return nil
+}
+
+// GetCommits returns an iterator over all revisions that change files
+func (r *Reader) GetCommits() (vcs.CommitIterator, error) {
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return &svnCommitIterator{commits: r.commits}, nil
+}
+
+// GetBranches returns a list of branch names
+func (r *Reader) GetBranches() ([]string, error) {
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r.branches, nil
+}
+
+// GetBranchPoints returns the revision each branch was copied from
+func (r *Reader) GetBranchPoints() (map[string]string, error) {
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r.branchPoints, nil
+}
+
+// GetTags returns a map of tag names to revision identifiers
+func (r *Reader) GetTags() (map[string]string, error) {
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r.tags, nil
+}
+
+// GetTagFixups returns the synthetic commits of tags that were modified
+// after they were copied
+func (r *Reader) GetTagFixups() (map[string]*vcs.Commit, error) {
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r.tagFixups, nil
+}
+
+// GetTagDates returns the date of the revision that last changed each tag
+func (r *Reader) GetTagDates() (map[string]time.Time, error) {
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r.tagDates, nil
+}
+
+// Close releases any resources
+func (r *Reader) Close() error {
+	return nil
+}
+
+// load replays the dump file and splits it into the commits of the layout
+func (r *Reader) load() error {
+	if r.loaded {
+		return nil
+	}
+	if err := r.options.Layout.Check(); err != nil {
+		return err
+	}
+
+	file, err := os.Open(r.path)
+	if err != nil {
+		return fmt.Errorf("failed to open dump file: %w", err)
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Printf("Warning: failed to close dump file %s: %v", r.path, err)
+		}
+	}()
+
+	parser := NewDumpParser(file)
+	history := newHistory(r.options.Layout)
+	roots := make(map[int]*entry)
+	root := newDir()
+
+	for {
+		rev, err := parser.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read dump file: %w", err)
+		}
+
+		previous := root
+		for _, node := range rev.Nodes {
+			if root, err = applyNode(root, roots, node); err != nil {
+				return fmt.Errorf("revision %d: %w", rev.Number, err)
+			}
+		}
+		roots[rev.Number] = root
+		history.add(rev, previous, root)
+	}
+
+	r.commits = history.commits
+	r.branches = history.branches()
+	r.branchPoints = history.branchPoints()
+	r.tags, r.tagFixups = history.tags()
+	r.tagDates = history.tagDates()
+	r.loaded = true
+	return nil
+}
+
+// applyNode applies a node record to the tree and returns the new root
+func applyNode(root *entry, roots map[int]*entry, node *Node) (*entry, error) {
+	path := node.Path
+	existing := root.lookup(path)
+
+	switch node.Action {
+	case "delete":
+		if existing == nil {
+			return nil, fmt.Errorf("cannot delete missing path %s", path)
+		}
+		return root.with(path, nil), nil
+
+	case "replace":
+		root = root.with(path, nil)
+		fallthrough
+
+	case "add":
+		var base *entry
+		if node.CopyFromPath != "" {
+			source, ok := roots[node.CopyFromRev]
+			if !ok {
+				return nil, fmt.Errorf("copy source revision %d of %s is not in the dump", node.CopyFromRev, path)
+			}
+			if base = source.lookup(node.CopyFromPath); base == nil {
+				return nil, fmt.Errorf("copy source %s@%d of %s does not exist", node.CopyFromPath, node.CopyFromRev, path)
+			}
+		}
+
+		updated, err := updateEntry(base, node)
+		if err != nil {
+			return nil, err
+		}
+		return root.with(path, updated), nil
+
+	case "change":
+		if existing == nil {
+			return nil, fmt.Errorf("cannot change missing path %s", path)
+		}
+		updated, err := updateEntry(existing, node)
+		if err != nil {
+			return nil, err
+		}
+		return root.with(path, updated), nil
+
+	default:
+		return nil, fmt.Errorf("unknown action %q for %s", node.Action, path)
+	}
+}
+
+// updateEntry creates the entry for a node from its base entry, which is
+// nil for new paths, applying the node's text and properties
+func updateEntry(base *entry, node *Node) (*entry, error) {
+	var updated *entry
+	switch {
+	case base != nil:
+		copied := *base
+		updated = &copied
+	case node.Kind == kindDir:
+		updated = newDir()
+	case node.Kind == kindFile:
+		updated = &entry{}
+	default:
+		return nil, fmt.Errorf("unknown node kind %q for %s", node.Kind, node.Path)
+	}
+
+	if node.HasText {
+		if updated.dir {
+			return nil, fmt.Errorf("directory %s cannot have text", node.Path)
+		}
+		content := node.Text
+		if node.TextDelta {
+			var err error
+			if content, err = applyDelta(updated.content, node.Text); err != nil {
+				return nil, fmt.Errorf("failed to apply delta to %s: %w", node.Path, err)
+			}
+		}
+		if node.TextMD5 != "" {
+			sum := md5.Sum(content)
+			if hex.EncodeToString(sum[:]) != node.TextMD5 {
+				return nil, fmt.Errorf("checksum mismatch for %s", node.Path)
+			}
+		}
+		updated.content = content
+	}
+
+	if node.Props != nil || len(node.DeletedProps) > 0 {
+		props := make(map[string]string)
+		if node.PropDelta {
+			for key, value := range updated.props {
+				props[key] = value
+			}
+			for _, key := range node.DeletedProps {
+				delete(props, key)
+			}
+		}
+		for key, value := range node.Props {
+			props[key] = value
+		}
+		updated.props = props
+	}
+
+	return updated, nil
+}
+
+// svnCommitIterator implements CommitIterator for Subversion
+type svnCommitIterator struct {
+	commits []*vcs.Commit
+	index   int
+}
+
+func (i *svnCommitIterator) Next() bool {
+	i.index++
+	return i.index <= len(i.commits)
+}
+
+func (i *svnCommitIterator) Commit() *vcs.Commit {
+	if i.index < 1 || i.index > len(i.commits) {
+		return nil
+	}
+	return i.commits[i.index-1]
+}
+
+func (i *svnCommitIterator) Err() error {
+	return nil
+}
@@ -0,0 +1,403 @@
+package svn
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+)
+
+// writeDump writes a dump file into a temporary directory
+func writeDump(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "repo.dump")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+// readCommits reads all commits from a dump file
+func readCommits(t *testing.T, content string) []*vcs.Commit {
+	reader := NewReader(writeDump(t, content))
+	require.NoError(t, reader.Validate())
+
+	iter, err := reader.GetCommits()
+	require.NoError(t, err)
+	var commits []*vcs.Commit
+	for iter.Next() {
+		commits = append(commits, iter.Commit())
+	}
+	require.NoError(t, iter.Err())
+	require.NoError(t, reader.Close())
+	return commits
+}
+
+// fileChanges summarizes the file changes of a commit as path -> action:content
+func fileChanges(commit *vcs.Commit) map[string]string {
+	actions := map[vcs.Action]string{vcs.ActionAdd: "A", vcs.ActionModify: "M", vcs.ActionDelete: "D"}
+	changes := make(map[string]string)
+	for _, f := range commit.Files {
+		changes[f.Path] = actions[f.Action] + ":" + string(f.Content)
+	}
+	return changes
+}
+
+func md5Header(content string) string {
+	sum := md5.Sum([]byte(content))
+	return "Text-content-md5: " + hex.EncodeToString(sum[:])
+}
+
+func TestReader_GetCommits(t *testing.T) {
+	dump := newDump(2).
+		revision(0, "", "2024-01-01T00:00:00.000000Z", "").
+		revision(1, "alice", "2024-01-02T10:00:00.000000Z", "Initial import").
+		node([]string{"Node-path: trunk", "Node-kind: dir", "Node-action: add"}, []string{}, nil).
+		node([]string{"Node-path: trunk/README", "Node-kind: file", "Node-action: add", md5Header("hello\n")},
+			[]string{}, []byte("hello\n")).
+		node([]string{"Node-path: trunk/src", "Node-kind: dir", "Node-action: add"}, []string{}, nil).
+		node([]string{"Node-path: trunk/src/main.c", "Node-kind: file", "Node-action: add"},
+			[]string{}, []byte("int main;\n")).
+		revision(2, "bob", "2024-01-03T10:00:00.000000Z", "Create branch").
+		node([]string{"Node-path: branches", "Node-kind: dir", "Node-action: add"}, []string{}, nil).
+		node([]string{"Node-path: branches/dev", "Node-kind: dir", "Node-action: add",
+			"Node-copyfrom-rev: 1", "Node-copyfrom-path: trunk"}, nil, nil).
+		revision(3, "alice", "2024-01-04T10:00:00.000000Z", "Update and remove").
+		node([]string{"Node-path: trunk/README", "Node-kind: file", "Node-action: change"}, nil, []byte("hello world\n")).
+		node([]string{"Node-path: trunk/src", "Node-action: delete"}, nil, nil).
+		revision(4, "alice", "2024-01-05T10:00:00.000000Z", "Properties only").
+		node([]string{"Node-path: trunk/README", "Node-kind: file", "Node-action: change"},
+			[]string{"svn:eol-style", "native"}, nil).
+		revision(5, "bob", "2024-01-06T10:00:00.000000Z", "Replace from trunk").
+		node([]string{"Node-path: branches/dev/README", "Node-kind: file", "Node-action: replace",
+			"Node-copyfrom-rev: 3", "Node-copyfrom-path: trunk/README"}, nil, nil)
+
+	commits := readCommits(t, dump.String())
+	require.Len(t, commits, 4)
+
+	first := commits[0]
+	require.Equal(t, "1", first.Revision)
+	require.Equal(t, "alice", first.Author)
+	require.Equal(t, "Initial import", first.Message)
+	require.Equal(t, time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC), first.Date)
+	require.Empty(t, first.Branch)
+	require.Equal(t, map[string]string{
+		"trunk/README":     "A:hello\n",
+		"trunk/src/main.c": "A:int main;\n",
+	}, fileChanges(first))
+	for _, f := range first.Files {
+		require.Equal(t, "1", f.Revision)
+	}
+
+	require.Equal(t, "2", commits[1].Revision)
+	require.Equal(t, map[string]string{
+		"branches/dev/README":     "A:hello\n",
+		"branches/dev/src/main.c": "A:int main;\n",
+	}, fileChanges(commits[1]))
+
+	require.Equal(t, "3", commits[2].Revision)
+	require.Equal(t, map[string]string{
+		"trunk/README":     "M:hello world\n",
+		"trunk/src/main.c": "D:",
+	}, fileChanges(commits[2]))
+
+	require.Equal(t, "5", commits[3].Revision)
+	require.Equal(t, map[string]string{
+		"branches/dev/README": "M:hello world\n",
+	}, fileChanges(commits[3]))
+}
+
+func TestReader_Deltas(t *testing.T) {
+	// Version 3 dumps store text as svndiff against the previous content
+	// or the copy source
+	create := append([]byte("SVN\x00"), svndiffWindow(0, 0, 5, []byte{0x85}, []byte("hello"))...)
+	appendBang := append([]byte("SVN\x00"), svndiffWindow(0, 5, 6, []byte{0x05, 0x00, 0x81}, []byte("!"))...)
+	appendQuery := append([]byte("SVN\x00"), svndiffWindow(0, 6, 7, []byte{0x06, 0x00, 0x81}, []byte("?"))...)
+
+	dump := newDump(3).
+		revision(1, "alice", "2024-01-02T10:00:00.000000Z", "Add").
+		node([]string{"Node-path: a.txt", "Node-kind: file", "Node-action: add", "Text-delta: true",
+			md5Header("hello")}, nil, create).
+		revision(2, "alice", "2024-01-03T10:00:00.000000Z", "Change").
+		node([]string{"Node-path: a.txt", "Node-kind: file", "Node-action: change", "Text-delta: true",
+			md5Header("hello!")}, nil, appendBang).
+		revision(3, "bob", "2024-01-04T10:00:00.000000Z", "Copy and change").
+		node([]string{"Node-path: b.txt", "Node-kind: file", "Node-action: add", "Node-copyfrom-rev: 2",
+			"Node-copyfrom-path: a.txt", "Text-delta: true"}, nil, appendQuery)
+
+	commits := readCommits(t, dump.String())
+	require.Len(t, commits, 3)
+	require.Equal(t, map[string]string{"a.txt": "A:hello"}, fileChanges(commits[0]))
+	require.Equal(t, map[string]string{"a.txt": "M:hello!"}, fileChanges(commits[1]))
+	require.Equal(t, map[string]string{"b.txt": "A:hello!?"}, fileChanges(commits[2]))
+}
+
+func TestReader_ChangesWithinRevision(t *testing.T) {
+	dump := newDump(2).
+		revision(1, "alice", "2024-01-02T10:00:00.000000Z", "Add").
+		node([]string{"Node-path: keep.txt", "Node-kind: file", "Node-action: add"}, nil, []byte("v1")).
+		revision(2, "alice", "2024-01-03T10:00:00.000000Z", "Shuffle").
+		node([]string{"Node-path: temp.txt", "Node-kind: file", "Node-action: add"}, nil, []byte("tmp")).
+		node([]string{"Node-path: temp.txt", "Node-action: delete"}, nil, nil).
+		node([]string{"Node-path: keep.txt", "Node-action: delete"}, nil, nil).
+		node([]string{"Node-path: keep.txt", "Node-kind: file", "Node-action: add"}, nil, []byte("v2"))
+
+	commits := readCommits(t, dump.String())
+	require.Len(t, commits, 2)
+	require.Equal(t, map[string]string{"keep.txt": "M:v2"}, fileChanges(commits[1]))
+}
+
+func TestReader_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		dump *dumpBuilder
+		want string
+	}{
+		{
+			name: "checksum mismatch",
+			dump: newDump(2).revision(1, "alice", "2024-01-02T10:00:00.000000Z", "Add").
+				node([]string{"Node-path: a.txt", "Node-kind: file", "Node-action: add", md5Header("other")}, nil, []byte("hello")),
+			want: "checksum mismatch",
+		},
+		{
+			name: "missing copy source",
+			dump: newDump(2).revision(1, "alice", "2024-01-02T10:00:00.000000Z", "Copy").
+				node([]string{"Node-path: b", "Node-kind: dir", "Node-action: add",
+					"Node-copyfrom-rev: 0", "Node-copyfrom-path: a"}, nil, nil),
+			want: "not in the dump",
+		},
+		{
+			name: "delete missing path",
+			dump: newDump(2).revision(1, "alice", "2024-01-02T10:00:00.000000Z", "Delete").
+				node([]string{"Node-path: a", "Node-action: delete"}, nil, nil),
+			want: "cannot delete missing path",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := NewReader(writeDump(t, tt.dump.String()))
+			_, err := reader.GetCommits()
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestReader_Validate(t *testing.T) {
+	dir := t.TempDir()
+
+	err := NewReader(filepath.Join(dir, "missing.dump")).Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "validation failed")
+
+	err = NewReader(dir).Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "is a directory")
+
+	notDump := filepath.Join(dir, "notes.txt")
+	require.NoError(t, os.WriteFile(notDump, []byte("Just: text\n\n"), 0644))
+	err = NewReader(notDump).Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "missing format version")
+
+	require.NoError(t, NewReader(writeDump(t, newDump(3).String())).Validate())
+}
+
+// readLayout reads a dump file with a layout
+func readLayout(t *testing.T, content string, layout Layout) (*Reader, []*vcs.Commit) {
+	reader := NewReaderWithOptions(writeDump(t, content), ReaderOptions{Layout: layout})
+	require.NoError(t, reader.Validate())
+
+	iter, err := reader.GetCommits()
+	require.NoError(t, err)
+	var commits []*vcs.Commit
+	for iter.Next() {
+		commits = append(commits, iter.Commit())
+	}
+	return reader, commits
+}
+
+func TestReader_StandardLayout(t *testing.T) {
+	dir := []string{"Node-kind: dir", "Node-action: add"}
+	file := []string{"Node-kind: file", "Node-action: add"}
+	change := []string{"Node-kind: file", "Node-action: change"}
+	copyOf := func(path string, rev string) []string {
+		return []string{"Node-kind: dir", "Node-action: add", "Node-copyfrom-rev: " + rev, "Node-copyfrom-path: " + path}
+	}
+	at := func(path string, headers []string) []string {
+		return append([]string{"Node-path: " + path}, headers...)
+	}
+
+	dump := newDump(2).
+		revision(1, "alice", "2024-01-01T00:00:00.000000Z", "Layout").
+		node(at("trunk", dir), nil, nil).
+		node(at("branches", dir), nil, nil).
+		node(at("tags", dir), nil, nil).
+		node(at("trunk/README", file), nil, []byte("v1")).
+		revision(2, "bob", "2024-01-02T00:00:00.000000Z", "Branch dev").
+		node(at("branches/dev", copyOf("trunk", "1")), nil, nil).
+		revision(3, "bob", "2024-01-03T00:00:00.000000Z", "Work on dev").
+		node(at("branches/dev/README", change), nil, []byte("dev")).
+		revision(4, "alice", "2024-01-04T00:00:00.000000Z", "Tag releases").
+		node(at("tags/0.9", copyOf("trunk", "1")), nil, nil).
+		node(at("tags/1.0", copyOf("trunk", "2")), nil, nil).
+		revision(5, "alice", "2024-01-05T00:00:00.000000Z", "Second version").
+		node(at("trunk/README", change), nil, []byte("v2")).
+		revision(6, "alice", "2024-01-06T00:00:00.000000Z", "Tag 2.0").
+		node(at("tags/2.0", copyOf("trunk", "5")), nil, nil).
+		revision(7, "carol", "2024-01-07T00:00:00.000000Z", "Fix tag").
+		node(at("tags/2.0/README", change), nil, []byte("v2-fixed")).
+		revision(8, "bob", "2024-01-08T00:00:00.000000Z", "Branch stable").
+		node(at("branches/stable", copyOf("branches/dev", "7")), nil, nil).
+		revision(9, "bob", "2024-01-09T00:00:00.000000Z", "Clean up").
+		node(at("branches/dev", []string{"Node-action: delete"}), nil, nil).
+		node(at("tags/0.9", []string{"Node-action: delete"}), nil, nil).
+		revision(10, "alice", "2024-01-10T00:00:00.000000Z", "Outside").
+		node(at("NOTES", file), nil, []byte("notes"))
+
+	reader, commits := readLayout(t, dump.String(), StandardLayout)
+
+	require.Len(t, commits, 3)
+	require.Equal(t, "trunk@1", commits[0].Revision)
+	require.Empty(t, commits[0].Branch)
+	require.Empty(t, commits[0].Parent)
+	require.Equal(t, map[string]string{"README": "A:v1"}, fileChanges(commits[0]))
+
+	require.Equal(t, "branches/dev@3", commits[1].Revision)
+	require.Equal(t, "dev", commits[1].Branch)
+	require.Equal(t, "trunk@1", commits[1].Parent)
+	require.Equal(t, map[string]string{"README": "M:dev"}, fileChanges(commits[1]))
+
+	require.Equal(t, "trunk@5", commits[2].Revision)
+	require.Empty(t, commits[2].Parent)
+
+	branches, err := reader.GetBranches()
+	require.NoError(t, err)
+	require.Equal(t, []string{"dev", "stable"}, branches)
+
+	points, err := reader.GetBranchPoints()
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"dev": "trunk@1", "stable": "branches/dev@3"}, points)
+
+	tags, err := reader.GetTags()
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"1.0": "trunk@1", "2.0": "tags/2.0@7"}, tags)
+
+	fixups, err := reader.GetTagFixups()
+	require.NoError(t, err)
+	require.Len(t, fixups, 1)
+	fixup := fixups["tags/2.0@7"]
+	require.NotNil(t, fixup)
+	require.Equal(t, "trunk@5", fixup.Parent)
+	require.Equal(t, "carol", fixup.Author)
+	require.Equal(t, "Fix tag", fixup.Message)
+	require.Equal(t, map[string]string{"README": "M:v2-fixed"}, fileChanges(fixup))
+
+	dates, err := reader.GetTagDates()
+	require.NoError(t, err)
+	require.Equal(t, map[string]time.Time{
+		"1.0": time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC),
+		"2.0": time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC),
+	}, dates)
+}
+
+func TestReader_ProjectRoot(t *testing.T) {
+	dump := newDump(2).
+		revision(1, "alice", "2024-01-01T00:00:00.000000Z", "Two projects").
+		node([]string{"Node-path: alpha", "Node-kind: dir", "Node-action: add"}, nil, nil).
+		node([]string{"Node-path: alpha/trunk", "Node-kind: dir", "Node-action: add"}, nil, nil).
+		node([]string{"Node-path: alpha/trunk/a.c", "Node-kind: file", "Node-action: add"}, nil, []byte("a")).
+		node([]string{"Node-path: alpha/trunk/b.c", "Node-kind: file", "Node-action: add"}, nil, []byte("b")).
+		node([]string{"Node-path: beta", "Node-kind: dir", "Node-action: add"}, nil, nil).
+		node([]string{"Node-path: beta/trunk", "Node-kind: dir", "Node-action: add"}, nil, nil).
+		node([]string{"Node-path: beta/trunk/x.c", "Node-kind: file", "Node-action: add"}, nil, []byte("x")).
+		revision(2, "bob", "2024-01-02T00:00:00.000000Z", "Branch and change").
+		node([]string{"Node-path: alpha/branches", "Node-kind: dir", "Node-action: add"}, nil, nil).
+		node([]string{"Node-path: alpha/branches/fix", "Node-kind: dir", "Node-action: add",
+			"Node-copyfrom-rev: 1", "Node-copyfrom-path: alpha/trunk"}, nil, nil).
+		node([]string{"Node-path: alpha/branches/fix/b.c", "Node-kind: file", "Node-action: change"}, nil, []byte("b2"))
+
+	layout := StandardLayout
+	layout.Root = "alpha"
+	reader, commits := readLayout(t, dump.String(), layout)
+
+	require.Len(t, commits, 2)
+	require.Equal(t, "alpha/trunk@1", commits[0].Revision)
+	require.Equal(t, map[string]string{"a.c": "A:a", "b.c": "A:b"}, fileChanges(commits[0]))
+
+	require.Equal(t, "alpha/branches/fix@2", commits[1].Revision)
+	require.Equal(t, "fix", commits[1].Branch)
+	require.Equal(t, "alpha/trunk@1", commits[1].Parent)
+	require.Equal(t, map[string]string{"b.c": "M:b2"}, fileChanges(commits[1]))
+
+	branches, err := reader.GetBranches()
+	require.NoError(t, err)
+	require.Equal(t, []string{"fix"}, branches)
+}
+
+func TestReader_InvalidLayout(t *testing.T) {
+	reader := NewReaderWithOptions(writeDump(t, newDump(2).String()), ReaderOptions{
+		Layout: Layout{Tags: []string{"tags/["}},
+	})
+	err := reader.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid layout pattern")
+}
+
+func TestReader_FileModes(t *testing.T) {
+	dir := []string{"Node-kind: dir", "Node-action: add"}
+	file := []string{"Node-kind: file", "Node-action: add"}
+	change := []string{"Node-kind: file", "Node-action: change"}
+	at := func(path string, headers []string) []string {
+		return append([]string{"Node-path: " + path}, headers...)
+	}
+
+	dump := newDump(2).
+		revision(1, "alice", "2024-01-01T00:00:00.000000Z", "Import").
+		node(at("trunk", dir), nil, nil).
+		node(at("branches", dir), nil, nil).
+		node(at("trunk/run.sh", file), []string{"svn:executable", "*"}, []byte("#!/bin/sh\n")).
+		node(at("trunk/current", file), []string{"svn:special", "*"}, []byte("link run.sh")).
+		node(at("trunk/README", file), []string{}, []byte("v1")).
+		revision(2, "bob", "2024-01-02T00:00:00.000000Z", "Branch dev").
+		node(at("branches/dev", append(dir, "Node-copyfrom-rev: 1", "Node-copyfrom-path: trunk")), nil, nil).
+		revision(3, "bob", "2024-01-03T00:00:00.000000Z", "Both lines").
+		node(at("trunk/README", change), nil, []byte("trunk")).
+		node(at("branches/dev/README", change), nil, []byte("dev")).
+		revision(4, "alice", "2024-01-04T00:00:00.000000Z", "Properties only").
+		node(at("trunk/run.sh", change), []string{}, nil).
+		node(at("trunk/README", change), []string{"svn:executable", "*"}, nil)
+
+	_, commits := readLayout(t, dump.String(), StandardLayout)
+	require.Len(t, commits, 4)
+
+	modes := func(commit *vcs.Commit) map[string]vcs.FileMode {
+		modes := make(map[string]vcs.FileMode)
+		for _, f := range commit.Files {
+			modes[f.Path] = f.Mode
+		}
+		return modes
+	}
+
+	// Special files hold the link target
+	require.Equal(t, map[string]string{"run.sh": "A:#!/bin/sh\n", "current": "A:run.sh", "README": "A:v1"}, fileChanges(commits[0]))
+	require.Equal(t, map[string]vcs.FileMode{
+		"run.sh":  vcs.ModeExecutable,
+		"current": vcs.ModeSymlink,
+		"README":  vcs.ModeRegular,
+	}, modes(commits[0]))
+
+	// A path changed on two lines in one revision has a revision on each
+	require.Equal(t, "branches/dev@3", commits[1].Revision)
+	require.Equal(t, "branches/dev@3", commits[1].Files[0].Revision)
+	require.Equal(t, "trunk@3", commits[2].Revision)
+	require.Equal(t, "trunk@3", commits[2].Files[0].Revision)
+
+	// Property changes alone change the mode
+	require.Equal(t, map[string]string{"run.sh": "M:#!/bin/sh\n", "README": "M:trunk"}, fileChanges(commits[3]))
+	require.Equal(t, map[string]vcs.FileMode{"run.sh": vcs.ModeRegular, "README": vcs.ModeExecutable}, modes(commits[3]))
+}
@@ -0,0 +1,192 @@
+package svn
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+)
+
+// svndiff instruction opcodes
+const (
+	opSource = 0 // Copy from the source view
+	opTarget = 1 // Copy from the target produced so far
+	opNew    = 2 // Copy from the window's new data
+)
+
+// maxWindowLen bounds the target view and decompressed sections of a delta
+// window. Subversion writes windows of 100 KiB; lengths far beyond that
+// come from corrupt dumps and are rejected before anything is allocated.
+const maxWindowLen = 1 << 24
+
+// applyDelta applies an svndiff delta to the source text
+func applyDelta(source, delta []byte) ([]byte, error) {
+	if len(delta) < 4 || !bytes.Equal(delta[:3], []byte("SVN")) {
+		return nil, fmt.Errorf("invalid svndiff header")
+	}
+	version := delta[3]
+	if version > 1 {
+		return nil, fmt.Errorf("unsupported svndiff version %d", version)
+	}
+
+	var target []byte
+	r := bytes.NewReader(delta[4:])
+	for r.Len() > 0 {
+		sviewOffset, err := readVarint(r)
+		if err != nil {
+			return nil, err
+		}
+		sviewLen, err := readVarint(r)
+		if err != nil {
+			return nil, err
+		}
+		tviewLen, err := readVarint(r)
+		if err != nil {
+			return nil, err
+		}
+		insLen, err := readVarint(r)
+		if err != nil {
+			return nil, err
+		}
+		newLen, err := readVarint(r)
+		if err != nil {
+			return nil, err
+		}
+
+		if sviewOffset > len(source) || sviewLen > len(source)-sviewOffset {
+			return nil, fmt.Errorf("svndiff source view out of range")
+		}
+		if tviewLen > maxWindowLen {
+			return nil, fmt.Errorf("svndiff target view of %d bytes is too large", tviewLen)
+		}
+		instructions, err := readSection(r, insLen, version)
+		if err != nil {
+			return nil, err
+		}
+		newData, err := readSection(r, newLen, version)
+		if err != nil {
+			return nil, err
+		}
+
+		window, err := applyWindow(source[sviewOffset:sviewOffset+sviewLen], instructions, newData, tviewLen)
+		if err != nil {
+			return nil, err
+		}
+		target = append(target, window...)
+	}
+
+	return target, nil
+}
+
+// applyWindow runs the instructions of a single delta window
+func applyWindow(sview, instructions, newData []byte, tviewLen int) ([]byte, error) {
+	tview := make([]byte, 0, tviewLen)
+	ins := bytes.NewReader(instructions)
+	newPos := 0
+
+	for ins.Len() > 0 {
+		b, _ := ins.ReadByte()
+		op := int(b >> 6)
+		length := int(b & 0x3f)
+		if length == 0 {
+			var err error
+			if length, err = readVarint(ins); err != nil {
+				return nil, err
+			}
+		}
+
+		if length > tviewLen-len(tview) {
+			return nil, fmt.Errorf("svndiff window exceeds its target view of %d bytes", tviewLen)
+		}
+
+		switch op {
+		case opSource:
+			offset, err := readVarint(ins)
+			if err != nil {
+				return nil, err
+			}
+			if offset > len(sview) || length > len(sview)-offset {
+				return nil, fmt.Errorf("svndiff source copy out of range")
+			}
+			tview = append(tview, sview[offset:offset+length]...)
+		case opTarget:
+			offset, err := readVarint(ins)
+			if err != nil {
+				return nil, err
+			}
+			if offset >= len(tview) {
+				return nil, fmt.Errorf("svndiff target copy out of range")
+			}
+			// The copy may overlap the bytes it produces
+			for i := 0; i < length; i++ {
+				tview = append(tview, tview[offset+i])
+			}
+		case opNew:
+			if length > len(newData)-newPos {
+				return nil, fmt.Errorf("svndiff new data out of range")
+			}
+			tview = append(tview, newData[newPos:newPos+length]...)
+			newPos += length
+		default:
+			return nil, fmt.Errorf("invalid svndiff instruction %d", op)
+		}
+	}
+
+	if len(tview) != tviewLen {
+		return nil, fmt.Errorf("svndiff window produced %d bytes, expected %d", len(tview), tviewLen)
+	}
+	return tview, nil
+}
+
+// readSection reads an instruction or new data section, decompressing it
+// for svndiff version 1
+func readSection(r *bytes.Reader, length int, version byte) ([]byte, error) {
+	if length > r.Len() {
+		return nil, fmt.Errorf("truncated svndiff window")
+	}
+	data := make([]byte, length)
+	_, _ = r.Read(data)
+	if version == 0 {
+		return data, nil
+	}
+
+	sr := bytes.NewReader(data)
+	size, err := readVarint(sr)
+	if err != nil {
+		return nil, err
+	}
+	if size > maxWindowLen {
+		return nil, fmt.Errorf("svndiff section of %d bytes is too large", size)
+	}
+	if size == sr.Len() {
+		return data[length-size:], nil
+	}
+
+	zr, err := zlib.NewReader(sr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress svndiff section: %w", err)
+	}
+	defer zr.Close()
+
+	out := make([]byte, size)
+	if _, err := io.ReadFull(zr, out); err != nil {
+		return nil, fmt.Errorf("failed to decompress svndiff section: %w", err)
+	}
+	return out, nil
+}
+
+// readVarint reads a big-endian base-128 integer
+func readVarint(r io.ByteReader) (int, error) {
+	n := 0
+	for i := 0; i < 9; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, fmt.Errorf("truncated svndiff integer")
+		}
+		n = n<<7 | int(b&0x7f)
+		if b&0x80 == 0 {
+			return n, nil
+		}
+	}
+	return 0, fmt.Errorf("svndiff integer too large")
+}
@@ -0,0 +1,162 @@
+package svn
+
+import (
+	"bytes"
+	"compress/zlib"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// svndiffWindow encodes a single delta window
+func svndiffWindow(sviewOffset, sviewLen, tviewLen int, instructions, newData []byte) []byte {
+	window := []byte{byte(sviewOffset), byte(sviewLen), byte(tviewLen), byte(len(instructions)), byte(len(newData))}
+	window = append(window, instructions...)
+	return append(window, newData...)
+}
+
+// svndiffInt encodes an svndiff integer, for lengths that do not fit the
+// single bytes of svndiffWindow
+func svndiffInt(n int) []byte {
+	out := []byte{byte(n & 0x7f)}
+	for n >>= 7; n > 0; n >>= 7 {
+		out = append([]byte{byte(n&0x7f) | 0x80}, out...)
+	}
+	return out
+}
+
+func TestApplyDelta(t *testing.T) {
+	// Copy "hello " from the source, insert "there ", copy "world" from the
+	// source and repeat " world" from the target
+	instructions := []byte{0x06, 0x00, 0x86, 0x05, 0x06, 0x46, 0x0b}
+	delta := append([]byte("SVN\x00"), svndiffWindow(0, 11, 23, instructions, []byte("there "))...)
+
+	target, err := applyDelta([]byte("hello world"), delta)
+	require.NoError(t, err)
+	require.Equal(t, "hello there world world", string(target))
+}
+
+func TestApplyDelta_OverlappingTargetCopy(t *testing.T) {
+	instructions := []byte{0x82, 0x46, 0x00}
+	delta := append([]byte("SVN\x00"), svndiffWindow(0, 0, 8, instructions, []byte("ab"))...)
+
+	target, err := applyDelta(nil, delta)
+	require.NoError(t, err)
+	require.Equal(t, "abababab", string(target))
+}
+
+func TestApplyDelta_MultipleWindows(t *testing.T) {
+	delta := []byte("SVN\x00")
+	delta = append(delta, svndiffWindow(0, 3, 3, []byte{0x03, 0x00}, nil)...)
+	delta = append(delta, svndiffWindow(3, 3, 4, []byte{0x03, 0x00, 0x81}, []byte("!"))...)
+
+	target, err := applyDelta([]byte("foobar"), delta)
+	require.NoError(t, err)
+	require.Equal(t, "foobar!", string(target))
+}
+
+func TestApplyDelta_Version1(t *testing.T) {
+	newData := bytes.Repeat([]byte("x"), 100)
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	_, err := zw.Write(newData)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	// Instructions are stored uncompressed, new data compressed; both are
+	// prefixed with their original length
+	instructions := []byte{0x04, 0x03, 0x00, 0x80, 0x64}
+	newSection := append([]byte{0x64}, compressed.Bytes()...)
+
+	delta := []byte("SVN\x01")
+	delta = append(delta, 0x00, 0x03, 0x67, byte(len(instructions)), byte(len(newSection)))
+	delta = append(delta, instructions...)
+	delta = append(delta, newSection...)
+
+	target, err := applyDelta([]byte("abc"), delta)
+	require.NoError(t, err)
+	require.Equal(t, "abc"+string(newData), string(target))
+}
+
+func TestApplyDelta_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		delta []byte
+	}{
+		{"bad header", []byte("XYZ\x00")},
+		{"unsupported version", []byte("SVN\x02")},
+		{"truncated window", []byte("SVN\x00\x00\x00\x05")},
+		{"source out of range", append([]byte("SVN\x00"), svndiffWindow(0, 20, 0, nil, nil)...)},
+		{"wrong target length", append([]byte("SVN\x00"), svndiffWindow(0, 0, 5, []byte{0x81}, []byte("a"))...)},
+		{"target copy out of range", append([]byte("SVN\x00"), svndiffWindow(0, 0, 2, []byte{0x42, 0x00}, nil)...)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := applyDelta([]byte("source"), tt.delta); err == nil {
+				t.Errorf("applyDelta succeeded, expected an error")
+			}
+		})
+	}
+}
+
+func TestApplyDelta_CorruptLengths(t *testing.T) {
+	huge := svndiffInt(1 << 62)
+	largest := svndiffInt(math.MaxInt)
+	window := func(version byte, header [][]byte, sections ...[]byte) []byte {
+		delta := append([]byte("SVN"), version)
+		for _, n := range header {
+			delta = append(delta, n...)
+		}
+		for _, section := range sections {
+			delta = append(delta, section...)
+		}
+		return delta
+	}
+	n := func(i int) []byte { return svndiffInt(i) }
+
+	tests := []struct {
+		name  string
+		delta []byte
+	}{
+		{"source view overflow", window(0, [][]byte{huge, huge, n(0), n(0), n(0)})},
+		{"huge target view", window(0, [][]byte{n(0), n(0), huge, n(0), n(0)})},
+		{"source copy overflow", window(0, [][]byte{n(0), n(6), n(1), n(1 + len(largest)), n(0)},
+			append([]byte{0x01}, largest...))},
+		{"new data overflow", window(0, [][]byte{n(0), n(0), n(1), n(1 + len(huge)), n(1)},
+			append([]byte{0x80}, huge...), []byte("a"))},
+		{"target copy beyond view", window(0, [][]byte{n(0), n(0), n(3), n(3 + len(huge)), n(1)},
+			append(append([]byte{0x81, 0x40}, huge...), 0x00), []byte("a"))},
+		{"huge decompressed section", window(1, [][]byte{n(0), n(0), n(0), n(len(huge) + 1), n(0)},
+			append(huge, 0x00))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := applyDelta([]byte("source"), tt.delta); err == nil {
+				t.Errorf("applyDelta succeeded, expected an error")
+			}
+		})
+	}
+}
+
+func TestReadVarint(t *testing.T) {
+	tests := []struct {
+		input []byte
+		want  int
+	}{
+		{[]byte{0x00}, 0},
+		{[]byte{0x7f}, 127},
+		{[]byte{0x81, 0x00}, 128},
+		{[]byte{0x82, 0x80, 0x01}, 32769},
+	}
+
+	for _, tt := range tests {
+		got, err := readVarint(bytes.NewReader(tt.input))
+		require.NoError(t, err)
+		if got != tt.want {
+			t.Errorf("readVarint(%x) = %d, want %d", tt.input, got, tt.want)
+		}
+	}
+}
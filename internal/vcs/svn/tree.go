@@ -0,0 +1,181 @@
+package svn
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+
+	"github.com/adamf123git/git-migrator/internal/vcs"
+)
+
+// node kinds as recorded in Node-kind headers
+const (
+	kindFile = "file"
+	kindDir  = "dir"
+)
+
+// entry is a file or directory in the tree of one revision. Entries are
+// never modified once they are part of a revision, so copies share them.
+type entry struct {
+	dir      bool
+	children map[string]*entry
+	content  []byte
+	props    map[string]string
+}
+
+// Node property names that set the kind of a file
+const (
+	propExecutable = "svn:executable"
+	propSpecial    = "svn:special"
+)
+
+// file returns the content and mode a file entry is written to Git with.
+// A special file holds "link <target>" and becomes a symbolic link.
+func (e *entry) file() ([]byte, vcs.FileMode) {
+	if _, ok := e.props[propSpecial]; ok {
+		if target, ok := bytes.CutPrefix(e.content, []byte("link ")); ok {
+			return target, vcs.ModeSymlink
+		}
+	}
+	if _, ok := e.props[propExecutable]; ok {
+		return e.content, vcs.ModeExecutable
+	}
+	return e.content, vcs.ModeRegular
+}
+
+// newDir creates an empty directory entry
+func newDir() *entry {
+	return &entry{dir: true, children: make(map[string]*entry)}
+}
+
+// lookup finds the entry at a slash-separated path below e
+func (e *entry) lookup(path string) *entry {
+	current := e
+	for _, name := range splitPath(path) {
+		if current == nil || !current.dir {
+			return nil
+		}
+		current = current.children[name]
+	}
+	return current
+}
+
+// with returns a copy of the tree rooted at e in which path refers to
+// child, creating missing parent directories. A nil child removes path.
+func (e *entry) with(path string, child *entry) *entry {
+	return e.withNames(splitPath(path), child)
+}
+
+func (e *entry) withNames(names []string, child *entry) *entry {
+	if len(names) == 0 {
+		return child
+	}
+
+	dir := newDir()
+	if e != nil && e.dir {
+		dir.props = e.props
+		for name, c := range e.children {
+			dir.children[name] = c
+		}
+	}
+
+	updated := dir.children[names[0]].withNames(names[1:], child)
+	if updated == nil {
+		delete(dir.children, names[0])
+	} else {
+		dir.children[names[0]] = updated
+	}
+	return dir
+}
+
+// walkFiles calls fn for every file below e in path order
+func (e *entry) walkFiles(prefix string, fn func(path string, file *entry)) {
+	if e == nil {
+		return
+	}
+	if !e.dir {
+		fn(prefix, e)
+		return
+	}
+
+	names := make([]string, 0, len(e.children))
+	for name := range e.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		e.children[name].walkFiles(joinPath(prefix, name), fn)
+	}
+}
+
+// splitPath splits a repository path into its components
+func splitPath(path string) []string {
+	var names []string
+	for _, name := range strings.Split(path, "/") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// joinPath joins a repository path and a child name
+func joinPath(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "/" + name
+}
+
+// diffTrees appends the file changes that turn tree a into tree b, with
+// paths relative to the trees. Either tree may be nil.
+func diffTrees(a, b *entry, prefix string, changes []vcs.FileChange) []vcs.FileChange {
+	if a == b {
+		return changes
+	}
+
+	switch {
+	case b == nil:
+		a.walkFiles(prefix, func(p string, _ *entry) {
+			changes = append(changes, vcs.FileChange{Path: p, Action: vcs.ActionDelete})
+		})
+		return changes
+	case !b.dir:
+		content, mode := b.file()
+		if a != nil && !a.dir {
+			if oldContent, oldMode := a.file(); !bytes.Equal(oldContent, content) || oldMode != mode {
+				changes = append(changes, vcs.FileChange{Path: prefix, Action: vcs.ActionModify, Content: content, Mode: mode})
+			}
+			return changes
+		}
+		changes = diffTrees(a, nil, prefix, changes)
+		return append(changes, vcs.FileChange{Path: prefix, Action: vcs.ActionAdd, Content: content, Mode: mode})
+	}
+
+	if a != nil && !a.dir {
+		changes = diffTrees(a, nil, prefix, changes)
+		a = nil
+	}
+
+	names := make([]string, 0, len(b.children))
+	for name := range b.children {
+		names = append(names, name)
+	}
+	if a != nil {
+		for name := range a.children {
+			if _, ok := b.children[name]; !ok {
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		var child *entry
+		if a != nil {
+			child = a.children[name]
+		}
+		changes = diffTrees(child, b.children[name], joinPath(prefix, name), changes)
+	}
+	return changes
+}
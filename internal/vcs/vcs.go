@@ -13,14 +13,33 @@ type Commit struct {
 	Date     time.Time // Commit timestamp
 	Message  string    // Commit message
 	Branch   string    // Branch name (empty for trunk/main)
+	Parent   string    // Revision a branch forks from (first commit on a branch only)
 	Files    []FileChange
 }
 
 // FileChange represents a file change in a commit
 type FileChange struct {
-	Path    string // File path
-	Action  Action // Add, Modify, Delete
-	Content []byte // File content (for Add/Modify)
+	Path     string   // File path
+	Action   Action   // Add, Modify, Delete
+	Content  []byte   // File content (for Add/Modify); the target of a symbolic link
+	Mode     FileMode // Kind of file (for Add/Modify)
+	Revision string   // Source revision of the file (if the VCS tracks files individually)
+}
+
+// FileMode is the kind of file a change writes
+type FileMode int
+
+const (
+	ModeRegular    FileMode = iota // Regular file
+	ModeExecutable                 // Executable file
+	ModeSymlink                    // Symbolic link
+)
+
+// Signature identifies who made a change and when
+type Signature struct {
+	Name  string
+	Email string
+	When  time.Time
 }
 
 // Action represents the type of file change
@@ -50,6 +69,28 @@ type VCSReader interface {
 	Close() error
 }
 
+// BranchPointReader is implemented by readers that know which commit each
+// branch was forked from
+type BranchPointReader interface {
+	// GetBranchPoints returns a map of branch names to revision identifiers
+	GetBranchPoints() (map[string]string, error)
+}
+
+// TagFixupReader is implemented by readers whose tags may refer to commits
+// that do not exist in the source history and must be synthesized
+type TagFixupReader interface {
+	// GetTagFixups returns synthetic commits keyed by revision identifier;
+	// each one applies on top of the commit named by its Parent
+	GetTagFixups() (map[string]*Commit, error)
+}
+
+// TagDateReader is implemented by readers that know when tags were made
+type TagDateReader interface {
+	// GetTagDates returns the date of the latest file revision or change
+	// each tag refers to, keyed by tag name
+	GetTagDates() (map[string]time.Time, error)
+}
+
 // CommitIterator provides iteration over commits
 type CommitIterator interface {
 	// Next advances to the next commit, returns false when done
@@ -73,8 +114,9 @@ type VCSWriter interface {
 	// CreateBranch creates a new branch
 	CreateBranch(name, revision string) error
 
-	// CreateTag creates a new tag
-	CreateTag(name, revision string) error
+	// CreateTag creates a new tag. An empty message creates a lightweight
+	// tag, any other message an annotated tag.
+	CreateTag(name, revision, message string) error
 
 	// Close releases any resources
 	Close() error
@@ -304,10 +304,13 @@ desc
 		t.Fatalf("Parse failed: %v", err)
 	}
 
+	// FEATURE_X is a branch; BUGFIX tags a revision on it
 	branches := rcsFile.GetBranches()
-	// Should have at least the branches from symbols
-	if len(branches) < 2 {
-		t.Errorf("Expected at least 2 branches, got %d", len(branches))
+	if len(branches) != 1 || branches[0] != "FEATURE_X" {
+		t.Errorf("Expected branch FEATURE_X, got %v", branches)
+	}
+	if _, ok := rcsFile.GetTags()["BUGFIX"]; !ok {
+		t.Error("Expected BUGFIX to be a tag")
 	}
 }
 
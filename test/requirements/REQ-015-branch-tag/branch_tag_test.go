@@ -15,7 +15,7 @@ import (
 func TestCreateBranch(t *testing.T) {
 	writer, repoPath := setupTestRepoWithCommit(t)
 	defer func() {
-		if err := os.RemoveAll(filepath.Dir(filepath.Dir(repoPath))); err != nil {
+		if err := os.RemoveAll(filepath.Dir(repoPath)); err != nil {
 			t.Logf("Warning: failed to remove temp dir: %v", err)
 		}
 	}()
@@ -53,7 +53,7 @@ func TestCreateBranch(t *testing.T) {
 func TestCreateBranchFromRevision(t *testing.T) {
 	writer, repoPath := setupTestRepoWithCommits(t, 2)
 	defer func() {
-		if err := os.RemoveAll(filepath.Dir(filepath.Dir(repoPath))); err != nil {
+		if err := os.RemoveAll(filepath.Dir(repoPath)); err != nil {
 			t.Logf("Warning: failed to remove temp dir: %v", err)
 		}
 	}()
@@ -101,7 +101,7 @@ func TestCreateBranchFromRevision(t *testing.T) {
 func TestCreateTag(t *testing.T) {
 	writer, repoPath := setupTestRepoWithCommit(t)
 	defer func() {
-		if err := os.RemoveAll(filepath.Dir(filepath.Dir(repoPath))); err != nil {
+		if err := os.RemoveAll(filepath.Dir(repoPath)); err != nil {
 			t.Logf("Warning: failed to remove temp dir: %v", err)
 		}
 	}()
@@ -132,7 +132,7 @@ func TestCreateTag(t *testing.T) {
 func TestCreateAnnotatedTag(t *testing.T) {
 	writer, repoPath := setupTestRepoWithCommit(t)
 	defer func() {
-		if err := os.RemoveAll(filepath.Dir(filepath.Dir(repoPath))); err != nil {
+		if err := os.RemoveAll(filepath.Dir(repoPath)); err != nil {
 			t.Logf("Warning: failed to remove temp dir: %v", err)
 		}
 	}()
@@ -163,7 +163,7 @@ func TestCreateAnnotatedTag(t *testing.T) {
 func TestListBranches(t *testing.T) {
 	writer, repoPath := setupTestRepoWithCommit(t)
 	defer func() {
-		if err := os.RemoveAll(filepath.Dir(filepath.Dir(repoPath))); err != nil {
+		if err := os.RemoveAll(filepath.Dir(repoPath)); err != nil {
 			t.Logf("Warning: failed to remove temp dir: %v", err)
 		}
 	}()
@@ -207,7 +207,7 @@ func TestListBranches(t *testing.T) {
 func TestListTags(t *testing.T) {
 	writer, repoPath := setupTestRepoWithCommit(t)
 	defer func() {
-		if err := os.RemoveAll(filepath.Dir(filepath.Dir(repoPath))); err != nil {
+		if err := os.RemoveAll(filepath.Dir(repoPath)); err != nil {
 			t.Logf("Warning: failed to remove temp dir: %v", err)
 		}
 	}()